@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/analyzer"
+)
+
+// sqliteDriver is the database/sql driver the history is opened with. It is
+// registered by sqlite.go, which only builds with the sqlite tag.
+const sqliteDriver = "sqlite"
+
+// historyPruneInterval is how often the history is cut back to its size.
+const historyPruneInterval = time.Minute
+
+var errNoSQLiteDriver = errors.New("this binary was built without the SQLite driver; rebuild with -tags sqlite")
+
+// openHistory opens the SQLite database at path and starts a history keeping
+// the newest maxRows analyses in it. The database is closed by the caller
+// once the history is.
+func openHistory(ctx context.Context, path string, maxRows int, log *slog.Logger) (*analyzer.History, *sql.DB, error) {
+	if !slices.Contains(sql.Drivers(), sqliteDriver) {
+		return nil, nil, errNoSQLiteDriver
+	}
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	// SQLite takes one writer at a time; a single connection queues the
+	// reads behind the writes instead of failing them with SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+	store, err := analyzer.NewSQLHistoryStore(ctx, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, nil, err
+	}
+	history := analyzer.NewHistory(store, analyzer.HistoryOptions{
+		MaxRows:       maxRows,
+		PruneInterval: historyPruneInterval,
+	}, log)
+	return history, db, nil
+}
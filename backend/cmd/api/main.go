@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,12 +13,16 @@ import (
 	"time"
 
 	"github.com/Bahjat/page-insight-tool/backend/internal/analyzer"
+	"github.com/Bahjat/page-insight-tool/backend/internal/demo"
 	"github.com/Bahjat/page-insight-tool/backend/internal/pageinsight"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/config"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/logger"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/middleware"
 )
 
+// apiPrefix is the version prefix of the API routes.
+const apiPrefix = "/v1"
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
@@ -27,13 +33,74 @@ func main() {
 	log := logger.New(cfg.LogLevel)
 
 	fetcher := pageinsight.NewHTTPClient()
-	checker := pageinsight.NewLinkChecker(cfg.LinkCheckConcurrency)
+	fetcher.SetUserAgent(cfg.UserAgent)
+	fetcher.SetMaxBodyBytes(cfg.MaxResponseBodyBytes)
+	fetcher.SetTimeout(cfg.FetchTimeout)
+	fetcher.SetMaxAttempts(cfg.FetchAttempts)
+	proxy := cfg.OutboundProxy()
+	if proxy != nil {
+		fetcher.SetProxy(proxy)
+	}
+	checker := pageinsight.NewLinkChecker(pageinsight.LinkCheckerOptions{
+		Concurrency:          cfg.LinkCheckConcurrency,
+		PerHost:              cfg.LinkCheckPerHost,
+		Timeout:              cfg.LinkCheckTimeout,
+		MaxLinks:             cfg.LinkCheckMaxLinks,
+		Strategy:             cfg.LinkCheckStrategy,
+		MaxRedirects:         cfg.LinkCheckMaxRedirects,
+		HostFailureThreshold: cfg.LinkCheckHostFailureThreshold,
+		CacheTTL:             cfg.LinkCheckCacheTTL,
+		CacheSize:            cfg.LinkCheckCacheSize,
+		Overflow:             cfg.LinkCheckOverflow,
+		UserAgent:            cfg.UserAgent,
+		BlockedHosts:         cfg.BlockedHosts,
+		Proxy:                proxy,
+	})
 	engine := pageinsight.NewEngine(fetcher, checker)
+	engine.BlockHosts(cfg.BlockedHosts)
+	engine.PreventSelfAnalysis(cfg.OwnHostnames)
+	engine.CheckRobotsTxt(cfg.RobotsTxt, cfg.UserAgent)
+	engine.EnablePageCache(cfg.PageCacheTTL, cfg.PageCacheSize)
+	if cfg.CheckMediaLinks {
+		engine.EnableMediaChecks()
+	}
+	if cfg.CollapseDuplicateSlashes {
+		engine.CollapseDuplicateSlashes()
+	}
 	svc := analyzer.NewService(engine, log)
-	transport := analyzer.NewTransport(svc, log)
+	limiter := analyzer.NewLimiter(cfg.MaxConcurrentAnalyses, cfg.AnalysisQueueWait)
+	svc.LimitConcurrency(limiter)
+	// Published whatever the config, but served only by the pprof listener.
+	expvar.Publish("analyses_in_flight", expvar.Func(func() any { return limiter.InFlight() }))
+	expvar.Publish("analyses_limit", expvar.Func(func() any { return limiter.Limit() }))
+	svc.EnableCrawl(pageinsight.NewCrawler(engine, cfg.CrawlDelay))
+	jobs := analyzer.NewJobQueue(svc, analyzer.JobOptions{
+		Workers:   cfg.JobWorkers,
+		QueueSize: cfg.JobQueueSize,
+		TTL:       cfg.JobTTL,
+	})
+	var history *analyzer.History
+	var historyDB *sql.DB
+	if cfg.HistoryDBPath != "" {
+		history, historyDB, err = openHistory(context.Background(), cfg.HistoryDBPath, cfg.MaxHistoryRows, log)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "history: %v\n", err)
+			os.Exit(1)
+		}
+		svc.EnableHistory(history)
+	}
+	transport := analyzer.NewTransport(svc, cfg.MaxUploadBytes, log)
+	transport.EnableJobs(jobs)
+	transport.SetBatchConcurrency(cfg.BatchConcurrency)
+	if cfg.HTTPResultCaching {
+		transport.EnableResultCaching(cfg.PageCacheTTL)
+	}
+	transport.EnableDemo(analyzer.NewService(demo.NewEngine(), log))
 
 	mux := http.NewServeMux()
-	transport.RegisterRoutes(mux)
+	transport.RegisterRoutes(mux, apiPrefix)
+	// The unversioned routes are kept for one release after /v1.
+	transport.RegisterAliases(mux, apiPrefix)
 	handler := middleware.CORS(mux)
 	handler = middleware.Logging(log)(handler)
 	handler = middleware.RequestID(handler)
@@ -51,13 +118,24 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 		}
 	}()
 
+	var pprofSrv *http.Server
+	if cfg.EnablePprof {
+		pprofSrv = newPprofServer(cfg.PprofPort)
+		log.Info("pprof server starting", "addr", pprofSrv.Addr)
+		go func() {
+			if err := pprofSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("pprof: %w", err)
+			}
+		}()
+	}
+
 	select {
 	case err := <-errCh:
 		log.Error("server error", "error", err)
@@ -66,13 +144,30 @@ func main() {
 		log.Info("shutting down gracefully", "signal", sig.String())
 	}
 
+	// Analyses already running drain below; new ones are turned away.
+	limiter.Close()
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 
+	if pprofSrv != nil {
+		// A profile still being captured is not worth waiting for.
+		_ = pprofSrv.Close()
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		cancel()
 		log.Error("forced shutdown", "error", err)
 		os.Exit(1)
 	}
 	defer cancel()
+	// Running jobs get what is left of the shutdown timeout, then fail.
+	if err := jobs.Shutdown(ctx); err != nil {
+		log.Warn("analysis jobs cut short by shutdown", "error", err)
+	}
+	// Jobs are done, so the last analyses are queued for the history.
+	if history != nil {
+		if err := history.Close(ctx); err != nil {
+			log.Warn("analysis history cut short by shutdown", "error", err)
+		}
+		_ = historyDB.Close()
+	}
 	log.Info("server stopped")
 }
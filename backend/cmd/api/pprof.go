@@ -0,0 +1,32 @@
+package main
+
+import (
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// newPprofServer serves the runtime profiles, and the expvar metrics at
+// /debug/vars, on the loopback interface only, apart from the public mux, so
+// they can be captured from the host (or through a port-forward) without
+// being reachable from the internet.
+func newPprofServer(port string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:              net.JoinHostPort("127.0.0.1", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		// CPU profiles and traces stream for as long as their seconds
+		// parameter asks, so there is no write timeout.
+		IdleTimeout: 120 * time.Second,
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// The SQLite driver backs the analysis history. It is only linked into
+// builds tagged sqlite, as most deployments run without a history.
+import _ "modernc.org/sqlite"
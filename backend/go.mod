@@ -5,4 +5,7 @@ go 1.26.0
 require (
 	github.com/google/uuid v1.6.0
 	golang.org/x/net v0.50.0
+	golang.org/x/sync v0.23.0
 )
+
+require golang.org/x/text v0.34.0 // indirect
@@ -0,0 +1,208 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+	"github.com/google/uuid"
+)
+
+// HistoryRecord is a completed analysis as a HistoryStore keeps it. Result
+// is the model.PageAnalysis as JSON.
+type HistoryRecord struct {
+	ID        string
+	URL       string
+	RequestID string
+	CreatedAt time.Time
+	Result    []byte
+}
+
+// HistoryStore persists the analysis history.
+type HistoryStore interface {
+	Insert(ctx context.Context, rec HistoryRecord) error
+	// List returns the newest records first, only those for targetURL when
+	// it isn't empty, without their results.
+	List(ctx context.Context, targetURL string, limit int) ([]HistoryRecord, error)
+	// Get returns the record with the given id, reporting whether there is
+	// one.
+	Get(ctx context.Context, id string) (HistoryRecord, bool, error)
+	// Prune deletes all but the newest keep records.
+	Prune(ctx context.Context, keep int) error
+}
+
+// HistoryOptions size a History.
+type HistoryOptions struct {
+	// MaxRows is how many analyses are kept; older ones are pruned.
+	MaxRows int
+	// PruneInterval is how often the analyses beyond MaxRows are pruned.
+	PruneInterval time.Duration
+}
+
+const (
+	// historyQueueSize is how many completed analyses may wait to be
+	// written; more are dropped rather than holding up their responses.
+	historyQueueSize = 100
+	// historyWriteTimeout bounds each write to the store.
+	historyWriteTimeout = 5 * time.Second
+)
+
+// History records completed analyses in a HistoryStore. Analyses are
+// written by a single background writer, which also prunes the store, so
+// recording one never adds latency to the request that produced it.
+type History struct {
+	store   HistoryStore
+	maxRows int
+	logger  *slog.Logger
+	now     func() time.Time
+	writes  chan pendingRecord
+	done    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// pendingRecord is an analysis waiting for the writer, which encodes it.
+type pendingRecord struct {
+	HistoryRecord
+	result *model.PageAnalysis
+}
+
+// NewHistory starts the writer of a history kept in store.
+func NewHistory(store HistoryStore, opts HistoryOptions, logger *slog.Logger) *History {
+	h := &History{
+		store:   store,
+		maxRows: opts.MaxRows,
+		logger:  logger,
+		now:     time.Now,
+		writes:  make(chan pendingRecord, historyQueueSize),
+		done:    make(chan struct{}),
+	}
+	go h.write(opts.PruneInterval)
+	return h
+}
+
+// record queues a completed analysis to be stored under its URL with the
+// request ID of ctx. It never blocks: when the writer has fallen behind, the
+// analysis is logged and left out of the history. A nil *History records
+// nothing.
+func (h *History) record(ctx context.Context, result *model.PageAnalysis) {
+	if h == nil {
+		return
+	}
+	rec := pendingRecord{
+		HistoryRecord: HistoryRecord{
+			ID:        uuid.NewString(),
+			URL:       result.URL,
+			RequestID: requestid.FromContext(ctx),
+			CreatedAt: h.now(),
+		},
+		result: result,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	select {
+	case h.writes <- rec:
+	default:
+		h.logger.Warn("analysis history is behind, analysis not recorded", "url", rec.URL, "request_id", rec.RequestID)
+	}
+}
+
+// write stores queued analyses and prunes the store every pruneEvery until
+// the history is closed, then stores what is left and prunes once more.
+func (h *History) write(pruneEvery time.Duration) {
+	defer close(h.done)
+	ticker := time.NewTicker(max(pruneEvery, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case rec, ok := <-h.writes:
+			if !ok {
+				h.prune()
+				return
+			}
+			h.insert(rec)
+		case <-ticker.C:
+			h.prune()
+		}
+	}
+}
+
+func (h *History) insert(rec pendingRecord) {
+	var err error
+	if rec.Result, err = json.Marshal(rec.result); err != nil {
+		h.logger.Error("failed to encode analysis for the history", "url", rec.URL, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historyWriteTimeout)
+	defer cancel()
+	if err := h.store.Insert(ctx, rec.HistoryRecord); err != nil {
+		h.logger.Error("failed to record analysis", "url", rec.URL, "request_id", rec.RequestID, "error", err)
+	}
+}
+
+func (h *History) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), historyWriteTimeout)
+	defer cancel()
+	if err := h.store.Prune(ctx, h.maxRows); err != nil {
+		h.logger.Error("failed to prune analysis history", "error", err)
+	}
+}
+
+// list returns the newest stored analyses, of targetURL when it isn't empty.
+func (h *History) list(ctx context.Context, targetURL string, limit int) ([]model.StoredAnalysis, error) {
+	records, err := h.store.List(ctx, targetURL, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]model.StoredAnalysis, len(records))
+	for i, rec := range records {
+		entries[i] = model.StoredAnalysis{
+			ID:        rec.ID,
+			URL:       rec.URL,
+			RequestID: rec.RequestID,
+			CreatedAt: rec.CreatedAt.UTC().Format(time.RFC3339),
+		}
+	}
+	return entries, nil
+}
+
+// get returns the stored analysis with the given id, reporting whether there
+// is one.
+func (h *History) get(ctx context.Context, id string) (*model.PageAnalysis, bool, error) {
+	rec, ok, err := h.store.Get(ctx, id)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var result model.PageAnalysis
+	if err := json.Unmarshal(rec.Result, &result); err != nil {
+		return nil, false, err
+	}
+	return &result, true, nil
+}
+
+// Close stops taking analyses and waits until those queued are stored, or
+// ctx ends.
+func (h *History) Close(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.writes)
+	}
+	h.mu.Unlock()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// historySchema creates the table of the SQLite history. created_at is in
+// Unix milliseconds.
+const historySchema = `
+CREATE TABLE IF NOT EXISTS analyses (
+	id         TEXT PRIMARY KEY,
+	url        TEXT NOT NULL,
+	request_id TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	result     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS analyses_created_at ON analyses (created_at);
+CREATE INDEX IF NOT EXISTS analyses_url_created_at ON analyses (url, created_at);
+`
+
+// SQLHistoryStore keeps the analysis history in a SQLite database.
+type SQLHistoryStore struct {
+	db *sql.DB
+}
+
+// NewSQLHistoryStore returns a store backed by db, creating its table if it
+// is missing.
+func NewSQLHistoryStore(ctx context.Context, db *sql.DB) (*SQLHistoryStore, error) {
+	if _, err := db.ExecContext(ctx, historySchema); err != nil {
+		return nil, err
+	}
+	return &SQLHistoryStore{db: db}, nil
+}
+
+func (s *SQLHistoryStore) Insert(ctx context.Context, rec HistoryRecord) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO analyses (id, url, request_id, created_at, result) VALUES (?, ?, ?, ?, ?)`,
+		rec.ID, rec.URL, rec.RequestID, rec.CreatedAt.UnixMilli(), string(rec.Result))
+	return err
+}
+
+func (s *SQLHistoryStore) List(ctx context.Context, targetURL string, limit int) ([]HistoryRecord, error) {
+	query := `SELECT id, url, request_id, created_at FROM analyses ORDER BY created_at DESC, rowid DESC LIMIT ?`
+	args := []any{limit}
+	if targetURL != "" {
+		query = `SELECT id, url, request_id, created_at FROM analyses WHERE url = ? ORDER BY created_at DESC, rowid DESC LIMIT ?`
+		args = []any{targetURL, limit}
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []HistoryRecord
+	for rows.Next() {
+		var rec HistoryRecord
+		var createdAt int64
+		if err := rows.Scan(&rec.ID, &rec.URL, &rec.RequestID, &createdAt); err != nil {
+			return nil, err
+		}
+		rec.CreatedAt = time.UnixMilli(createdAt)
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func (s *SQLHistoryStore) Get(ctx context.Context, id string) (HistoryRecord, bool, error) {
+	rec := HistoryRecord{ID: id}
+	var createdAt int64
+	var result string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT url, request_id, created_at, result FROM analyses WHERE id = ?`, id,
+	).Scan(&rec.URL, &rec.RequestID, &createdAt, &result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryRecord{}, false, nil
+	}
+	if err != nil {
+		return HistoryRecord{}, false, err
+	}
+	rec.CreatedAt, rec.Result = time.UnixMilli(createdAt), []byte(result)
+	return rec, true, nil
+}
+
+// Prune deletes the records older than the keep-th newest. Records sharing
+// its timestamp are kept, so a few more than keep may remain.
+func (s *SQLHistoryStore) Prune(ctx context.Context, keep int) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM analyses WHERE created_at < (SELECT created_at FROM analyses ORDER BY created_at DESC LIMIT 1 OFFSET ?)`,
+		keep-1)
+	return err
+}
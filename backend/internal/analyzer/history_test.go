@@ -0,0 +1,249 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+)
+
+// memHistoryStore keeps history records in memory, newest last. Insert
+// blocks while gate is set and not yet closed.
+type memHistoryStore struct {
+	gate chan struct{}
+
+	mu      sync.Mutex
+	records []HistoryRecord
+	prunes  int
+}
+
+func (s *memHistoryStore) Insert(_ context.Context, rec HistoryRecord) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *memHistoryStore) List(_ context.Context, targetURL string, limit int) ([]HistoryRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []HistoryRecord
+	for _, rec := range slices.Backward(s.records) {
+		if len(records) < limit && (targetURL == "" || rec.URL == targetURL) {
+			rec.Result = nil
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (s *memHistoryStore) Get(_ context.Context, id string) (HistoryRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range s.records {
+		if rec.ID == id {
+			return rec, true, nil
+		}
+	}
+	return HistoryRecord{}, false, nil
+}
+
+func (s *memHistoryStore) Prune(_ context.Context, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prunes++
+	if len(s.records) > keep {
+		s.records = slices.Clone(s.records[len(s.records)-keep:])
+	}
+	return nil
+}
+
+func (s *memHistoryStore) snapshot() []HistoryRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records)
+}
+
+func TestService_Analyze_RecordsHistory(t *testing.T) {
+	store := &memHistoryStore{}
+	history := NewHistory(store, HistoryOptions{MaxRows: 2, PruneInterval: time.Hour}, slog.Default())
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com/", Title: "Example"}}
+	svc := NewService(provider, slog.Default())
+	svc.EnableHistory(history)
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	for range 3 {
+		if _, err := svc.Analyze(ctx, "example.com", model.AnalyzeOptions{}); err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+	}
+	provider.result, provider.err = nil, errPrimaryUsed
+	_, _ = svc.Analyze(ctx, "https://down.example.com/", model.AnalyzeOptions{})
+	if err := history.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	records := store.snapshot()
+	if len(records) != 2 {
+		t.Fatalf("history has %d records, want the 2 newest of the 3 completed analyses", len(records))
+	}
+	rec := records[1]
+	if rec.URL != "https://example.com/" || rec.RequestID != "req-1" || rec.ID == "" || rec.CreatedAt.IsZero() {
+		t.Errorf("record = %+v, want the analyzed URL, the request ID, an id and a time", rec)
+	}
+	var stored model.PageAnalysis
+	if err := json.Unmarshal(rec.Result, &stored); err != nil || stored.Title != "Example" {
+		t.Errorf("stored result = %s, %v, want the analysis", rec.Result, err)
+	}
+}
+
+func TestService_Analyze_SkipsHistoryWithHeaders(t *testing.T) {
+	store := &memHistoryStore{}
+	history := NewHistory(store, HistoryOptions{MaxRows: 10, PruneInterval: time.Hour}, slog.Default())
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com/account", Title: "Account"}}
+	svc := NewService(provider, slog.Default())
+	svc.EnableHistory(history)
+
+	opts := model.AnalyzeOptions{Headers: model.RequestHeaders{"Cookie": "session=secret"}}
+	if _, err := svc.Analyze(context.Background(), "https://example.com/account", opts); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if err := history.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if records := store.snapshot(); len(records) != 0 {
+		t.Errorf("history has %d records, want none for an analysis with caller-supplied headers", len(records))
+	}
+}
+
+func TestHistory_RecordDoesNotBlock(t *testing.T) {
+	store := &memHistoryStore{gate: make(chan struct{})}
+	history := NewHistory(store, HistoryOptions{MaxRows: 1000, PruneInterval: time.Hour}, slog.Default())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range historyQueueSize + 10 {
+			history.record(context.Background(), &model.PageAnalysis{URL: "https://example.com/"})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("record blocked on a store that is behind")
+	}
+
+	close(store.gate)
+	if err := history.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := len(store.snapshot()); got < historyQueueSize || got > historyQueueSize+1 {
+		t.Errorf("history has %d records, want the %d that fit in the queue", got, historyQueueSize)
+	}
+}
+
+func newHistoryTestMux(t *testing.T, store *memHistoryStore) *http.ServeMux {
+	t.Helper()
+	logger := slog.Default()
+	history := NewHistory(store, HistoryOptions{MaxRows: 100, PruneInterval: time.Hour}, logger)
+	t.Cleanup(func() { _ = history.Close(context.Background()) })
+	svc := NewService(&mockProvider{}, logger)
+	svc.EnableHistory(history)
+	mux := http.NewServeMux()
+	NewTransport(svc, testMaxUpload, logger).RegisterRoutes(mux, "/v1")
+	return mux
+}
+
+func TestHandleListAnalyses(t *testing.T) {
+	created := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	store := &memHistoryStore{}
+	for i, target := range []string{"https://a.example/", "https://b.example/", "https://a.example/"} {
+		_ = store.Insert(context.Background(), HistoryRecord{
+			ID: string(rune('1' + i)), URL: target, RequestID: "r", CreatedAt: created.Add(time.Duration(i) * time.Minute), Result: []byte("{}"),
+		})
+	}
+	mux := newHistoryTestMux(t, store)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantIDs    []string
+	}{
+		{"all", "", http.StatusOK, []string{"3", "2", "1"}},
+		{"by url", "?url=https://a.example/", http.StatusOK, []string{"3", "1"}},
+		{"limit", "?limit=1", http.StatusOK, []string{"3"}},
+		{"unknown url", "?url=https://c.example/", http.StatusOK, []string{}},
+		{"limit too large", "?limit=1000", http.StatusBadRequest, nil},
+		{"limit not a number", "?limit=all", http.StatusBadRequest, nil},
+		{"unsupported parameter", "?title=x", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyses"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantIDs == nil {
+				return
+			}
+			var entries []model.StoredAnalysis
+			if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]string, len(entries))
+			for i, e := range entries {
+				ids[i] = e.ID
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if tt.name == "all" && entries[2].CreatedAt != "2026-03-04T05:06:07Z" {
+				t.Errorf("created_at = %q, want it in RFC 3339", entries[2].CreatedAt)
+			}
+		})
+	}
+}
+
+func TestHandleGetAnalysis(t *testing.T) {
+	store := &memHistoryStore{}
+	result, _ := json.Marshal(&model.PageAnalysis{URL: "https://example.com/", Title: "Stored"})
+	_ = store.Insert(context.Background(), HistoryRecord{ID: "abc", URL: "https://example.com/", CreatedAt: time.Now(), Result: result})
+	mux := newHistoryTestMux(t, store)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyses/abc", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title":"Stored"`) {
+		t.Errorf("stored analysis: status %d, body %s, want 200 with the result", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyses/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown analysis: status %d, want 404", rec.Code)
+	}
+}
+
+func TestHandleListAnalyses_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newTestMux(&mockProvider{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyses", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 with the history off", rec.Code)
+	}
+}
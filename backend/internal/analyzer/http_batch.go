@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+	"github.com/google/uuid"
+)
+
+// batchWriteSlack is the time left for writing a batch response once its
+// last analysis has had its full timeout.
+const batchWriteSlack = 15 * time.Second
+
+// batchRequest takes the analyze options, applied to every URL, and the URLs
+// to analyze in place of a single "url".
+type batchRequest struct {
+	analyzeRequest
+	URLs []string `json:"urls"`
+}
+
+func (req *batchRequest) validate() string {
+	if req.URL != "" {
+		return "a batch takes \"urls\"; send a single \"url\" to POST /analyze"
+	}
+	if len(req.URLs) == 0 {
+		return "the \"urls\" field is required"
+	}
+	if len(req.URLs) > maxBatchURLs {
+		return fmt.Sprintf("at most %d \"urls\" may be analyzed in one batch", maxBatchURLs)
+	}
+	return req.validateOptions()
+}
+
+// handleAnalyzeBatch analyzes several URLs with the same options, a few at a
+// time, and answers with one entry per URL in the order given: the analysis,
+// or the error body a request for that URL alone would have failed with. A
+// URL that fails leaves the rest of the batch alone.
+func (t *Transport) handleAnalyzeBatch(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+
+	var req batchRequest
+	if msg := decodeRequestBody(w, r, &req, "urls"); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	// Each URL gets the whole analyze timeout once it starts, so the batch
+	// may take that many times over as its URLs wait for a turn. The server's
+	// write timeout is sized for one analysis; give this response longer.
+	rounds := (len(req.URLs) + t.batchWorkers - 1) / t.batchWorkers
+	deadline := time.Now().Add(time.Duration(rounds)*analyzeTimeout + batchWriteSlack)
+	_ = http.NewResponseController(w).SetWriteDeadline(deadline) // not every ResponseWriter supports it
+
+	batchID := uuid.NewString()
+	ctx := withBatchID(r.Context(), batchID)
+	opts := req.options()
+	entries := make([]any, len(req.URLs))
+	failed := make([]bool, len(req.URLs))
+	slots := make(chan struct{}, t.batchWorkers)
+	var wg sync.WaitGroup
+	for i, target := range req.URLs {
+		wg.Go(func() {
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			entries[i], failed[i] = t.analyzeBatchEntry(ctx, r, schema, target, opts)
+		})
+	}
+	wg.Wait()
+
+	var failures int
+	for _, f := range failed {
+		if f {
+			failures++
+		}
+	}
+	t.logger.Info("batch complete",
+		"batch_id", batchID,
+		"request_id", requestid.FromContext(ctx),
+		"urls", len(req.URLs),
+		"failed", failures,
+	)
+	t.renderJSON(w, http.StatusOK, entries)
+}
+
+// analyzeBatchEntry analyzes one URL of a batch within analyzeTimeout and
+// returns its entry, reporting whether it failed.
+func (t *Transport) analyzeBatchEntry(ctx context.Context, r *http.Request, schema, target string, opts model.AnalyzeOptions) (any, bool) {
+	ctx, cancel := context.WithTimeout(ctx, analyzeTimeout)
+	defer cancel()
+
+	service, targetURL := t.route(r, target)
+	result, err := service.Analyze(ctx, targetURL, opts)
+	if err != nil {
+		return errorResponse(err), true
+	}
+	return schemaRenderers[schema](result), false
+}
@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// batchProvider fails the URLs on down.example.com and records how many
+// analyses ran at once.
+type batchProvider struct {
+	mu            sync.Mutex
+	running, peak int
+}
+
+func (p *batchProvider) Analyze(_ context.Context, targetURL string, _ model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	p.mu.Lock()
+	p.running++
+	p.peak = max(p.peak, p.running)
+	p.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+
+	if strings.Contains(targetURL, "down.example.com") {
+		return nil, &errs.AppError{Kind: errs.Unreachable, Message: "cannot reach"}
+	}
+	return &model.PageAnalysis{URL: targetURL, Title: "Batched"}, nil
+}
+
+func (p *batchProvider) AnalyzeHTML(context.Context, io.Reader, string) (*model.PageAnalysis, error) {
+	return nil, errPrimaryUsed
+}
+
+func newBatchTestMux(provider PageInsightProvider, logs io.Writer, concurrency int) *http.ServeMux {
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+	transport := NewTransport(NewService(provider, logger), testMaxUpload, logger)
+	transport.SetBatchConcurrency(concurrency)
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	return mux
+}
+
+func TestHandleAnalyzeBatch(t *testing.T) {
+	provider := &batchProvider{}
+	var logs bytes.Buffer
+	mux := newBatchTestMux(provider, &logs, 2)
+
+	urls := []string{"https://a.example.com", "https://down.example.com", "https://b.example.com", "https://c.example.com"}
+	body, _ := json.Marshal(map[string]any{"urls": urls})
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze/batch", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var entries []struct {
+		URL        string `json:"url"`
+		Title      string `json:"title"`
+		StatusCode int    `json:"status_code"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(entries) != len(urls) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(urls))
+	}
+	for i, entry := range entries {
+		if i == 1 {
+			if entry.StatusCode != http.StatusBadGateway {
+				t.Errorf("entry %d = %+v, want a 502 error", i, entry)
+			}
+			continue
+		}
+		if entry.URL != urls[i] || entry.Title != "Batched" {
+			t.Errorf("entry %d = %+v, want the analysis of %s", i, entry, urls[i])
+		}
+	}
+	if provider.peak > 2 {
+		t.Errorf("%d analyses ran at once, want at most 2", provider.peak)
+	}
+
+	ids := regexp.MustCompile(`"batch_id":"([^"]+)"`).FindAllStringSubmatch(logs.String(), -1)
+	if len(ids) != len(urls)+1 {
+		t.Fatalf("found %d log entries with a batch id, want one per URL and a summary: %s", len(ids), logs.String())
+	}
+	for _, id := range ids {
+		if id[1] != ids[0][1] {
+			t.Errorf("log entries carry batch ids %q and %q, want one", ids[0][1], id[1])
+		}
+	}
+}
+
+func TestHandleAnalyzeBatch_InvalidRequests(t *testing.T) {
+	tooMany := make([]string, maxBatchURLs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("https://example.com/%d", i)
+	}
+	tooManyBody, _ := json.Marshal(map[string]any{"urls": tooMany})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed json", `{"urls":`},
+		{"no urls", `{}`},
+		{"empty urls", `{"urls": []}`},
+		{"single url", `{"url": "https://example.com"}`},
+		{"too many urls", string(tooManyBody)},
+		{"invalid option", `{"urls": ["https://example.com"], "check_links": "some"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &batchProvider{}
+			mux := newBatchTestMux(provider, io.Discard, 2)
+			req := httptest.NewRequest(http.MethodPost, "/v1/analyze/batch", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if provider.peak != 0 {
+				t.Error("a rejected batch analyzed a URL")
+			}
+		})
+	}
+}
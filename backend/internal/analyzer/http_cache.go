@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// EnableResultCaching lets clients cache analyze results: a result is sent
+// with an ETag and Cache-Control max-age of maxAge, and a request whose
+// If-None-Match has the ETag of its result is answered 304 Not Modified.
+// The analysis still runs; what a repeat saves is the response body.
+func (t *Transport) EnableResultCaching(maxAge time.Duration) {
+	t.resultCaching = true
+	t.resultMaxAge = maxAge
+}
+
+// renderResult sends an analysis in the given schema and format, as a
+// cacheable response when result caching is on and the format is JSON.
+func (t *Transport) renderResult(w http.ResponseWriter, r *http.Request, schema, format string, result *model.PageAnalysis) {
+	if format == formatCSV {
+		t.renderCSV(w, result)
+		return
+	}
+	if !t.resultCaching {
+		t.renderJSON(w, http.StatusOK, schemaRenderers[schema](result))
+		return
+	}
+
+	etag, err := resultETag(schema, result)
+	if err != nil {
+		t.logger.Error("failed to compute the ETag of a result", "error", err)
+		t.renderJSON(w, http.StatusOK, schemaRenderers[schema](result))
+		return
+	}
+	// Results may come from caller-supplied headers, so only the caller's
+	// own cache may keep them.
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(t.resultMaxAge/time.Second)))
+	w.Header().Set("ETag", etag)
+	// The schema header and Accept, which can ask for CSV, both change the
+	// response.
+	w.Header().Add("Vary", "X-PageInsight-Schema")
+	w.Header().Add("Vary", "Accept")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	t.renderJSON(w, http.StatusOK, schemaRenderers[schema](result))
+}
+
+// resultETag is a weak ETag of the result as rendered in schema, leaving out
+// what changes from one run to the next when the page doesn't: whether it
+// came from the page cache and how long the fetch, the link checks and each
+// phase took. Two responses with the same ETag may differ in those alone.
+func resultETag(schema string, result *model.PageAnalysis) (string, error) {
+	stable := *result
+	stable.Cached = false
+	stable.Timings = model.Timings{}
+	stable.Fetch.DNSMs, stable.Fetch.ConnectMs, stable.Fetch.TLSMs = 0, 0, 0
+	stable.Fetch.TTFBMs, stable.Fetch.TotalMs, stable.Fetch.Attempts = 0, 0, 0
+	stable.Links.LatencyP50Ms, stable.Links.LatencyP95Ms, stable.Links.SlowestLinks = 0, 0, nil
+	stable.LinksDetail = slices.Clone(result.LinksDetail)
+	for i := range stable.LinksDetail {
+		stable.LinksDetail[i].ElapsedMs = 0
+	}
+
+	encoded, err := json.Marshal(schemaRenderers[schema](&stable))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header names etag, comparing
+// weakly as RFC 9110 has it for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+func newCachingTestMux(provider PageInsightProvider) *http.ServeMux {
+	logger := slog.Default()
+	transport := NewTransport(NewService(provider, logger), testMaxUpload, logger)
+	transport.EnableResultCaching(time.Minute)
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	return mux
+}
+
+func TestHandleAnalyzeQuery_ResultCaching(t *testing.T) {
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com", Title: "Example"}}
+	mux := newCachingTestMux(provider)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/analyze?url=https://example.com", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q, want 200 with an ETag", first.Code, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q, want private, max-age=60", got)
+	}
+	if got := first.Header().Values("Vary"); !slices.Equal(got, []string{"X-PageInsight-Schema", "Accept"}) {
+		t.Errorf("Vary = %q, want X-PageInsight-Schema and Accept", got)
+	}
+
+	// A rerun that only took a different time is the same result.
+	provider.result = &model.PageAnalysis{URL: "https://example.com", Title: "Example", Cached: true, Timings: model.Timings{TotalMs: 5}}
+	if again := get(etag); again.Code != http.StatusNotModified || again.Body.Len() != 0 {
+		t.Errorf("revalidation: status = %d with %d body bytes, want an empty 304", again.Code, again.Body.Len())
+	}
+
+	provider.result = &model.PageAnalysis{URL: "https://example.com", Title: "Changed"}
+	changed := get(etag)
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == etag {
+		t.Errorf("changed page: status = %d, ETag = %q, want 200 with a new ETag", changed.Code, changed.Header().Get("ETag"))
+	}
+
+	provider.err = errPrimaryUsed
+	if failed := get(etag); failed.Header().Get("Cache-Control") != "no-store" || failed.Header().Get("ETag") != "" {
+		t.Errorf("failed analysis: Cache-Control = %q, ETag = %q, want no-store without an ETag",
+			failed.Header().Get("Cache-Control"), failed.Header().Get("ETag"))
+	}
+}
+
+func TestHandleAnalyzeQuery_ResultCachingOff(t *testing.T) {
+	mux := newTestMux(&mockProvider{result: &model.PageAnalysis{URL: "https://example.com"}})
+	req := httptest.NewRequest(http.MethodGet, "/v1/analyze?url=https://example.com", nil)
+	req.Header.Set("If-None-Match", "*")
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != "" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("status = %d, ETag = %q, Cache-Control = %q, want an uncached 200",
+			rec.Code, rec.Header().Get("ETag"), rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `W/"abc"`
+	tests := map[string]bool{
+		`W/"abc"`:      true,
+		`"abc"`:        true,
+		`"x", W/"abc"`: true,
+		"*":            true,
+		`W/"abd"`:      false,
+		"":             false,
+	}
+	for header, want := range tests {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIDocument describes every route of the transport. A test holds it to
+// the registered routes and the rendered response shapes.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// docsPage renders openAPIDocument with Redoc, which it loads from a CDN.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// redocScript is the one Redoc release docsPage loads.
+const redocScript = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// docsPolicy lets the docs page run redocScript and nothing else from the
+// CDN, so a script the page didn't name can't load, and keeps what Redoc
+// needs: its inline styles, its search worker and the spec at ./openapi.json.
+const docsPolicy = "default-src 'none'; script-src " + redocScript +
+	"; worker-src blob:; style-src 'unsafe-inline'; img-src 'self' data:; connect-src 'self'"
+
+func (t *Transport) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
+
+func (t *Transport) handleDocs(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	_, _ = w.Write(docsPage)
+}
@@ -0,0 +1,266 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/demo"
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// openAPISchema is the part of an OpenAPI schema object the tests check
+// responses against.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 any                       `json:"type"`
+	Properties           map[string]*openAPISchema `json:"properties"`
+	Required             []string                  `json:"required"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties"`
+	Items                *openAPISchema            `json:"items"`
+	OneOf                []*openAPISchema          `json:"oneOf"`
+}
+
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Responses map[string]any `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	t.Helper()
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("openapi = %q, want a 3.x document", doc.OpenAPI)
+	}
+	return doc
+}
+
+// propertiesSeen records, for each object schema a response was checked
+// against, the properties that appeared.
+type propertiesSeen map[*openAPISchema]map[string]bool
+
+// conform reports how value, decoded from JSON, departs from schema: a key
+// the schema doesn't document or a value of the wrong type. It records the
+// properties it met in seen.
+func (doc openAPIDoc) conform(schema *openAPISchema, value any, path string, seen propertiesSeen) []string {
+	if schema.Ref != "" {
+		return doc.conform(doc.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")], value, path, seen)
+	}
+	if len(schema.OneOf) > 0 {
+		for _, option := range schema.OneOf {
+			if problems := doc.conform(option, value, path, propertiesSeen{}); len(problems) == 0 {
+				return doc.conform(option, value, path, seen)
+			}
+		}
+		return []string{path + ": matches none of its oneOf schemas"}
+	}
+	if got := jsonType(value); !schemaAllows(schema.Type, got) {
+		return []string{fmt.Sprintf("%s: got %s, documented as %v", path, got, schema.Type)}
+	}
+
+	var problems []string
+	switch v := value.(type) {
+	case map[string]any:
+		if seen[schema] == nil {
+			seen[schema] = make(map[string]bool)
+		}
+		for key, item := range v {
+			seen[schema][key] = true
+			switch prop, ok := schema.Properties[key]; {
+			case ok:
+				problems = append(problems, doc.conform(prop, item, path+"."+key, seen)...)
+			case schema.AdditionalProperties != nil:
+				problems = append(problems, doc.conform(schema.AdditionalProperties, item, path+"."+key, seen)...)
+			default:
+				problems = append(problems, path+"."+key+": not documented")
+			}
+		}
+	case []any:
+		for i, item := range v {
+			problems = append(problems, doc.conform(schema.Items, item, path+"["+strconv.Itoa(i)+"]", seen)...)
+		}
+	}
+	return problems
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// schemaAllows reports whether a schema's type, a name or a list of names,
+// admits a JSON value of type got.
+func schemaAllows(schemaType any, got string) bool {
+	var allowed []string
+	switch st := schemaType.(type) {
+	case string:
+		allowed = []string{st}
+	case []any:
+		for _, name := range st {
+			allowed = append(allowed, fmt.Sprint(name))
+		}
+	default:
+		return true
+	}
+	return slices.Contains(allowed, got) || (got == "integer" && slices.Contains(allowed, "number"))
+}
+
+// checkShape checks responses against the named schema. Between them they
+// are expected to set every field, so a documented property that none of
+// them has is reported too.
+func (doc openAPIDoc) checkShape(t *testing.T, schemaName string, responses ...any) {
+	t.Helper()
+	seen := propertiesSeen{}
+	for _, response := range responses {
+		encoded, err := json.Marshal(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var value any
+		if err := json.Unmarshal(encoded, &value); err != nil {
+			t.Fatal(err)
+		}
+		for _, problem := range doc.conform(&openAPISchema{Ref: "#/components/schemas/" + schemaName}, value, schemaName, seen) {
+			t.Error(problem)
+		}
+	}
+	for name, schema := range doc.Components.Schemas {
+		if keys, ok := seen[schema]; ok {
+			for prop := range schema.Properties {
+				if !keys[prop] {
+					t.Errorf("%s.%s is documented but never in the response", name, prop)
+				}
+			}
+		}
+	}
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	doc := loadOpenAPI(t)
+	logger := slog.Default()
+	svc := NewService(&mockProvider{}, logger)
+	svc.EnableCrawl(&mockCrawler{})
+	history := NewHistory(&memHistoryStore{}, HistoryOptions{MaxRows: 1, PruneInterval: time.Hour}, logger)
+	t.Cleanup(func() { _ = history.Close(context.Background()) })
+	svc.EnableHistory(history)
+	jobs := NewJobQueue(svc, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+	t.Cleanup(func() { _ = jobs.Shutdown(context.Background()) })
+	transport := NewTransport(svc, testMaxUpload, logger)
+	transport.EnableJobs(jobs)
+	transport.EnableDemo(NewService(demo.NewEngine(), logger))
+
+	var documented []string
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(documented)
+	served := slices.Sorted(maps.Keys(transport.routes("/v1")))
+	if !slices.Equal(documented, served) {
+		t.Errorf("documented routes %q, want the served routes %q", documented, served)
+	}
+}
+
+func TestOpenAPI_ResponseShapes(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	doc.checkShape(t, "PageAnalysis", renderSchemaV2(fullAnalysis()))
+	doc.checkShape(t, "ErrorResponse", errorResponse(&errs.AppError{
+		Kind:           errs.Unreachable,
+		UpstreamStatus: http.StatusForbidden,
+		Message:        "The target answered 403 Forbidden.",
+		Detail:         model.UpstreamErrorDetail{Title: "Access denied"},
+	}), errorResponse(&errs.AppError{
+		Kind:           errs.Timeout,
+		UpstreamStatus: http.StatusGatewayTimeout,
+		Message:        "Analysis timed out.",
+		Detail: model.TimeoutDetail{
+			Phase:           "link_check",
+			PhaseElapsedMs:  900,
+			CompletedPhases: []model.PhaseTiming{{Phase: "fetch", DurationMs: 100}},
+			LinksChecked:    3,
+			LinksTotal:      9,
+		},
+	}))
+}
+
+func TestOpenAPI_DocumentsErrorStatuses(t *testing.T) {
+	doc := loadOpenAPI(t)
+	responses := doc.Paths["/v1/analyze"]["post"].Responses
+
+	// Unavailable is the last Kind; extend the loop with the list.
+	for kind := errs.Unknown; kind <= errs.Unavailable; kind++ {
+		status := errorResponse(&errs.AppError{Kind: kind}).StatusCode
+		if _, ok := responses[strconv.Itoa(status)]; !ok {
+			t.Errorf("POST /analyze doesn't document status %d, which errors of kind %d answer with", status, kind)
+		}
+	}
+}
+
+func TestHandleDocs(t *testing.T) {
+	mux := newTestMux(&mockProvider{})
+	tests := []struct {
+		path        string
+		contentType string
+		want        []byte
+	}{
+		{"/v1/openapi.json", "application/json", openAPIDocument},
+		{"/v1/docs", "text/html; charset=utf-8", docsPage},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("GET %s: status %d, Content-Type %q, want 200 and %s", tt.path, rec.Code, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+		if rec.Body.String() != string(tt.want) {
+			t.Errorf("GET %s served something other than the embedded file", tt.path)
+		}
+	}
+}
+
+func TestHandleDocs_PinsRedoc(t *testing.T) {
+	if !strings.Contains(string(docsPage), `<script src="`+redocScript+`" crossorigin="anonymous"`) {
+		t.Errorf("docs.html doesn't load %s anonymously, the script the page's policy allows", redocScript)
+	}
+	rec := httptest.NewRecorder()
+	newTestMux(&mockProvider{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/docs", nil))
+	if got := rec.Header().Get("Content-Security-Policy"); got != docsPolicy {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, docsPolicy)
+	}
+}
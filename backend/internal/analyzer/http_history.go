@@ -0,0 +1,70 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Limits on the analyses one listing returns.
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// handleListAnalyses serves GET /analyses?url=...&limit=..., the most
+// recent analyses in the history, newest first, of url when it is given.
+func (t *Transport) handleListAnalyses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	query := r.URL.Query()
+	for name := range query {
+		if name != "url" && name != "limit" {
+			t.renderError(w, http.StatusBadRequest, fmt.Sprintf("unsupported query parameter %q; this GET takes only url, limit", name))
+			return
+		}
+	}
+	limit := defaultHistoryLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			t.renderError(w, http.StatusBadRequest, fmt.Sprintf("the \"limit\" query parameter must be a number from 1 to %d", maxHistoryLimit))
+			return
+		}
+		limit = n
+	}
+
+	entries, err := t.service.history.list(r.Context(), query.Get("url"), limit)
+	if err != nil {
+		t.logger.Error("failed to list analysis history", "error", err)
+		t.renderError(w, http.StatusInternalServerError, "The analysis history could not be read.")
+		return
+	}
+	t.renderJSON(w, http.StatusOK, entries)
+}
+
+// handleGetAnalysis serves an analysis from the history in the schema and
+// format asked for, like a fresh one.
+func (t *Transport) handleGetAnalysis(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+	format, ok := negotiateFormat(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedFormatMessage(format))
+		return
+	}
+
+	result, found, err := t.service.history.get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		t.logger.Error("failed to read analysis history", "error", err)
+		t.renderError(w, http.StatusInternalServerError, "The analysis history could not be read.")
+		return
+	}
+	if !found {
+		t.renderError(w, http.StatusNotFound, "No such analysis. The history keeps only the most recent ones.")
+		return
+	}
+	t.renderResult(w, r, schema, format, result)
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+//go:embed report.html
+var reportTemplates string
+
+// reportPages renders an analysis as a self-contained HTML page, the
+// "report" template, or an error as one, the "error" template. Neither loads
+// anything from elsewhere, and html/template escapes all the page-derived
+// text they show.
+var reportPages = template.Must(template.New("report.html").Funcs(template.FuncMap{
+	"inaccessibleLinks": inaccessibleLinks,
+}).Parse(reportTemplates))
+
+// reportPolicy forbids the report any script and any resource but its own
+// inline styles, should something slip past the escaping.
+const reportPolicy = "default-src 'none'; style-src 'unsafe-inline'"
+
+// handleReport serves GET /report?url=..., an analysis with the default
+// options rendered as an HTML page to open in a browser or save. Errors are
+// rendered as an HTML page too, with the status code the JSON API uses.
+func (t *Transport) handleReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	req, msg := parseAnalyzeQuery(r.URL.Query())
+	if msg == "" {
+		msg = req.validate()
+	}
+	if msg != "" {
+		t.renderReport(w, http.StatusBadRequest, "error", model.ErrorResponse{
+			Error:      http.StatusText(http.StatusBadRequest),
+			StatusCode: http.StatusBadRequest,
+			Message:    msg,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
+	defer cancel()
+
+	opts := req.options()
+	opts.IncludeLinks = true // for the table of inaccessible links
+	service, targetURL := t.route(r, req.URL)
+	result, err := service.Analyze(ctx, targetURL, opts)
+	if err != nil {
+		setRetryAfter(w, err)
+		resp := errorResponse(err)
+		t.renderReport(w, resp.StatusCode, "error", resp)
+		return
+	}
+	t.renderReport(w, http.StatusOK, "report", result)
+}
+
+func (t *Transport) renderReport(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := reportPages.ExecuteTemplate(&buf, name, data); err != nil {
+		t.logger.Error("failed to render report", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", reportPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// inaccessibleLinks returns the links counted as inaccessible: those whose
+// check failed, other than the rate limited and blocked ones.
+func inaccessibleLinks(links []model.LinkDetail) []model.LinkDetail {
+	var failed []model.LinkDetail
+	for _, link := range links {
+		if link.Failure != "" && link.Failure != "rate-limited" && link.Failure != "blocked-host" {
+			failed = append(failed, link)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+func TestHandleReport(t *testing.T) {
+	provider := &mockProvider{result: &model.PageAnalysis{
+		URL:         "https://example.com",
+		HTMLVersion: "HTML5",
+		Title:       `<script>alert("x")</script>`,
+		Headings:    map[string]int{"h1": 2},
+		Links:       model.LinkStats{Internal: 3, External: 1, Inaccessible: 1, Checked: 4},
+		LinksDetail: []model.LinkDetail{
+			{URL: "https://example.com/ok", Checked: true, Status: 200},
+			{URL: "https://example.com/gone", Checked: true, Status: 404, Failure: "http-4xx"},
+		},
+		Warnings: []string{"<b>no</b> meta description"},
+	}}
+	mux := newTestMux(provider)
+	req := httptest.NewRequest(http.MethodGet, "/v1/report?url="+url.QueryEscape("https://example.com"), nil)
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != reportPolicy {
+		t.Errorf("Content-Security-Policy = %q, want %q", got, reportPolicy)
+	}
+	if !provider.opts.IncludeLinks {
+		t.Error("report analysis did not include the links")
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "<script") || strings.Contains(body, "<b>") {
+		t.Errorf("report contains unescaped page text:\n%s", body)
+	}
+	for _, want := range []string{"&lt;script&gt;", "HTML5", "h1 2", "https://example.com/gone", "http-4xx", "&lt;b&gt;no&lt;/b&gt; meta description"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "https://example.com/ok") {
+		t.Error("report lists an accessible link as inaccessible")
+	}
+}
+
+func TestHandleReport_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		err        error
+		wantStatus int
+		wantText   string
+	}{
+		{"missing url", "", nil, http.StatusBadRequest, "query parameter is required"},
+		{"unsupported parameter", "?url=https://example.com&schema=2", nil, http.StatusBadRequest, "unsupported query parameter"},
+		{
+			"unreachable",
+			"?url=https://down.example.com",
+			&errs.AppError{Kind: errs.Unreachable, Message: "<i>cannot reach</i>", UpstreamStatus: 503},
+			http.StatusBadGateway,
+			"&lt;i&gt;cannot reach&lt;/i&gt;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(&mockProvider{err: tt.err})
+			req := httptest.NewRequest(http.MethodGet, "/v1/report"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want text/html", got)
+			}
+			if body := rec.Body.String(); !strings.Contains(body, tt.wantText) {
+				t.Errorf("error page is missing %q:\n%s", tt.wantText, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
+)
+
+// handleAnalyzeStream runs an analysis and streams its progress as
+// Server-Sent Events: fetching, parsing, and checking_links with a running
+// count of the links checked, then a result event with the analysis or an
+// error event with the body a plain request would have failed with. It takes
+// the body of POST /analyze, or for an EventSource the url parameter of GET
+// /analyze. A client that disconnects cancels the analysis.
+func (t *Transport) handleAnalyzeStream(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+	var req *analyzeRequest
+	if r.Method == http.MethodGet {
+		req, ok = t.queryAnalyzeRequest(w, r)
+	} else {
+		req, ok = t.decodeAnalyzeRequest(w, r)
+	}
+	if !ok {
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.renderError(w, http.StatusInternalServerError, "Streaming is not supported by this server.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Keep reverse proxies like nginx from buffering the events.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{w: w, flusher: flusher, t: t}
+	defer stream.close()
+
+	service, targetURL := t.route(r, req.URL)
+	ctx = progress.NewContext(ctx, func(ev progress.Event) { stream.send(ev.Stage, ev) })
+	result, err := service.Analyze(ctx, targetURL, req.options())
+	if err != nil {
+		stream.send("error", errorResponse(err))
+		return
+	}
+	stream.send("result", schemaRenderers[schema](result))
+}
+
+// eventStream writes Server-Sent Events to a response, flushing each one.
+// Once closed it drops events, so a late one can't write to a response the
+// handler has already finished.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	t       *Transport
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// send writes one event with data encoded as JSON.
+func (s *eventStream) send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		s.t.logger.Error("failed to encode event", "event", event, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		// The client went away; its context is canceled with the request.
+		s.closed = true
+		return
+	}
+	s.flusher.Flush()
+}
+
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
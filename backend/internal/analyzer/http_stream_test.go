@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
+)
+
+// progressProvider reports a fetch, a parse and one checked link before
+// returning its result or error.
+type progressProvider struct {
+	mockProvider
+}
+
+func (p *progressProvider) Analyze(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	progress.Report(ctx, progress.Event{Stage: progress.Fetching})
+	progress.Report(ctx, progress.Event{Stage: progress.Parsing})
+	progress.Report(ctx, progress.Event{Stage: progress.CheckingLinks, Checked: 1, Total: 1})
+	return p.mockProvider.Analyze(ctx, targetURL, opts)
+}
+
+func TestHandleAnalyzeStream(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		err       error
+		wantEvent string
+		wantData  string
+	}{
+		{
+			name:      "post",
+			method:    http.MethodPost,
+			target:    "/v1/analyze/stream",
+			body:      `{"url": "https://example.com"}`,
+			wantEvent: "result",
+			wantData:  `"url":"https://example.com"`,
+		},
+		{
+			name:      "get",
+			method:    http.MethodGet,
+			target:    "/v1/analyze/stream?url=" + url.QueryEscape("https://example.com"),
+			wantEvent: "result",
+			wantData:  `"url":"https://example.com"`,
+		},
+		{
+			name:      "analysis error",
+			method:    http.MethodPost,
+			target:    "/v1/analyze/stream",
+			body:      `{"url": "https://down.example.com"}`,
+			err:       &errs.AppError{Kind: errs.Unreachable, Message: "cannot reach"},
+			wantEvent: "error",
+			wantData:  `"status_code":502`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &progressProvider{mockProvider{err: tt.err}}
+			if tt.err == nil {
+				provider.result = &model.PageAnalysis{URL: "https://example.com"}
+			}
+			server := httptest.NewServer(newTestMux(provider))
+			defer server.Close()
+
+			req, err := http.NewRequestWithContext(context.Background(), tt.method, server.URL+tt.target, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("Content-Type = %q, want text/event-stream", got)
+			}
+			events := strings.Split(strings.TrimSuffix(string(body), "\n\n"), "\n\n")
+			want := []string{
+				"event: fetching\ndata: {\"stage\":\"fetching\"}",
+				"event: parsing\ndata: {\"stage\":\"parsing\"}",
+				"event: checking_links\ndata: {\"stage\":\"checking_links\",\"checked\":1,\"total\":1}",
+			}
+			if len(events) != len(want)+1 {
+				t.Fatalf("got events %q, want the progress then one final event", events)
+			}
+			for i, w := range want {
+				if events[i] != w {
+					t.Errorf("event %d = %q, want %q", i, events[i], w)
+				}
+			}
+			end := events[len(want)]
+			if !strings.HasPrefix(end, "event: "+tt.wantEvent+"\n") || !strings.Contains(end, tt.wantData) {
+				t.Errorf("final event = %q, want a %s event with %s", end, tt.wantEvent, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestHandleAnalyzeStream_InvalidRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"post without url", http.MethodPost, "/v1/analyze/stream", `{}`},
+		{"get without url", http.MethodGet, "/v1/analyze/stream", ""},
+		{"get with options", http.MethodGet, "/v1/analyze/stream?url=https://example.com&check_images=true", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(&progressProvider{})
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if got := rec.Header().Get("Content-Type"); got == "text/event-stream" {
+				t.Error("a rejected request started an event stream")
+			}
+		})
+	}
+}
@@ -3,83 +3,709 @@ package analyzer
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Bahjat/page-insight-tool/backend/internal/demo"
 	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"golang.org/x/net/http/httpguts"
 )
 
-const analyzeTimeout = 60 * time.Second
+const (
+	analyzeTimeout = 60 * time.Second
+
+	// uploadMemory is how much of a multipart upload is kept in memory; the
+	// rest is spooled to a temporary file and streamed into the parser.
+	uploadMemory = 1 << 20 // 1 MB
+	// uploadOverhead leaves room for multipart headers and form fields on top
+	// of the file size limit.
+	uploadOverhead = 64 << 10 // 64 KB
+	// sniffLen is how many leading bytes of an upload are sniffed for its
+	// content type.
+	sniffLen = 512
+
+	// maxBatchURLs caps the URLs of one batch request.
+	maxBatchURLs = 20
+	// defaultBatchConcurrency is how many of a batch's URLs are analyzed at
+	// once unless SetBatchConcurrency says otherwise.
+	defaultBatchConcurrency = 4
+)
 
 // Transport handles HTTP requests for page analysis.
 type Transport struct {
-	service *Service
-	logger  *slog.Logger
+	service        *Service
+	demo           *Service
+	jobs           *JobQueue
+	batchWorkers   int
+	prefix         string
+	resultCaching  bool
+	resultMaxAge   time.Duration
+	maxUploadBytes int64
+	logger         *slog.Logger
 }
 
 // NewTransport creates an HTTP transport backed by the given service.
-func NewTransport(service *Service, logger *slog.Logger) *Transport {
-	return &Transport{service: service, logger: logger}
+// maxUploadBytes caps the size of HTML files accepted by the upload endpoint.
+func NewTransport(service *Service, maxUploadBytes int64, logger *slog.Logger) *Transport {
+	return &Transport{service: service, batchWorkers: defaultBatchConcurrency, maxUploadBytes: maxUploadBytes, logger: logger}
+}
+
+// SetBatchConcurrency sets how many URLs of a POST /analyze/batch request
+// are analyzed at once. Values below 1 are ignored.
+func (t *Transport) SetBatchConcurrency(n int) {
+	if n > 0 {
+		t.batchWorkers = n
+	}
+}
+
+// EnableDemo serves the built-in sample page and routes analyze requests for
+// it to the given service instead of the primary one.
+func (t *Transport) EnableDemo(service *Service) {
+	t.demo = service
+}
+
+// EnableJobs serves asynchronous analyses from the given queue: a POST to
+// /analyze/jobs answers 202 with a job to poll at /analyze/jobs/{id}.
+func (t *Transport) EnableJobs(jobs *JobQueue) {
+	t.jobs = jobs
 }
 
-// RegisterRoutes attaches the transport's handlers to the given mux.
-func (t *Transport) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /analyze", t.handleAnalyze)
+// RegisterRoutes attaches the transport's handlers to the given mux under a
+// version prefix such as /v1. The demo page is not part of the API and is
+// served at demo.Path regardless.
+func (t *Transport) RegisterRoutes(mux *http.ServeMux, prefix string) {
+	t.prefix = prefix
+	for pattern, handler := range t.routes(prefix) {
+		mux.HandleFunc(pattern, handler)
+	}
 }
 
+// RegisterAliases serves the routes of RegisterRoutes without their version
+// prefix as well, for clients from before the API was versioned. Responses
+// to them are marked deprecated and link to the versioned route.
+func (t *Transport) RegisterAliases(mux *http.ServeMux, prefix string) {
+	versioned := t.routes(prefix)
+	for pattern, handler := range t.routes("") {
+		if _, ok := versioned[pattern]; ok {
+			continue
+		}
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+prefix+r.URL.Path+`>; rel="successor-version"`)
+			handler(w, r)
+		})
+	}
+}
+
+// routes maps the patterns the transport serves under prefix, given the
+// features enabled, to their handlers.
+func (t *Transport) routes(prefix string) map[string]http.HandlerFunc {
+	routes := map[string]http.HandlerFunc{
+		"POST " + prefix + "/analyze":        t.handleAnalyze,
+		"GET " + prefix + "/analyze":         t.handleAnalyzeQuery,
+		"POST " + prefix + "/analyze/stream": t.handleAnalyzeStream,
+		"GET " + prefix + "/analyze/stream":  t.handleAnalyzeStream,
+		"POST " + prefix + "/analyze/upload": t.handleUpload,
+		"POST " + prefix + "/analyze/batch":  t.handleAnalyzeBatch,
+		"GET " + prefix + "/openapi.json":    t.handleOpenAPI,
+		"GET " + prefix + "/docs":            t.handleDocs,
+		"GET " + prefix + "/report":          t.handleReport,
+	}
+	if t.jobs != nil {
+		routes["POST "+prefix+"/analyze/jobs"] = t.handleSubmitJob
+		routes["GET "+prefix+"/analyze/jobs/{id}"] = t.handleGetJob
+	}
+	if t.service.crawler != nil {
+		routes["POST "+prefix+"/crawl"] = t.handleCrawl
+	}
+	if t.service.history != nil {
+		routes["GET "+prefix+"/analyses"] = t.handleListAnalyses
+		routes["GET "+prefix+"/analyses/{id}"] = t.handleGetAnalysis
+	}
+	if t.demo != nil {
+		routes["GET "+demo.Path] = t.handleDemoPage
+	}
+	return routes
+}
+
+// analyzeRequest names the page to analyze and its options, given in an
+// "options" object or, as before the object existed, at the top level
+// beside "url". A request may use one place or the other, not both.
 type analyzeRequest struct {
 	URL string `json:"url"`
+	requestOptions
+	Options *requestOptions `json:"options"`
+}
+
+// requestOptions are the options of an analyze request as sent. options
+// turns them into the model.AnalyzeOptions of the analysis.
+type requestOptions struct {
+	FollowMetaRefresh bool              `json:"follow_meta_refresh"`
+	CheckPreloads     bool              `json:"check_preloads"`
+	CheckImages       bool              `json:"check_images"`
+	Include           []string          `json:"include"`
+	CheckLinks        string            `json:"check_links"`
+	ExcludeLinks      []string          `json:"exclude_links"`
+	Force             bool              `json:"force"`
+	TimeoutSeconds    int               `json:"timeout_seconds"`
+	Headers           map[string]string `json:"headers"`
+}
+
+// settings returns the options of the request from wherever it gave them.
+func (req *analyzeRequest) settings() *requestOptions {
+	if req.Options != nil {
+		return req.Options
+	}
+	return &req.requestOptions
 }
 
+// includeLinks is the "include" value that adds per-link detail to the result.
+const includeLinks = "links"
+
+// Limits on the "headers" of a request. Only the headers a login or a locale
+// needs may be set; the rest would let a request impersonate another client
+// or confuse the target about which host it is.
+const (
+	maxRequestHeaders     = 20
+	maxRequestHeaderValue = 4 << 10 // 4 KB
+)
+
+var allowedRequestHeaders = []string{"Cookie", "Authorization", "Accept-Language"}
+
+// validateHeader returns the message to reject a "headers" entry with, or ""
+// if it may be sent.
+func validateHeader(name, value string) string {
+	canonical := http.CanonicalHeaderKey(name)
+	if !httpguts.ValidHeaderFieldName(name) ||
+		(!slices.Contains(allowedRequestHeaders, canonical) && !strings.HasPrefix(canonical, "X-")) {
+		return fmt.Sprintf("unsupported \"headers\" name %q; allowed: %s and X-* headers", name, strings.Join(allowedRequestHeaders, ", "))
+	}
+	if !httpguts.ValidHeaderFieldValue(value) || len(value) > maxRequestHeaderValue {
+		return fmt.Sprintf("invalid \"headers\" value for %s; values must be at most 4 KB without control characters", canonical)
+	}
+	return ""
+}
+
+// checkLinksValues are the accepted "check_links" values; omitting the field
+// checks every link.
+var checkLinksValues = []string{model.CheckLinksAll, model.CheckLinksInternal, model.CheckLinksExternal, model.CheckLinksNone}
+
 func (t *Transport) handleAnalyze(w http.ResponseWriter, r *http.Request) {
-	const maxRequestBody = 1 << 20 // 1 MB
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
 
+	req, ok := t.decodeAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+	t.analyze(w, r, schema, req)
+}
+
+// decodeAnalyzeRequest reads the JSON body of an analyze request, rendering
+// the error when it can't.
+func (t *Transport) decodeAnalyzeRequest(w http.ResponseWriter, r *http.Request) (*analyzeRequest, bool) {
 	var req analyzeRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		t.renderError(w, http.StatusBadRequest, "Invalid request body. Please send a JSON object with a \"url\" field.")
+	if msg := decodeRequestBody(w, r, &req, "url"); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return nil, false
+	}
+	return &req, true
+}
+
+// maxRequestBody caps the JSON body of a request.
+const maxRequestBody = 1 << 20 // 1 MB
+
+// decodeRequestBody decodes the body of r, a single JSON object, into v. It
+// returns the message to reject the body with, or "" if it decoded; field
+// names the field the object must have, for the message about a body that
+// isn't one. Fields v doesn't have are rejected rather than ignored, so a
+// misspelt option fails instead of quietly leaving its default in place.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, v any, field string) string {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && !errors.Is(dec.Decode(&json.RawMessage{}), io.EOF) {
+		return "the request body must hold a single JSON object"
+	}
+	if err == nil {
+		return ""
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return fmt.Sprintf("the request body must not exceed %d bytes", tooLarge.Limit)
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("the request body is not valid JSON: %s at byte %d", syntaxErr, syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "the request body is not valid JSON: it ends in the middle of a value"
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return fmt.Sprintf("the %q field must be %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value)
+	}
+	// DisallowUnknownFields reports a field it doesn't know only by message.
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Sprintf("unsupported field %s", name)
+	}
+	return fmt.Sprintf("Invalid request body. Please send a JSON object with a %q field.", field)
+}
+
+// jsonTypeName describes the JSON values that decode into a value of type t.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "an object"
+	}
+}
+
+// handleSubmitJob queues an analysis taking the same body as POST /analyze
+// and answers 202 with the job, without waiting for it.
+func (t *Transport) handleSubmitJob(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+	req, ok := t.decodeAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	j, err := t.jobs.submit(r.Context(), req.URL, req.options(), schema)
+	if err != nil {
+		t.handleServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", t.prefix+"/analyze/jobs/"+j.id)
+	t.renderJSON(w, http.StatusAccepted, j.view())
+}
+
+// handleGetJob reports the state of a job, with its result or error once it
+// has finished.
+func (t *Transport) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	j, ok := t.jobs.get(r.PathValue("id"))
+	if !ok {
+		t.renderError(w, http.StatusNotFound, "No such job. Jobs are forgotten a while after they finish.")
+		return
+	}
+	t.renderJSON(w, http.StatusOK, j.view())
+}
+
+// handleAnalyzeQuery serves GET /analyze?url=..., an analysis with the
+// default options for quick checks from a browser or curl. Only the url,
+// schema and format parameters are taken; other options need a POST.
+func (t *Transport) handleAnalyzeQuery(w http.ResponseWriter, r *http.Request) {
+	// A later GET of the same URL must analyze the page again, not replay an
+	// answer a browser or proxy kept, unless result caching says otherwise
+	// for a successful analysis.
+	w.Header().Set("Cache-Control", "no-store")
+
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+	req, ok := t.queryAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+	t.analyze(w, r, schema, req)
+}
+
+// queryAnalyzeRequest reads an analyze request from the url query parameter
+// of a GET, rendering the error when it is missing, repeated or accompanied
+// by options only a POST takes.
+func (t *Transport) queryAnalyzeRequest(w http.ResponseWriter, r *http.Request) (*analyzeRequest, bool) {
+	req, msg := parseAnalyzeQuery(r.URL.Query(), "schema", "format")
+	if msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return nil, false
+	}
+	return req, true
+}
+
+// parseAnalyzeQuery reads an analyze request with the default options from
+// the url query parameter. It returns the message to reject the query with
+// when url is missing or repeated, or when it has parameters other than url
+// and the others given.
+func parseAnalyzeQuery(query url.Values, others ...string) (*analyzeRequest, string) {
+	for name := range query {
+		if name != "url" && !slices.Contains(others, name) {
+			return nil, fmt.Sprintf("unsupported query parameter %q; this GET takes only %s", name, strings.Join(append([]string{"url"}, others...), ", "))
+		}
+	}
+	switch len(query["url"]) {
+	case 0:
+		return nil, "the \"url\" query parameter is required"
+	case 1:
+		return &analyzeRequest{URL: query.Get("url")}, ""
+	default:
+		return nil, "the \"url\" query parameter must be given once"
+	}
+}
+
+// analyze validates an analyze request and runs it within analyzeTimeout,
+// on the demo service when it targets the sample page.
+func (t *Transport) analyze(w http.ResponseWriter, r *http.Request, schema string, req *analyzeRequest) {
+	format, ok := negotiateFormat(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedFormatMessage(format))
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
+	defer cancel()
+
+	service, targetURL := t.route(r, req.URL)
+	result, err := service.Analyze(ctx, targetURL, req.options())
+	if err != nil {
+		t.handleServiceError(w, err)
+		return
+	}
+
+	t.renderResult(w, r, schema, format, result)
+}
+
+// route picks the service analyzing target: the demo service for the sample
+// page, the primary one otherwise.
+func (t *Transport) route(r *http.Request, target string) (*Service, string) {
+	if t.demo != nil {
+		if demoURL, ok := demoTarget(r, target); ok {
+			return t.demo, demoURL
+		}
+	}
+	return t.service, target
+}
+
+// validate checks the fields of an analyze request, returning the message to
+// reject it with, or "" if it is valid.
+func (req *analyzeRequest) validate() string {
 	if req.URL == "" {
-		t.renderError(w, http.StatusBadRequest, "the \"url\" field is required")
+		return "the \"url\" field is required"
+	}
+	return req.validateOptions()
+}
+
+// validateOptions checks the fields of an analyze request other than its URL.
+func (req *analyzeRequest) validateOptions() string {
+	if req.Options != nil && !reflect.ValueOf(req.requestOptions).IsZero() {
+		return "options go either in \"options\" or at the top level of the request, not both"
+	}
+	return req.settings().validate()
+}
+
+// validate checks the options of an analyze request, returning the message
+// to reject them with, or "" if they are valid.
+func (o *requestOptions) validate() string {
+	for _, section := range o.Include {
+		if section != includeLinks {
+			return fmt.Sprintf("unsupported \"include\" value %q; supported values: %s", section, includeLinks)
+		}
+	}
+	if o.CheckLinks != "" && !slices.Contains(checkLinksValues, o.CheckLinks) {
+		return fmt.Sprintf("unsupported \"check_links\" value %q; supported values: %s", o.CheckLinks, strings.Join(checkLinksValues, ", "))
+	}
+	if o.TimeoutSeconds < 0 {
+		return "the \"timeout_seconds\" field must not be negative"
+	}
+	if len(o.Headers) > maxRequestHeaders {
+		return fmt.Sprintf("at most %d \"headers\" may be set", maxRequestHeaders)
+	}
+	seen := make(map[string]bool, len(o.Headers))
+	for name, value := range o.Headers {
+		if msg := validateHeader(name, value); msg != "" {
+			return msg
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if seen[canonical] {
+			return fmt.Sprintf("the %s header is set more than once in \"headers\"", canonical)
+		}
+		seen[canonical] = true
+	}
+	return ""
+}
+
+// options turns the validated options of an analyze request into those of
+// its analysis. It is the one place their defaults are applied, so the
+// options the service logs are the ones the analysis ran with: every link is
+// checked unless check_links says otherwise, and the analysis may take up to
+// analyzeTimeout unless timeout_seconds is shorter.
+func (req *analyzeRequest) options() model.AnalyzeOptions {
+	o := req.settings()
+	opts := model.AnalyzeOptions{
+		FollowMetaRefresh: o.FollowMetaRefresh,
+		CheckPreloads:     o.CheckPreloads,
+		CheckImages:       o.CheckImages,
+		IncludeLinks:      slices.Contains(o.Include, includeLinks),
+		CheckLinks:        o.CheckLinks,
+		ExcludeLinks:      o.ExcludeLinks,
+		Force:             o.Force,
+		Headers:           o.Headers,
+		Timeout:           analyzeTimeout,
+	}
+	if opts.CheckLinks == "" {
+		opts.CheckLinks = model.CheckLinksAll
+	}
+	// Longer timeouts are clamped: analyzeTimeout stays the ceiling.
+	if o.TimeoutSeconds > 0 {
+		opts.Timeout = min(time.Duration(o.TimeoutSeconds)*time.Second, analyzeTimeout)
+	}
+	return opts
+}
+
+// crawlRequest takes the analyze options, applied to every page, plus the
+// crawl limits and which query parameters tell pages apart. A missing depth
+// means model.DefaultCrawlDepth, so an explicit 0 can ask for the start page
+// alone.
+type crawlRequest struct {
+	analyzeRequest
+	Depth             *int     `json:"depth"`
+	MaxPages          int      `json:"max_pages"`
+	IgnoreQueryParams bool     `json:"ignore_query_params"`
+	StripParams       []string `json:"strip_params"`
+}
+
+// validate checks the fields of a crawl request, returning the message to
+// reject it with, or "" if it is valid.
+func (req *crawlRequest) validate() string {
+	if msg := req.analyzeRequest.validate(); msg != "" {
+		return msg
+	}
+	if slices.Contains(req.StripParams, "") {
+		return "the \"strip_params\" field must not contain empty names"
+	}
+	return ""
+}
+
+func (t *Transport) handleCrawl(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+
+	var req crawlRequest
+	if msg := decodeRequestBody(w, r, &req, "url"); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := req.validate(); msg != "" {
+		t.renderError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	opts := model.CrawlOptions{
+		Depth:             model.DefaultCrawlDepth,
+		MaxPages:          req.MaxPages,
+		IgnoreQueryParams: req.IgnoreQueryParams,
+		StripParams:       req.StripParams,
+		Analyze:           req.options(),
+	}
+	if req.Depth != nil {
+		opts.Depth = *req.Depth
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
+	defer cancel()
+
+	crawl, err := t.service.Crawl(ctx, req.URL, opts)
+	if err != nil {
+		t.handleServiceError(w, err)
+		return
+	}
+
+	t.renderJSON(w, http.StatusOK, renderCrawl(schema, crawl))
+}
+
+func (t *Transport) handleUpload(w http.ResponseWriter, r *http.Request) {
+	schema, ok := negotiateSchema(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedSchemaMessage(schema))
+		return
+	}
+
+	format, ok := negotiateFormat(r)
+	if !ok {
+		t.renderError(w, http.StatusBadRequest, unsupportedFormatMessage(format))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, t.maxUploadBytes+uploadOverhead)
+
+	if err := r.ParseMultipartForm(uploadMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			t.renderError(w, http.StatusRequestEntityTooLarge, t.uploadTooLargeMessage())
+			return
+		}
+		t.renderError(w, http.StatusBadRequest, "Invalid upload. Please send a multipart/form-data request with a \"file\" part.")
+		return
+	}
+	defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		t.renderError(w, http.StatusBadRequest, "the \"file\" part is required")
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	if header.Size > t.maxUploadBytes {
+		t.renderError(w, http.StatusRequestEntityTooLarge, t.uploadTooLargeMessage())
+		return
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		t.renderError(w, http.StatusBadRequest, "The uploaded file could not be read.")
+		return
+	}
+	head = head[:n]
+	if !uploadIsHTML(head, header.Header.Get("Content-Type")) {
+		t.renderError(w, http.StatusUnsupportedMediaType, "The uploaded file does not look like an HTML document.")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), analyzeTimeout)
 	defer cancel()
 
-	result, err := t.service.Analyze(ctx, req.URL)
+	body := io.MultiReader(bytes.NewReader(head), file)
+	result, err := t.service.AnalyzeHTML(ctx, body, r.FormValue("base_url"))
 	if err != nil {
 		t.handleServiceError(w, err)
 		return
 	}
 
-	t.renderJSON(w, http.StatusOK, result)
+	if format == formatCSV {
+		t.renderCSV(w, result)
+		return
+	}
+	t.renderJSON(w, http.StatusOK, schemaRenderers[schema](result))
+}
+
+// uploadIsHTML reports whether an upload starting with head is an HTML
+// document, of the types page fetches analyze: its content sniffs as
+// text/html, or the part is declared application/xhtml+xml and its content
+// sniffs as markup, which an XHTML document opening with an XML declaration
+// does as text/xml.
+func uploadIsHTML(head []byte, declared string) bool {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if sniffed == "text/html" {
+		return true
+	}
+	declaredType, _, _ := mime.ParseMediaType(declared)
+	return declaredType == "application/xhtml+xml" && sniffed == "text/xml"
+}
+
+func (t *Transport) uploadTooLargeMessage() string {
+	return fmt.Sprintf("The uploaded file exceeds the %d MB limit.", t.maxUploadBytes>>20)
+}
+
+func (t *Transport) handleDemoPage(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(demo.Page)
+}
+
+// demoTarget reports whether target refers to the sample page, either by the
+// "demo" shorthand or by this server's own demo page URL, and returns the URL
+// it should be analyzed as.
+func demoTarget(r *http.Request, target string) (string, bool) {
+	if target == demo.Target {
+		return demo.URL, true
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+	if strings.EqualFold(u.Host, r.Host) && u.Path == demo.Path {
+		return target, true
+	}
+	return "", false
 }
 
 func (t *Transport) handleServiceError(w http.ResponseWriter, err error) {
+	setRetryAfter(w, err)
+	resp := errorResponse(err)
+	t.renderJSON(w, resp.StatusCode, resp)
+}
+
+// errorResponse maps a service error to the response that reports it.
+func errorResponse(err error) model.ErrorResponse {
 	var appErr *errs.AppError
-	if errors.As(err, &appErr) {
-		status := http.StatusInternalServerError
-		switch appErr.Kind {
-		case errs.InvalidInput:
-			status = http.StatusBadRequest
-		case errs.Unreachable:
-			status = http.StatusBadGateway
-		case errs.Timeout:
-			status = http.StatusGatewayTimeout
-		case errs.ParsingFailed, errs.Unknown:
+	if !errors.As(err, &appErr) {
+		return model.ErrorResponse{
+			Error:      http.StatusText(http.StatusInternalServerError),
+			StatusCode: http.StatusInternalServerError,
+			Message:    "An unexpected error occurred.",
 		}
-		t.renderError(w, status, appErr.Message)
-		return
 	}
 
-	t.renderError(w, http.StatusInternalServerError, "An unexpected error occurred.")
+	status := http.StatusInternalServerError
+	switch appErr.Kind {
+	case errs.InvalidInput:
+		status = http.StatusBadRequest
+	case errs.Unreachable:
+		status = http.StatusBadGateway
+	case errs.Timeout:
+		status = http.StatusGatewayTimeout
+	case errs.Forbidden:
+		status = http.StatusForbidden
+	case errs.UnsupportedContent:
+		status = http.StatusUnsupportedMediaType
+	case errs.Unavailable:
+		status = http.StatusServiceUnavailable
+	case errs.Overloaded:
+		status = http.StatusTooManyRequests
+	case errs.ParsingFailed, errs.Unknown:
+	}
+	return model.ErrorResponse{
+		Error:          http.StatusText(status),
+		StatusCode:     status,
+		UpstreamStatus: appErr.UpstreamStatus,
+		Message:        appErr.Message,
+		Detail:         appErr.Detail,
+	}
 }
 
 func (t *Transport) renderJSON(w http.ResponseWriter, status int, data any) {
@@ -95,6 +721,90 @@ func (t *Transport) renderJSON(w http.ResponseWriter, status int, data any) {
 	_, _ = buf.WriteTo(w)
 }
 
+// csvHeader names the columns of a CSV analysis. The first row after it
+// describes the page, with the link columns empty; each link of an analysis
+// that includes them follows in a row of its own, with only the url and link
+// columns set.
+var csvHeader = []string{
+	"row", "url", "final_url", "title", "html_version",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+	"internal_links", "external_links", "inaccessible_links", "has_login_form",
+	"link_type", "link_status", "link_failure", "link_latency_ms",
+}
+
+// renderCSV sends an analysis as CSV, named for download after the analyzed
+// host.
+func (t *Transport) renderCSV(w http.ResponseWriter, result *model.PageAnalysis) {
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	// A negative count means the links were not checked, which an empty
+	// cell says without passing for a number.
+	var inaccessible string
+	if result.Links.Inaccessible >= 0 {
+		inaccessible = strconv.Itoa(result.Links.Inaccessible)
+	}
+	rows := [][]string{csvHeader, {
+		"page", spreadsheetSafe(result.URL), spreadsheetSafe(result.FinalURL), spreadsheetSafe(result.Title), result.HTMLVersion,
+		strconv.Itoa(result.Headings["h1"]), strconv.Itoa(result.Headings["h2"]), strconv.Itoa(result.Headings["h3"]),
+		strconv.Itoa(result.Headings["h4"]), strconv.Itoa(result.Headings["h5"]), strconv.Itoa(result.Headings["h6"]),
+		strconv.Itoa(result.Links.Internal), strconv.Itoa(result.Links.External), inaccessible,
+		strconv.FormatBool(result.HasLoginForm),
+		"", "", "", "",
+	}}
+	for _, link := range result.LinksDetail {
+		linkType := "external"
+		if link.Internal {
+			linkType = "internal"
+		}
+		var status, latency string
+		if link.Checked {
+			status, latency = strconv.Itoa(link.Status), strconv.FormatInt(link.ElapsedMs, 10)
+		}
+		row := make([]string, len(csvHeader))
+		row[0], row[1] = "link", spreadsheetSafe(link.URL)
+		copy(row[len(row)-4:], []string{linkType, status, link.Failure, latency})
+		rows = append(rows, row)
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		t.logger.Error("failed to encode response", "error", err)
+		http.Error(w, `{"error":"Internal Server Error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": csvFilename(result.URL)}))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+// spreadsheetSafe keeps a cell taken from the page, like its title, from
+// being run as a formula when the CSV is opened in a spreadsheet, by
+// prefixing a leading =, +, -, @, tab or carriage return with a quote.
+func spreadsheetSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// csvFilename names a CSV analysis after the analyzed host, like
+// example.com-analysis.csv, keeping only characters a hostname has.
+func csvFilename(pageURL string) string {
+	var host string
+	if u, err := url.Parse(pageURL); err == nil {
+		host = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(u.Hostname()))
+	}
+	if host == "" {
+		return "analysis.csv"
+	}
+	return host + "-analysis.csv"
+}
+
 func (t *Transport) renderError(w http.ResponseWriter, status int, message string) {
 	t.renderJSON(w, status, model.ErrorResponse{
 		Error:      http.StatusText(status),
@@ -1,34 +1,62 @@
 package analyzer
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Bahjat/page-insight-tool/backend/internal/demo"
 	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
 )
 
+var errPrimaryUsed = errors.New("primary provider must not be used")
+
+const testMaxUpload = 1 << 20
+
 // mockProvider implements PageInsightProvider for testing.
 type mockProvider struct {
 	result *model.PageAnalysis
 	err    error
+
+	url      string
+	opts     model.AnalyzeOptions
+	uploaded []byte
+	baseURL  string
 }
 
-func (m *mockProvider) Analyze(_ context.Context, _ string) (*model.PageAnalysis, error) {
+func (m *mockProvider) Analyze(_ context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	m.url, m.opts = targetURL, opts
+	return m.result, m.err
+}
+
+func (m *mockProvider) AnalyzeHTML(_ context.Context, body io.Reader, baseURL string) (*model.PageAnalysis, error) {
+	m.uploaded, _ = io.ReadAll(body)
+	m.baseURL = baseURL
 	return m.result, m.err
 }
 
 func newTestMux(provider PageInsightProvider) *http.ServeMux {
 	logger := slog.Default()
 	svc := NewService(provider, logger)
-	transport := NewTransport(svc, logger)
+	transport := NewTransport(svc, testMaxUpload, logger)
 	mux := http.NewServeMux()
-	transport.RegisterRoutes(mux)
+	transport.RegisterRoutes(mux, "/v1")
 	return mux
 }
 
@@ -45,7 +73,7 @@ func TestHandleAnalyze_Success(t *testing.T) {
 	mux := newTestMux(provider)
 
 	body := `{"url": "https://example.com"}`
-	req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(body))
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(body))
 	rec := httptest.NewRecorder()
 
 	mux.ServeHTTP(rec, req)
@@ -63,6 +91,262 @@ func TestHandleAnalyze_Success(t *testing.T) {
 	}
 }
 
+func TestRegisterAliases(t *testing.T) {
+	logger := slog.Default()
+	svc := NewService(&mockProvider{result: &model.PageAnalysis{URL: "https://example.com"}}, logger)
+	jobs := NewJobQueue(svc, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+	t.Cleanup(func() { _ = jobs.Shutdown(context.Background()) })
+	transport := NewTransport(svc, testMaxUpload, logger)
+	transport.EnableJobs(jobs)
+	transport.EnableDemo(NewService(demo.NewEngine(), logger))
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	transport.RegisterAliases(mux, "/v1")
+
+	tests := []struct {
+		path       string
+		deprecated bool
+	}{
+		{"/v1/analyze", false},
+		{"/analyze", true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"url": "https://example.com"}`)))
+
+		var result struct {
+			SchemaVersion string `json:"schema_version"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil || rec.Code != http.StatusOK || result.SchemaVersion != latestSchema {
+			t.Errorf("POST %s: status %d, schema_version %q, want 200 and %s", tt.path, rec.Code, result.SchemaVersion, latestSchema)
+		}
+		if got := rec.Header().Get("Deprecation") == "true"; got != tt.deprecated {
+			t.Errorf("POST %s: deprecated = %v, want %v", tt.path, got, tt.deprecated)
+		}
+		if tt.deprecated && rec.Header().Get("Link") != `</v1/analyze>; rel="successor-version"` {
+			t.Errorf("POST %s: Link = %q, want the /v1 route", tt.path, rec.Header().Get("Link"))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/analyze/jobs", strings.NewReader(`{"url": "https://example.com"}`)))
+	if got := rec.Header().Get("Location"); !strings.HasPrefix(got, "/v1/analyze/jobs/") {
+		t.Errorf("job submitted through the alias has Location %q, want the /v1 route", got)
+	}
+}
+
+func TestHandleAnalyzeQuery_Success(t *testing.T) {
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com/a?b=c", Title: "Example"}}
+	mux := newTestMux(provider)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/analyze?url="+url.QueryEscape("https://example.com/a?b=c"), nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+	var result model.PageAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Title != "Example" || provider.url != "https://example.com/a?b=c" {
+		t.Errorf("analyzed %q with title %q, want the unescaped url parameter", provider.url, result.Title)
+	}
+}
+
+func TestHandleAnalyzeQuery_ErrorCases(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		err        error
+		wantStatus int
+	}{
+		{"missing url", "", nil, http.StatusBadRequest},
+		{"empty url", "?url=", nil, http.StatusBadRequest},
+		{"repeated url", "?url=https://a.example&url=https://b.example", nil, http.StatusBadRequest},
+		{"unsupported parameter", "?url=https://example.com&check_images=true", nil, http.StatusBadRequest},
+		{"unsupported schema", "?url=https://example.com&schema=9", nil, http.StatusBadRequest},
+		{
+			"invalid input",
+			"?url=ftp://bad",
+			&errs.AppError{Kind: errs.InvalidInput, Message: "bad url"},
+			http.StatusBadRequest,
+		},
+		{
+			"unreachable",
+			"?url=https://down.example.com",
+			&errs.AppError{Kind: errs.Unreachable, Message: "cannot reach"},
+			http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(&mockProvider{err: tt.err})
+			req := httptest.NewRequest(http.MethodGet, "/v1/analyze"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+		})
+	}
+}
+
+func TestHandleAnalyze_Options(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want model.AnalyzeOptions
+	}{
+		{name: "defaults", body: `{"url": "https://example.com"}`},
+		{
+			name: "follow meta refresh",
+			body: `{"url": "https://example.com", "follow_meta_refresh": true}`,
+			want: model.AnalyzeOptions{FollowMetaRefresh: true},
+		},
+		{
+			name: "check preloads",
+			body: `{"url": "https://example.com", "check_preloads": true}`,
+			want: model.AnalyzeOptions{CheckPreloads: true},
+		},
+		{
+			name: "check images",
+			body: `{"url": "https://example.com", "check_images": true}`,
+			want: model.AnalyzeOptions{CheckImages: true},
+		},
+		{
+			name: "include links",
+			body: `{"url": "https://example.com", "include": ["links"]}`,
+			want: model.AnalyzeOptions{IncludeLinks: true},
+		},
+		{
+			name: "check internal links",
+			body: `{"url": "https://example.com", "check_links": "internal"}`,
+			want: model.AnalyzeOptions{CheckLinks: model.CheckLinksInternal},
+		},
+		{
+			name: "exclude links",
+			body: `{"url": "https://example.com", "exclude_links": ["*/logout*"]}`,
+			want: model.AnalyzeOptions{ExcludeLinks: []string{"*/logout*"}},
+		},
+		{
+			name: "force",
+			body: `{"url": "https://example.com", "force": true}`,
+			want: model.AnalyzeOptions{Force: true},
+		},
+		{
+			name: "headers",
+			body: `{"url": "https://example.com", "headers": {"Cookie": "session=abc", "x-tenant": "acme"}}`,
+			want: model.AnalyzeOptions{Headers: model.RequestHeaders{"Cookie": "session=abc", "x-tenant": "acme"}},
+		},
+		{
+			name: "timeout",
+			body: `{"url": "https://example.com", "timeout_seconds": 5}`,
+			want: model.AnalyzeOptions{Timeout: 5 * time.Second},
+		},
+		{
+			name: "timeout beyond the ceiling",
+			body: `{"url": "https://example.com", "timeout_seconds": 600}`,
+			want: model.AnalyzeOptions{Timeout: analyzeTimeout},
+		},
+		{
+			name: "options object",
+			body: `{"url": "https://example.com", "options": {"check_images": true, "check_links": "external", "timeout_seconds": 5}}`,
+			want: model.AnalyzeOptions{CheckImages: true, CheckLinks: model.CheckLinksExternal, Timeout: 5 * time.Second},
+		},
+		{
+			name: "empty options object",
+			body: `{"url": "https://example.com", "options": {}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com"}}
+			mux := newTestMux(provider)
+			req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if want := withDefaults(tt.want); !reflect.DeepEqual(provider.opts, want) {
+				t.Errorf("options = %+v, want %+v", provider.opts, want)
+			}
+		})
+	}
+}
+
+// withDefaults fills in the options a request leaves out the way the
+// transport does.
+func withDefaults(opts model.AnalyzeOptions) model.AnalyzeOptions {
+	if opts.CheckLinks == "" {
+		opts.CheckLinks = model.CheckLinksAll
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = analyzeTimeout
+	}
+	return opts
+}
+
+func TestHandleAnalyze_StrictBody(t *testing.T) {
+	mux := newTestMux(&mockProvider{result: &model.PageAnalysis{}})
+
+	tests := []struct {
+		name    string
+		body    string
+		wantMsg string
+	}{
+		{"unknown field", `{"url": "https://example.com", "check_imagez": true}`, `unsupported field "check_imagez"`},
+		{"unknown option", `{"url": "https://example.com", "options": {"bogus": 1}}`, `unsupported field "bogus"`},
+		{"string for an integer", `{"url": "https://example.com", "timeout_seconds": "5"}`, `the "timeout_seconds" field must be an integer, not string`},
+		{"fraction for an integer", `{"url": "https://example.com", "timeout_seconds": 1.5}`, `the "timeout_seconds" field must be an integer, not number 1.5`},
+		{"number for a string", `{"url": 5}`, `the "url" field must be a string, not number`},
+		{"string for an array", `{"url": "https://example.com", "options": {"include": "links"}}`, `the "options.include" field must be an array, not string`},
+		{"array for an object", `{"url": "https://example.com", "options": []}`, `the "options" field must be an object, not array`},
+		{"string for a boolean", `{"url": "https://example.com", "options": {"force": "yes"}}`, `the "options.force" field must be a boolean, not string`},
+		{"syntax error", `{"url" "https://example.com"}`, "the request body is not valid JSON: invalid character"},
+		{"truncated", `{"url": "https://example.com"`, "ends in the middle of a value"},
+		{"not an object", `["https://example.com"]`, `Please send a JSON object with a "url" field.`},
+		{"two objects", `{"url": "https://example.com"} {}`, "must hold a single JSON object"},
+		{"options in both places", `{"url": "https://example.com", "force": true, "options": {"check_images": true}}`, "not both"},
+		{"too large", `{"url": "https://example.com/` + strings.Repeat("a", maxRequestBody) + `"}`, "must not exceed 1048576 bytes"},
+		{"invalid option value", `{"url": "https://example.com", "options": {"check_links": "some"}}`, `unsupported "check_links" value "some"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(resp.Message, tt.wantMsg) {
+				t.Errorf("message = %q, want it to contain %q", resp.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
 func TestHandleAnalyze_ErrorCases(t *testing.T) {
 	mux := newTestMux(&mockProvider{})
 
@@ -75,16 +359,23 @@ func TestHandleAnalyze_ErrorCases(t *testing.T) {
 		{"empty URL", http.MethodPost, `{"url": ""}`, http.StatusBadRequest},
 		{"missing body", http.MethodPost, "", http.StatusBadRequest},
 		{"malformed JSON", http.MethodPost, `{invalid json`, http.StatusBadRequest},
-		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"unsupported include", http.MethodPost, `{"url": "https://example.com", "include": ["images"]}`, http.StatusBadRequest},
+		{"unsupported check_links", http.MethodPost, `{"url": "https://example.com", "check_links": "some"}`, http.StatusBadRequest},
+		{"negative timeout", http.MethodPost, `{"url": "https://example.com", "timeout_seconds": -1}`, http.StatusBadRequest},
+		{"Host header", http.MethodPost, `{"url": "https://example.com", "headers": {"Host": "internal"}}`, http.StatusBadRequest},
+		{"unsupported header", http.MethodPost, `{"url": "https://example.com", "headers": {"User-Agent": "x"}}`, http.StatusBadRequest},
+		{"header value with a newline", http.MethodPost, `{"url": "https://example.com", "headers": {"Cookie": "a\r\nHost: x"}}`, http.StatusBadRequest},
+		{"header set twice", http.MethodPost, `{"url": "https://example.com", "headers": {"Cookie": "a", "cookie": "b"}}`, http.StatusBadRequest},
+		{"wrong method", http.MethodPut, "", http.StatusMethodNotAllowed},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var req *http.Request
-			if tt.body == "" && tt.method == http.MethodGet {
-				req = httptest.NewRequest(tt.method, "/analyze", nil)
+			if tt.body == "" && tt.method == http.MethodPut {
+				req = httptest.NewRequest(tt.method, "/v1/analyze", nil)
 			} else {
-				req = httptest.NewRequest(tt.method, "/analyze", strings.NewReader(tt.body))
+				req = httptest.NewRequest(tt.method, "/v1/analyze", strings.NewReader(tt.body))
 			}
 			rec := httptest.NewRecorder()
 
@@ -122,12 +413,24 @@ func TestHandleAnalyze_ServiceErrors(t *testing.T) {
 			`{"url": "https://slow.example.com"}`,
 			http.StatusGatewayTimeout,
 		},
+		{
+			"robots.txt disallows",
+			&errs.AppError{Kind: errs.Forbidden, Message: "The site's robots.txt does not allow PageInsightBot to fetch this page."},
+			`{"url": "https://example.com/private"}`,
+			http.StatusForbidden,
+		},
+		{
+			"not an HTML page",
+			&errs.AppError{Kind: errs.UnsupportedContent, Message: "The provided URL returned application/pdf content, not an HTML page."},
+			`{"url": "https://example.com/report.pdf"}`,
+			http.StatusUnsupportedMediaType,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mux := newTestMux(&mockProvider{err: tt.err})
-			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(tt.body))
+			req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(tt.body))
 			rec := httptest.NewRecorder()
 
 			mux.ServeHTTP(rec, req)
@@ -138,3 +441,607 @@ func TestHandleAnalyze_ServiceErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleAnalyze_HeadersRedactedInLogs(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com"}}
+	transport := NewTransport(NewService(provider, logger), testMaxUpload, logger)
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+
+	body := `{"url": "https://example.com", "headers": {"Authorization": "Bearer s3cret-token"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if strings.Contains(logs.String(), "s3cret-token") {
+		t.Errorf("logs contain the header value: %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), `"Authorization":"[REDACTED]"`) {
+		t.Errorf("logs = %s, want the header name with its value redacted", logs.String())
+	}
+	if !strings.Contains(logs.String(), `"check_links":"all"`) {
+		t.Errorf("logs = %s, want the options with their defaults applied", logs.String())
+	}
+	if got := provider.opts.Headers["Authorization"]; got != "Bearer s3cret-token" {
+		t.Errorf("provider got Authorization %q, want the value passed on", got)
+	}
+}
+
+// slowProvider blocks until the analysis deadline.
+type slowProvider struct{ mockProvider }
+
+func (p *slowProvider) Analyze(ctx context.Context, _ string, _ model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	<-ctx.Done()
+	return nil, &errs.AppError{Kind: errs.Unreachable, Message: "cannot reach", Cause: ctx.Err()}
+}
+
+func TestHandleAnalyze_RequestTimeout(t *testing.T) {
+	mux := newTestMux(&slowProvider{})
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(`{"url": "https://slow.example.com", "timeout_seconds": 1}`))
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("analysis took %v, want it to end after the requested second", elapsed)
+	}
+}
+
+func newDemoTestMux() *http.ServeMux {
+	logger := slog.Default()
+	transport := NewTransport(NewService(&mockProvider{err: errPrimaryUsed}, logger), testMaxUpload, logger)
+	transport.EnableDemo(NewService(demo.NewEngine(), logger))
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	return mux
+}
+
+// TestHandleAnalyze_Demo runs the full pipeline against the built-in sample
+// page and pins its documented, deterministic result.
+func TestHandleAnalyze_Demo(t *testing.T) {
+	mux := newDemoTestMux()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(`{"url": "demo"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var result model.PageAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if result.URL != demo.URL {
+		t.Errorf("URL = %q, want %q", result.URL, demo.URL)
+	}
+	if result.Title != "Page Insight Demo" {
+		t.Errorf("Title = %q, want %q", result.Title, "Page Insight Demo")
+	}
+	if result.HTMLVersion != "HTML5" {
+		t.Errorf("HTMLVersion = %q, want %q", result.HTMLVersion, "HTML5")
+	}
+	wantHeadings := map[string]int{"h1": 1, "h2": 2, "h3": 1, "h4": 0, "h5": 0, "h6": 0}
+	for level, count := range wantHeadings {
+		if result.Headings[level] != count {
+			t.Errorf("Headings[%s] = %d, want %d", level, result.Headings[level], count)
+		}
+	}
+	wantLinks := model.LinkStats{Internal: 4, External: 2, Inaccessible: 2, InternalInaccessible: 1, ExternalInaccessible: 1, HTTPErrors: 2, Checked: 5, EstimatedInaccessible: 2, Fragment: 1}
+	if !reflect.DeepEqual(result.Links, wantLinks) {
+		t.Errorf("Links = %+v, want %+v", result.Links, wantLinks)
+	}
+	if !result.HasLoginForm {
+		t.Error("HasLoginForm = false, want true")
+	}
+}
+
+func TestHandleAnalyze_DemoSelfURL(t *testing.T) {
+	mux := newDemoTestMux()
+
+	body := `{"url": "http://example.com/demo/page"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var result model.PageAnalysis
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.URL != "http://example.com/demo/page" {
+		t.Errorf("URL = %q, want the self URL", result.URL)
+	}
+	if result.Title != "Page Insight Demo" {
+		t.Errorf("Title = %q, want %q", result.Title, "Page Insight Demo")
+	}
+}
+
+func TestHandleDemoPage(t *testing.T) {
+	mux := newDemoTestMux()
+
+	req := httptest.NewRequest(http.MethodGet, demo.Path, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if rec.Body.String() != string(demo.Page) {
+		t.Error("body does not match the embedded sample page")
+	}
+}
+
+func newUploadRequest(t *testing.T, filename string, content []byte, baseURL string) *http.Request {
+	t.Helper()
+	return newTypedUploadRequest(t, filename, "application/octet-stream", content, baseURL)
+}
+
+// newTypedUploadRequest is newUploadRequest with the file part declared as
+// contentType.
+func newTypedUploadRequest(t *testing.T, filename, contentType string, content []byte, baseURL string) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if baseURL != "" {
+		_ = mw.WriteField("base_url", baseURL)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		t.Fatalf("failed to create form file: %v", err)
+	}
+	_, _ = part.Write(content)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze/upload", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestHandleUpload_Success(t *testing.T) {
+	page := []byte(`<!DOCTYPE html><html><head><title>Uploaded</title></head><body></body></html>`)
+	provider := &mockProvider{result: &model.PageAnalysis{Title: "Uploaded"}}
+	mux := newTestMux(provider)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newUploadRequest(t, "page.html", page, "https://example.com/page"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !bytes.Equal(provider.uploaded, page) {
+		t.Errorf("provider received %q, want the uploaded file", provider.uploaded)
+	}
+	if provider.baseURL != "https://example.com/page" {
+		t.Errorf("baseURL = %q, want %q", provider.baseURL, "https://example.com/page")
+	}
+}
+
+func TestHandleUpload_XHTML(t *testing.T) {
+	page := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>XHTML</title></head><body></body></html>`)
+	provider := &mockProvider{result: &model.PageAnalysis{Title: "XHTML"}}
+	mux := newTestMux(provider)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newTypedUploadRequest(t, "page.xhtml", "application/xhtml+xml", page, ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !bytes.Equal(provider.uploaded, page) {
+		t.Errorf("provider received %q, want the uploaded file", provider.uploaded)
+	}
+}
+
+func TestHandleUpload_Rejections(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	oversized := append([]byte("<!DOCTYPE html><html><body>"), bytes.Repeat([]byte("a"), testMaxUpload+1)...)
+
+	tests := []struct {
+		name       string
+		req        func(t *testing.T) *http.Request
+		wantStatus int
+	}{
+		{
+			name:       "oversized file",
+			req:        func(t *testing.T) *http.Request { return newUploadRequest(t, "big.html", oversized, "") },
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "png renamed to html",
+			req:        func(t *testing.T) *http.Request { return newUploadRequest(t, "image.html", png, "") },
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "plain text",
+			req: func(t *testing.T) *http.Request {
+				return newTypedUploadRequest(t, "notes.html", "text/html", []byte("just some notes, no markup"), "")
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "csv",
+			req: func(t *testing.T) *http.Request {
+				return newTypedUploadRequest(t, "data.csv", "text/csv", []byte("url,title\nhttps://example.com,Home\n"), "")
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "xml not declared as xhtml",
+			req: func(t *testing.T) *http.Request {
+				return newUploadRequest(t, "feed.html", []byte(`<?xml version="1.0"?><rss></rss>`), "")
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "not multipart",
+			req: func(_ *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/v1/analyze/upload", strings.NewReader(`{"url": "x"}`))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &mockProvider{result: &model.PageAnalysis{}}
+			mux := newTestMux(provider)
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, tt.req(t))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if provider.uploaded != nil {
+				t.Error("provider should not be called for a rejected upload")
+			}
+		})
+	}
+}
+
+func TestHandleAnalyze_TimeoutDetail(t *testing.T) {
+	const message = "Analysis timed out while checking links (3 of 10 checked)."
+	provider := &mockProvider{err: &errs.AppError{
+		Kind:    errs.Timeout,
+		Message: message,
+		Detail:  &model.TimeoutDetail{Phase: "link_check", LinksChecked: 3, LinksTotal: 10},
+	}}
+	mux := newTestMux(provider)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(`{"url": "https://example.com"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+
+	var resp struct {
+		Message string              `json:"message"`
+		Detail  model.TimeoutDetail `json:"detail"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Detail.Phase != "link_check" || resp.Detail.LinksChecked != 3 || resp.Detail.LinksTotal != 10 {
+		t.Errorf("detail = %+v, want link_check with 3/10 links", resp.Detail)
+	}
+	if resp.Message != message {
+		t.Errorf("message = %q, want the phase-specific message", resp.Message)
+	}
+}
+
+func TestHandleAnalyze_UpstreamError(t *testing.T) {
+	provider := &mockProvider{err: &errs.AppError{
+		Kind:           errs.Unreachable,
+		UpstreamStatus: http.StatusForbidden,
+		Message:        "The provided URL returned an error status.",
+		Detail:         &model.UpstreamErrorDetail{Title: "Attention Required! | Cloudflare"},
+	}}
+	mux := newTestMux(provider)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(`{"url": "https://example.com"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadGateway)
+	}
+	var resp struct {
+		UpstreamStatus int                       `json:"upstream_status"`
+		Detail         model.UpstreamErrorDetail `json:"detail"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.UpstreamStatus != http.StatusForbidden || resp.Detail.Title != "Attention Required! | Cloudflare" {
+		t.Errorf("response = %+v, want the upstream 403 and its page title", resp)
+	}
+}
+
+// mockCrawler implements SiteCrawler for testing.
+type mockCrawler struct {
+	crawl *model.SiteCrawl
+	err   error
+
+	opts model.CrawlOptions
+}
+
+func (m *mockCrawler) Crawl(_ context.Context, _ string, opts model.CrawlOptions) (*model.SiteCrawl, error) {
+	m.opts = opts
+	return m.crawl, m.err
+}
+
+func newCrawlTestMux(crawler SiteCrawler) *http.ServeMux {
+	logger := slog.Default()
+	svc := NewService(&mockProvider{err: errPrimaryUsed}, logger)
+	svc.EnableCrawl(crawler)
+	transport := NewTransport(svc, testMaxUpload, logger)
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	return mux
+}
+
+func TestHandleCrawl(t *testing.T) {
+	crawler := &mockCrawler{crawl: &model.SiteCrawl{
+		URL: "https://example.com/",
+		Pages: []model.CrawledPage{
+			{URL: "https://example.com/", Analysis: &model.PageAnalysis{URL: "https://example.com/", Title: "Home"}},
+			{URL: "https://example.com/gone", Depth: 1, Error: "The provided URL returned an error status."},
+		},
+		Summary: model.CrawlSummary{PagesAnalyzed: 1, PagesFailed: 1},
+	}}
+	mux := newCrawlTestMux(crawler)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/crawl", strings.NewReader(`{"url": "https://example.com/", "check_links": "internal"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	want := model.CrawlOptions{
+		Depth:   model.DefaultCrawlDepth,
+		Analyze: withDefaults(model.AnalyzeOptions{CheckLinks: model.CheckLinksInternal}),
+	}
+	if !reflect.DeepEqual(crawler.opts, want) {
+		t.Errorf("opts = %+v, want %+v", crawler.opts, want)
+	}
+
+	var got struct {
+		Pages []struct {
+			URL      string `json:"url"`
+			Analysis *struct {
+				SchemaVersion string `json:"schema_version"`
+				Title         string `json:"title"`
+			} `json:"analysis"`
+			Error string `json:"error"`
+		} `json:"pages"`
+		Summary model.CrawlSummary `json:"summary"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got.Pages) != 2 || got.Pages[0].Analysis == nil || got.Pages[0].Analysis.SchemaVersion != latestSchema ||
+		got.Pages[0].Analysis.Title != "Home" || got.Pages[1].Analysis != nil || got.Pages[1].Error == "" {
+		t.Errorf("pages = %+v, want a rendered analysis and an error", got.Pages)
+	}
+	if got.Summary.PagesFailed != 1 {
+		t.Errorf("summary = %+v, want one failed page", got.Summary)
+	}
+}
+
+func TestHandleCrawl_Limits(t *testing.T) {
+	crawler := &mockCrawler{crawl: &model.SiteCrawl{}}
+	mux := newCrawlTestMux(crawler)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/crawl", strings.NewReader(`{"url": "https://example.com/", "depth": 0, "max_pages": 3}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if crawler.opts.Depth != 0 || crawler.opts.MaxPages != 3 {
+		t.Errorf("opts = %+v, want depth 0 and 3 pages", crawler.opts)
+	}
+}
+
+func TestHandleCrawl_QueryParams(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantIgnore bool
+		wantStrip  []string
+	}{
+		{
+			name:       "ignore query",
+			body:       `{"url": "https://example.com/", "ignore_query_params": true}`,
+			wantStatus: http.StatusOK,
+			wantIgnore: true,
+		},
+		{
+			name:       "strip params",
+			body:       `{"url": "https://example.com/", "strip_params": ["utm_source", "sort"]}`,
+			wantStatus: http.StatusOK,
+			wantStrip:  []string{"utm_source", "sort"},
+		},
+		{
+			name:       "empty param name",
+			body:       `{"url": "https://example.com/", "strip_params": [""]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := &mockCrawler{crawl: &model.SiteCrawl{}}
+			mux := newCrawlTestMux(crawler)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/crawl", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if crawler.opts.IgnoreQueryParams != tt.wantIgnore || !slices.Equal(crawler.opts.StripParams, tt.wantStrip) {
+				t.Errorf("opts = %+v, want ignore %v and strip %v", crawler.opts, tt.wantIgnore, tt.wantStrip)
+			}
+		})
+	}
+}
+
+func TestHandleCrawl_NotEnabled(t *testing.T) {
+	mux := newTestMux(&mockProvider{})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/crawl", strings.NewReader(`{"url": "https://example.com/"}`))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAnalyze_CSV(t *testing.T) {
+	provider := &mockProvider{result: &model.PageAnalysis{
+		URL:      "https://Shop.Example.com/sale",
+		Title:    `Sale, "50%" off`,
+		Headings: map[string]int{"h1": 1, "h2": 3},
+		Links:    model.LinkStats{Internal: 1, External: 1, Inaccessible: 1},
+		LinksDetail: []model.LinkDetail{
+			{URL: "https://shop.example.com/a", Internal: true, Checked: true, Status: 200, ElapsedMs: 40},
+			{URL: "https://other.example/b", Checked: true, Status: 404, Failure: "http-4xx", ElapsedMs: 90},
+			{URL: "https://other.example/c"},
+		},
+	}}
+	mux := newTestMux(provider)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		accept string
+	}{
+		{"format parameter", http.MethodPost, "/v1/analyze?format=csv", ""},
+		{"accept header", http.MethodPost, "/v1/analyze", "application/json;q=0.5, text/csv"},
+		{"get", http.MethodGet, "/v1/analyze?url=https://shop.example.com/sale&format=csv", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(`{"url": "https://shop.example.com/sale", "include": ["links"]}`))
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+				t.Fatalf("status = %d, Content-Type = %q, want a 200 CSV", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=shop.example.com-analysis.csv` {
+				t.Errorf("Content-Disposition = %q, want the file named after the host", got)
+			}
+			rows, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("response is not valid CSV: %v", err)
+			}
+			want := [][]string{
+				csvHeader,
+				{"page", "https://Shop.Example.com/sale", "", `Sale, "50%" off`, "", "1", "3", "0", "0", "0", "0", "1", "1", "1", "false", "", "", "", ""},
+				{"link", "https://shop.example.com/a", "", "", "", "", "", "", "", "", "", "", "", "", "", "internal", "200", "", "40"},
+				{"link", "https://other.example/b", "", "", "", "", "", "", "", "", "", "", "", "", "", "external", "404", "http-4xx", "90"},
+				{"link", "https://other.example/c", "", "", "", "", "", "", "", "", "", "", "", "", "", "external", "", "", ""},
+			}
+			if !reflect.DeepEqual(rows, want) {
+				t.Errorf("rows = %q, want %q", rows, want)
+			}
+		})
+	}
+}
+
+func TestHandleAnalyze_CSVUncheckedLinks(t *testing.T) {
+	mux := newTestMux(&mockProvider{result: &model.PageAnalysis{
+		URL:   "https://example.com",
+		Links: model.LinkStats{Internal: 2, External: 1, Inaccessible: -1},
+	}})
+	req := httptest.NewRequest(http.MethodPost, "/v1/analyze?format=csv", strings.NewReader(`{"url": "https://example.com"}`))
+	rec := httptest.NewRecorder()
+
+	mux.ServeHTTP(rec, req)
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("rows = %v, err = %v, want the header and the page row", rows, err)
+	}
+	column := slices.Index(csvHeader, "inaccessible_links")
+	if got := rows[1][column]; got != "" {
+		t.Errorf("inaccessible_links = %q, want an empty cell for unchecked links", got)
+	}
+}
+
+func TestHandleAnalyze_CSVErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		err        error
+		wantStatus int
+	}{
+		{"unsupported format", "/v1/analyze?format=xml", nil, http.StatusBadRequest},
+		{"failed analysis", "/v1/analyze?format=csv", &errs.AppError{Kind: errs.Unreachable, Message: "cannot reach"}, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(&mockProvider{result: &model.PageAnalysis{}, err: tt.err})
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(`{"url": "https://example.com"}`))
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("status = %d, Content-Type = %q, want %d with a JSON error", rec.Code, rec.Header().Get("Content-Type"), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSpreadsheetSafe(t *testing.T) {
+	tests := map[string]string{
+		"Plain title":              "Plain title",
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1":                       "'+1",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"":                         "",
+	}
+	for cell, want := range tests {
+		if got := spreadsheetSafe(cell); got != want {
+			t.Errorf("spreadsheetSafe(%q) = %q, want %q", cell, got, want)
+		}
+	}
+}
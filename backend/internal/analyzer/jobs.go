@@ -0,0 +1,217 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+	"github.com/google/uuid"
+)
+
+var errShuttingDown = errors.New("job queue is shut down")
+
+// JobOptions size a JobQueue.
+type JobOptions struct {
+	// Workers is how many jobs run at once.
+	Workers int
+	// QueueSize is how many jobs may wait for a worker; more are refused.
+	QueueSize int
+	// TTL is how long a finished job is kept for its result to be read.
+	TTL time.Duration
+}
+
+// JobQueue runs analyses in the background on a fixed pool of workers, each
+// within the same deadline as a synchronous analysis. Jobs are kept in
+// memory, and forgotten TTL after they finish.
+type JobQueue struct {
+	service *Service
+	ttl     time.Duration
+	now     func() time.Time
+	queue   chan *job
+	// ctx is canceled when a shutdown runs out of time, failing the jobs
+	// still running.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mu     sync.Mutex
+	jobs   map[string]*job
+	closed bool
+}
+
+// job is one queued analysis. Its outcome fields are guarded by the queue's
+// mutex.
+type job struct {
+	id        string
+	url       string
+	opts      model.AnalyzeOptions
+	schema    string
+	requestID string
+	created   time.Time
+
+	status  string
+	result  *model.PageAnalysis
+	err     error
+	expires time.Time
+}
+
+// NewJobQueue starts the workers of a queue running analyses on service.
+func NewJobQueue(service *Service, opts JobOptions) *JobQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &JobQueue{
+		service: service,
+		ttl:     opts.TTL,
+		now:     time.Now,
+		queue:   make(chan *job, max(opts.QueueSize, 1)),
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*job),
+	}
+	for range max(opts.Workers, 1) {
+		q.workers.Go(q.work)
+	}
+	return q
+}
+
+// submit queues an analysis rendered in schema once done. The job carries
+// the request ID of ctx, but not its cancellation. It fails with Unavailable
+// when the queue is full or shut down.
+func (q *JobQueue) submit(ctx context.Context, targetURL string, opts model.AnalyzeOptions, schema string) (job, error) {
+	j := &job{
+		id:        uuid.NewString(),
+		url:       targetURL,
+		opts:      opts,
+		schema:    schema,
+		requestID: requestid.FromContext(ctx),
+		status:    model.JobPending,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return job{}, &errs.AppError{Kind: errs.Unavailable, Message: "The server is shutting down and takes no new jobs.", Cause: errShuttingDown}
+	}
+	q.evictLocked()
+	j.created = q.now()
+	select {
+	case q.queue <- j:
+	default:
+		return job{}, &errs.AppError{Kind: errs.Unavailable, Message: "Too many analysis jobs are waiting. Please retry later."}
+	}
+	q.jobs[j.id] = j
+	return *j, nil
+}
+
+// get returns a snapshot of the job with the given id, unless it is unknown
+// or expired.
+func (q *JobQueue) get(id string) (job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.jobs[id]
+	if !ok {
+		return job{}, false
+	}
+	if !j.expires.IsZero() && !q.now().Before(j.expires) {
+		delete(q.jobs, id)
+		return job{}, false
+	}
+	return *j, true
+}
+
+// evictLocked forgets the jobs whose results expired.
+func (q *JobQueue) evictLocked() {
+	now := q.now()
+	for id, j := range q.jobs {
+		if !j.expires.IsZero() && !now.Before(j.expires) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// work runs queued jobs until the queue is closed. Jobs still waiting when
+// it is are failed without running.
+func (q *JobQueue) work() {
+	for j := range q.queue {
+		q.mu.Lock()
+		if q.closed {
+			q.finishLocked(j, nil, shutdownError())
+			q.mu.Unlock()
+			continue
+		}
+		j.status = model.JobRunning
+		q.mu.Unlock()
+
+		// A job waits its turn for a slot; there is no caller to retry it.
+		ctx, cancel := context.WithTimeout(waitForSlot(requestid.NewContext(q.ctx, j.requestID)), analyzeTimeout)
+		result, err := q.service.Analyze(ctx, j.url, j.opts)
+		cancel()
+		if err != nil && q.ctx.Err() != nil {
+			err = shutdownError()
+		}
+
+		q.mu.Lock()
+		q.finishLocked(j, result, err)
+		q.mu.Unlock()
+	}
+}
+
+func (q *JobQueue) finishLocked(j *job, result *model.PageAnalysis, err error) {
+	j.status, j.result, j.err = model.JobDone, result, err
+	if err != nil {
+		j.status = model.JobFailed
+	}
+	j.expires = q.now().Add(q.ttl)
+}
+
+func shutdownError() error {
+	return &errs.AppError{Kind: errs.Unavailable, Message: "The server shut down before the job finished.", Cause: errShuttingDown}
+}
+
+// Shutdown stops taking jobs and fails those still waiting for a worker. It
+// waits for the running ones until ctx ends, then cancels them, so every job
+// ends up done or failed by the time it returns.
+func (q *JobQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(finished)
+	}()
+	defer q.cancel()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-finished
+		return ctx.Err()
+	}
+}
+
+// view renders a job snapshot for the response.
+func (j *job) view() model.AnalysisJob {
+	v := model.AnalysisJob{
+		ID:        j.id,
+		Status:    j.status,
+		URL:       j.url,
+		CreatedAt: j.created.UTC().Format(time.RFC3339),
+	}
+	switch j.status {
+	case model.JobDone:
+		v.Result = schemaRenderers[j.schema](j.result)
+	case model.JobFailed:
+		resp := errorResponse(j.err)
+		v.Error = &resp
+	}
+	return v
+}
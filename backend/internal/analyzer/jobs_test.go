@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// newJobMux serves the job routes from a queue running analyses on provider.
+func newJobMux(t *testing.T, provider PageInsightProvider, opts JobOptions) (*http.ServeMux, *JobQueue) {
+	t.Helper()
+	svc := NewService(provider, slog.Default())
+	jobs := NewJobQueue(svc, opts)
+	t.Cleanup(func() { _ = jobs.Shutdown(context.Background()) })
+	transport := NewTransport(svc, testMaxUpload, slog.Default())
+	transport.EnableJobs(jobs)
+	mux := http.NewServeMux()
+	transport.RegisterRoutes(mux, "/v1")
+	return mux, jobs
+}
+
+func submitJob(t *testing.T, mux *http.ServeMux, body string) (*httptest.ResponseRecorder, model.AnalysisJob) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/analyze/jobs", strings.NewReader(body)))
+	var j model.AnalysisJob
+	if rec.Code == http.StatusAccepted {
+		if err := json.NewDecoder(rec.Body).Decode(&j); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+	}
+	return rec, j
+}
+
+// pollJob fetches the job until it finishes.
+func pollJob(t *testing.T, mux *http.ServeMux, id string) map[string]any {
+	t.Helper()
+	for range 200 {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyze/jobs/"+id, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET job status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		var j map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&j); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		if j["status"] == model.JobDone || j["status"] == model.JobFailed {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("job did not finish")
+	return nil
+}
+
+func TestJobs_Done(t *testing.T) {
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com", Title: "Example"}}
+	mux, _ := newJobMux(t, provider, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+
+	rec, submitted := submitJob(t, mux, `{"url": "https://example.com", "check_images": true}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+	if len(submitted.ID) != 36 || rec.Header().Get("Location") != "/v1/analyze/jobs/"+submitted.ID {
+		t.Errorf("job id %q at %q, want a UUID and its URL", submitted.ID, rec.Header().Get("Location"))
+	}
+
+	j := pollJob(t, mux, submitted.ID)
+	result, _ := j["result"].(map[string]any)
+	if j["status"] != model.JobDone || result["title"] != "Example" || result["schema_version"] == nil {
+		t.Errorf("job = %v, want done with the rendered result", j)
+	}
+	if !provider.opts.CheckImages {
+		t.Error("the job's analysis did not get the request options")
+	}
+}
+
+func TestJobs_Failed(t *testing.T) {
+	provider := &mockProvider{err: &errs.AppError{Kind: errs.Unreachable, UpstreamStatus: 503, Message: "The provided URL returned an error status."}}
+	mux, _ := newJobMux(t, provider, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+
+	_, submitted := submitJob(t, mux, `{"url": "https://down.example"}`)
+	j := pollJob(t, mux, submitted.ID)
+
+	got, _ := j["error"].(map[string]any)
+	if j["status"] != model.JobFailed || got["status_code"] != float64(http.StatusBadGateway) || got["upstream_status"] != float64(503) {
+		t.Errorf("job = %v, want failed with the mapped 502 error", j)
+	}
+}
+
+func TestJobs_Rejections(t *testing.T) {
+	provider := newGatedProvider()
+	defer close(provider.release)
+	mux, _ := newJobMux(t, provider, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+
+	if rec, _ := submitJob(t, mux, `{"url": ""}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid request status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/analyze/jobs/00000000-0000-4000-8000-000000000000", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown job status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// One job runs and one waits; the next finds the queue full.
+	submitJob(t, mux, `{"url": "https://a.example"}`)
+	<-provider.started
+	submitJob(t, mux, `{"url": "https://b.example"}`)
+	if rec, _ := submitJob(t, mux, `{"url": "https://c.example"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("full queue status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestJobs_Expire(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	provider := &mockProvider{result: &model.PageAnalysis{URL: "https://example.com"}}
+	mux, jobs := newJobMux(t, provider, JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+	jobs.mu.Lock()
+	jobs.now = func() time.Time { return now }
+	jobs.mu.Unlock()
+
+	_, submitted := submitJob(t, mux, `{"url": "https://example.com"}`)
+	pollJob(t, mux, submitted.ID)
+
+	jobs.mu.Lock()
+	now = now.Add(time.Minute)
+	jobs.mu.Unlock()
+	if _, ok := jobs.get(submitted.ID); ok {
+		t.Error("job outlived its TTL")
+	}
+}
+
+func TestJobQueue_Shutdown(t *testing.T) {
+	provider := newGatedProvider()
+	jobs := NewJobQueue(NewService(provider, slog.Default()), JobOptions{Workers: 1, QueueSize: 2, TTL: time.Minute})
+
+	running, _ := jobs.submit(context.Background(), "https://a.example", model.AnalyzeOptions{}, latestSchema)
+	<-provider.started
+	waiting, _ := jobs.submit(context.Background(), "https://b.example", model.AnalyzeOptions{}, latestSchema)
+
+	done := make(chan error, 1)
+	go func() { done <- jobs.Shutdown(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := jobs.submit(context.Background(), "https://c.example", model.AnalyzeOptions{}, latestSchema); err == nil {
+		t.Error("a job was accepted during shutdown")
+	}
+	close(provider.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if j, _ := jobs.get(running.id); j.status != model.JobDone {
+		t.Errorf("running job ended %s, want it waited for and done", j.status)
+	}
+	if j, _ := jobs.get(waiting.id); j.status != model.JobFailed || !errors.Is(j.err, errShuttingDown) {
+		t.Errorf("waiting job ended %s (%v), want failed by the shutdown", j.status, j.err)
+	}
+}
+
+func TestJobQueue_ShutdownDeadline(t *testing.T) {
+	provider := newGatedProvider()
+	jobs := NewJobQueue(NewService(provider, slog.Default()), JobOptions{Workers: 1, QueueSize: 1, TTL: time.Minute})
+
+	running, _ := jobs.submit(context.Background(), "https://a.example", model.AnalyzeOptions{}, latestSchema)
+	<-provider.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := jobs.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown err = %v, want the deadline", err)
+	}
+	if j, _ := jobs.get(running.id); j.status != model.JobFailed || !errors.Is(j.err, errShuttingDown) {
+		t.Errorf("running job ended %s (%v), want failed by the shutdown", j.status, j.err)
+	}
+}
@@ -0,0 +1,142 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+var (
+	errAtCapacity  = errors.New("analysis limit reached")
+	errNotAdmitted = errors.New("limiter is closed")
+)
+
+// overloadRetryAfter is the Retry-After sent with a 429, long enough for a
+// typical analysis to finish.
+const overloadRetryAfter = 5 * time.Second
+
+// Limiter caps the analyses running at once across the server, since each
+// holds outbound connections and a page body in memory. An analysis that
+// finds every slot taken waits up to the limiter's wait for one, then fails
+// with Overloaded. Once closed for shutdown, it admits no more.
+type Limiter struct {
+	slots chan struct{}
+	wait  time.Duration
+
+	mu      sync.Mutex
+	closed  bool
+	closing chan struct{}
+}
+
+// NewLimiter returns a limiter running up to limit analyses at once, with
+// the others waiting up to wait for a slot.
+func NewLimiter(limit int, wait time.Duration) *Limiter {
+	return &Limiter{
+		slots:   make(chan struct{}, max(limit, 1)),
+		wait:    wait,
+		closing: make(chan struct{}),
+	}
+}
+
+type waitForSlotKey struct{}
+
+// waitForSlot returns a context whose analyses wait for a slot for as long
+// as ctx lasts rather than the limiter's wait, for background work with no
+// caller to answer 429 to.
+func waitForSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, waitForSlotKey{}, true)
+}
+
+// acquire takes a slot for an analysis, returning the func that gives it
+// back. It fails with Overloaded when none frees up in time, Unavailable
+// once the limiter is closed, or the error of ctx. A nil *Limiter admits
+// everything.
+func (l *Limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	closed := l.closed
+	l.mu.Unlock()
+	if closed {
+		return nil, shuttingDownError()
+	}
+
+	release := func() { <-l.slots }
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if queued, _ := ctx.Value(waitForSlotKey{}).(bool); !queued {
+		if l.wait <= 0 {
+			return nil, overloadedError()
+		}
+		timer := time.NewTimer(l.wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timeout:
+		return nil, overloadedError()
+	case <-l.closing:
+		return nil, shuttingDownError()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight is how many analyses hold a slot.
+func (l *Limiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Limit is how many analyses may run at once.
+func (l *Limiter) Limit() int {
+	return cap(l.slots)
+}
+
+// Close stops admitting analyses, failing those waiting for a slot. The ones
+// running keep theirs until they finish.
+func (l *Limiter) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.closed {
+		l.closed = true
+		close(l.closing)
+	}
+}
+
+func overloadedError() error {
+	return &errs.AppError{
+		Kind:    errs.Overloaded,
+		Message: "The server is running as many analyses as it can. Please retry in a few seconds.",
+		Cause:   errAtCapacity,
+	}
+}
+
+func shuttingDownError() error {
+	return &errs.AppError{Kind: errs.Unavailable, Message: "The server is shutting down and takes no new analyses.", Cause: errNotAdmitted}
+}
+
+// overloaded reports whether err is the limiter turning an analysis away.
+func overloaded(err error) bool {
+	var appErr *errs.AppError
+	return errors.As(err, &appErr) && appErr.Kind == errs.Overloaded
+}
+
+// setRetryAfter tells the client of an overloaded response when to retry.
+func setRetryAfter(w http.ResponseWriter, err error) {
+	if overloaded(err) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(overloadRetryAfter/time.Second)))
+	}
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+func errKind(err error) errs.Kind {
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) {
+		return errs.Unknown
+	}
+	return appErr.Kind
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := NewLimiter(1, 20*time.Millisecond)
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if got := l.InFlight(); got != 1 {
+		t.Errorf("InFlight = %d, want 1", got)
+	}
+	if _, err := l.acquire(ctx); errKind(err) != errs.Overloaded {
+		t.Errorf("acquire at capacity err = %v, want Overloaded", err)
+	}
+
+	// A background analysis waits for the slot instead of failing.
+	admitted := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(waitForSlot(ctx))
+		if err == nil {
+			release()
+		}
+		admitted <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	release()
+	if err := <-admitted; err != nil {
+		t.Errorf("waiting acquire err = %v, want the freed slot", err)
+	}
+	if got := l.InFlight(); got != 0 {
+		t.Errorf("InFlight = %d after every release, want 0", got)
+	}
+}
+
+func TestLimiter_Close(t *testing.T) {
+	l := NewLimiter(1, time.Minute)
+	release, _ := l.acquire(context.Background())
+	defer release()
+
+	waiting := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(context.Background())
+		waiting <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	l.Close()
+
+	if err := <-waiting; errKind(err) != errs.Unavailable {
+		t.Errorf("waiting acquire err = %v, want Unavailable once closed", err)
+	}
+	if _, err := l.acquire(context.Background()); errKind(err) != errs.Unavailable {
+		t.Errorf("acquire after Close err = %v, want Unavailable", err)
+	}
+}
+
+func TestService_Analyze_SharedRunTakesOneSlot(t *testing.T) {
+	provider := newGatedProvider()
+	svc := NewService(provider, slog.Default())
+	svc.LimitConcurrency(NewLimiter(1, 0))
+
+	_, failures := analyzeConcurrently(t, svc, provider, 4)
+
+	for i, err := range failures {
+		if err != nil {
+			t.Errorf("caller %d err = %v, want the shared result", i, err)
+		}
+	}
+}
+
+func TestHandleAnalyze_Overloaded(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	provider := newGatedProvider()
+	svc := NewService(provider, logger)
+	svc.LimitConcurrency(NewLimiter(1, 0))
+	mux := http.NewServeMux()
+	NewTransport(svc, testMaxUpload, logger).RegisterRoutes(mux, "/v1")
+
+	// A page fetched with caller headers is never shared, so the second
+	// request needs a slot of its own.
+	body := `{"url": "https://example.com", "headers": {"Cookie": "a=b"}}`
+	first := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(body)))
+		first <- rec.Code
+	}()
+	<-provider.started
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/analyze", strings.NewReader(body)))
+	close(provider.release)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("admitted request status = %d, want 200", code)
+	}
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want 5", got)
+	}
+	if !strings.Contains(logs.String(), `"msg":"rejected at capacity"`) || !strings.Contains(logs.String(), `"in_flight":1`) {
+		t.Errorf("logs = %s, want the rejection with the in-flight count", logs.String())
+	}
+	if got := provider.calls.Load(); got != 1 {
+		t.Errorf("provider ran %d times, want 1", got)
+	}
+}
@@ -2,11 +2,18 @@ package analyzer
 
 import (
 	"context"
+	"io"
 
 	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 )
 
 // PageInsightProvider defines the contract for any analysis engine.
 type PageInsightProvider interface {
-	Analyze(ctx context.Context, targetURL string) (*model.PageAnalysis, error)
+	Analyze(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error)
+	AnalyzeHTML(ctx context.Context, body io.Reader, baseURL string) (*model.PageAnalysis, error)
+}
+
+// SiteCrawler defines the contract for a multi-page crawl.
+type SiteCrawler interface {
+	Crawl(ctx context.Context, startURL string, opts model.CrawlOptions) (*model.SiteCrawl, error)
 }
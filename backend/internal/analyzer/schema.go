@@ -0,0 +1,208 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// latestSchema is the response schema served when a client does not ask for one.
+const latestSchema = "2"
+
+// schemaRenderers translates the internal model into each supported major
+// version of the response schema. The latest version is the model itself and
+// may gain fields. Older versions are frozen: the model may grow, but their
+// renderers keep producing exactly the shape they shipped with, dropping newer
+// fields and keeping renamed ones under their old names.
+var schemaRenderers = map[string]func(*model.PageAnalysis) any{
+	"1": renderSchemaV1,
+	"2": renderSchemaV2,
+}
+
+// schemaV2 is the version 2 response shape, which adds media stats. Before a
+// breaking change to the model, freeze it the way schemaV1 is frozen.
+type schemaV2 struct {
+	SchemaVersion string `json:"schema_version"`
+	*model.PageAnalysis
+}
+
+func renderSchemaV2(a *model.PageAnalysis) any {
+	return schemaV2{SchemaVersion: "2", PageAnalysis: a}
+}
+
+// crawlResponse is a crawl with each page's analysis in the negotiated schema.
+type crawlResponse struct {
+	URL     string              `json:"url"`
+	Pages   []crawlPageResponse `json:"pages"`
+	Summary model.CrawlSummary  `json:"summary"`
+}
+
+type crawlPageResponse struct {
+	URL      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Analysis any    `json:"analysis,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func renderCrawl(version string, crawl *model.SiteCrawl) crawlResponse {
+	pages := make([]crawlPageResponse, 0, len(crawl.Pages))
+	for _, page := range crawl.Pages {
+		rendered := crawlPageResponse{URL: page.URL, Depth: page.Depth, Error: page.Error}
+		if page.Analysis != nil {
+			rendered.Analysis = schemaRenderers[version](page.Analysis)
+		}
+		pages = append(pages, rendered)
+	}
+	return crawlResponse{URL: crawl.URL, Pages: pages, Summary: crawl.Summary}
+}
+
+// negotiateSchema picks the response schema from the X-PageInsight-Schema
+// header or the schema query parameter, in that order. It returns false if
+// the requested version is not supported.
+func negotiateSchema(r *http.Request) (string, bool) {
+	version := r.Header.Get("X-PageInsight-Schema")
+	if version == "" {
+		version = r.URL.Query().Get("schema")
+	}
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" {
+		return latestSchema, true
+	}
+	_, ok := schemaRenderers[version]
+	return version, ok
+}
+
+// Response formats of an analysis. Errors are JSON either way.
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// negotiateFormat picks the format of an analysis response from the format
+// query parameter, or else the Accept header: text/csv asks for CSV. It
+// returns false if the parameter names an unsupported format.
+func negotiateFormat(r *http.Request) (string, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		format = strings.ToLower(format)
+		return format, format == formatJSON || format == formatCSV
+	}
+	for accepted := range strings.SplitSeq(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/csv") {
+			return formatCSV, true
+		}
+	}
+	return formatJSON, true
+}
+
+func unsupportedFormatMessage(format string) string {
+	return fmt.Sprintf("Unsupported format %q. Supported formats: %s, %s.", format, formatJSON, formatCSV)
+}
+
+func unsupportedSchemaMessage(version string) string {
+	versions := make([]string, 0, len(schemaRenderers))
+	for v := range schemaRenderers {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	return fmt.Sprintf("Unsupported schema version %q. Supported versions: %s.", version, strings.Join(versions, ", "))
+}
+
+// schemaV1 is the version 1 response shape. Do not change it; add fields to
+// a new version instead.
+type schemaV1 struct {
+	SchemaVersion       string                `json:"schema_version"`
+	URL                 string                `json:"url"`
+	HTMLVersion         string                `json:"html_version"`
+	Title               string                `json:"title"`
+	TitleLength         int                   `json:"title_length"`
+	MultipleTitles      bool                  `json:"multiple_titles"`
+	Headings            map[string]int        `json:"headings"`
+	FirstH1             string                `json:"first_h1"`
+	Links               schemaV1LinkStats     `json:"links"`
+	HasLoginForm        bool                  `json:"has_login_form"`
+	SVG                 schemaV1SVGStats      `json:"svg"`
+	InlineEventHandlers int                   `json:"inline_event_handlers"`
+	JavascriptLinks     int                   `json:"javascript_links"`
+	MixedContent        schemaV1MixedContent  `json:"mixed_content"`
+	Transfer            schemaV1TransferStats `json:"transfer"`
+	Warnings            []string              `json:"warnings,omitempty"`
+}
+
+type schemaV1LinkStats struct {
+	Internal     int `json:"internal_count"`
+	External     int `json:"external_count"`
+	Inaccessible int `json:"inaccessible_count"`
+	EmptyText    int `json:"empty_text_count"`
+	Fragment     int `json:"fragment_count"`
+}
+
+type schemaV1SVGStats struct {
+	Count       int `json:"count"`
+	InlineBytes int `json:"inline_bytes"`
+	Oversized   int `json:"oversized_count"`
+}
+
+type schemaV1MixedContent struct {
+	Images      int      `json:"images"`
+	Scripts     int      `json:"scripts"`
+	Stylesheets int      `json:"stylesheets"`
+	Iframes     int      `json:"iframes"`
+	FormActions int      `json:"form_actions"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+type schemaV1TransferStats struct {
+	Compressed       bool    `json:"compressed"`
+	ContentEncoding  string  `json:"content_encoding,omitempty"`
+	WireBytes        int64   `json:"wire_bytes,omitempty"`
+	DecodedBytes     int64   `json:"decoded_bytes"`
+	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+}
+
+func renderSchemaV1(a *model.PageAnalysis) any {
+	return schemaV1{
+		SchemaVersion:  "1",
+		URL:            a.URL,
+		HTMLVersion:    a.HTMLVersion,
+		Title:          a.Title,
+		TitleLength:    a.TitleLength,
+		MultipleTitles: a.MultipleTitles,
+		Headings:       a.Headings,
+		FirstH1:        a.FirstH1,
+		Links: schemaV1LinkStats{
+			Internal:     a.Links.Internal,
+			External:     a.Links.External,
+			Inaccessible: max(a.Links.Inaccessible, 0),
+			EmptyText:    a.Links.EmptyText,
+			Fragment:     a.Links.Fragment,
+		},
+		HasLoginForm: a.HasLoginForm,
+		SVG: schemaV1SVGStats{
+			Count:       a.SVG.Count,
+			InlineBytes: a.SVG.InlineBytes,
+			Oversized:   a.SVG.Oversized,
+		},
+		InlineEventHandlers: a.InlineEventHandlers,
+		JavascriptLinks:     a.JavascriptLinks,
+		MixedContent: schemaV1MixedContent{
+			Images:      a.MixedContent.Images,
+			Scripts:     a.MixedContent.Scripts,
+			Stylesheets: a.MixedContent.Stylesheets,
+			Iframes:     a.MixedContent.Iframes,
+			FormActions: a.MixedContent.FormActions,
+			Examples:    a.MixedContent.Examples,
+		},
+		Transfer: schemaV1TransferStats{
+			Compressed:       a.Transfer.Compressed,
+			ContentEncoding:  a.Transfer.ContentEncoding,
+			WireBytes:        a.Transfer.WireBytes,
+			DecodedBytes:     a.Transfer.DecodedBytes,
+			CompressionRatio: a.Transfer.CompressionRatio,
+		},
+		Warnings: a.Warnings,
+	}
+}
@@ -0,0 +1,239 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// fullAnalysis returns a result with every field of the internal model set,
+// so golden files show exactly which fields each schema version keeps.
+func fullAnalysis() *model.PageAnalysis {
+	return &model.PageAnalysis{
+		URL:            "https://example.com/",
+		FinalURL:       "https://www.example.com/",
+		Redirects:      1,
+		Cached:         true,
+		ContentHash:    &model.ContentHash{SHA256: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Truncated: false},
+		HTMLVersion:    "HTML5",
+		Doctype:        "html",
+		Title:          "Example Domain",
+		TitleLength:    14,
+		MultipleTitles: true,
+		Headings:       map[string]int{"h1": 1, "h2": 2, "h3": 0, "h4": 0, "h5": 0, "h6": 0},
+		FirstH1:        "Example",
+		Dates:          model.PageDates{PublishedAt: "2024-01-02T10:00:00Z", PublishedSource: "json-ld", ModifiedAt: "2024-02-03T00:00:00Z", ModifiedSource: "opengraph"},
+		TextRatio:      0.125,
+		DOM: model.DOMStats{
+			Nodes:          120,
+			MaxDepth:       9,
+			MarkupWarnings: 2,
+			MarkupSamples:  []string{"stray </p> with no open element", "<div> never closed"},
+		},
+		LikelyRequiresJavascript: true,
+		LikelySoft404:            true,
+		Accessibility: model.Accessibility{
+			UnlabeledInputs: 2,
+			EmptyHeadings:   1,
+			Landmarks:       map[string]int{"banner": 1, "main": 1, "navigation": 2},
+			Roles:           map[string]int{"navigation": 1, "tablist": 1},
+		},
+		Links: model.LinkStats{
+			Internal:              4,
+			External:              2,
+			Inaccessible:          1,
+			InternalInaccessible:  1,
+			HTTPErrors:            1,
+			RateLimited:           2,
+			Skipped:               1,
+			Cached:                3,
+			Checked:               5,
+			Partial:               true,
+			Sampled:               true,
+			EstimatedInaccessible: 4,
+			Excluded:              2,
+			LatencyP50Ms:          120,
+			LatencyP95Ms:          900,
+			SlowestLinks:          []model.SlowLink{{URL: "https://other.com/ad", ElapsedMs: 900}},
+			EmptyText:             1,
+			Fragment:              3,
+			BrokenFragments:       1,
+			UnsafeTargetBlank:     2,
+		},
+		LinksDetail: []model.LinkDetail{
+			{URL: "https://example.com/docs", Internal: true, Text: "Docs", Checked: true, Status: 200, Redirects: 1, FinalURL: "https://example.com/docs/", ElapsedMs: 120},
+			{URL: "https://other.com/ad", Rel: []string{"nofollow", "sponsored"}, Text: "Ad", Checked: true, Status: 404, Failure: "http-4xx"},
+			{URL: "https://example.com/late", Internal: true},
+		},
+		BrokenFragmentSamples: []string{"pricing"},
+		Media: model.MediaStats{
+			Videos:       1,
+			Audios:       1,
+			Pictures:     2,
+			Internal:     1,
+			External:     1,
+			Checked:      true,
+			Inaccessible: 1,
+		},
+		HasLoginForm:  true,
+		LoginForm:     "login",
+		HasSearchForm: true,
+		SearchAction:  "https://example.com/search",
+		MetaRefresh:   &model.MetaRefresh{URL: "https://example.com/new", Followed: true},
+		PWA: model.PWASignals{
+			ManifestURL:           "https://example.com/manifest.webmanifest",
+			ManifestInaccessible:  true,
+			MentionsServiceWorker: true,
+		},
+		Pagination: model.Pagination{
+			Next:             "https://example.com/?page=3",
+			Prev:             "https://example.com/?page=1",
+			NextInaccessible: true,
+		},
+		ResourceHints: model.ResourceHints{
+			Preload: 2, Preconnect: 1, Hosts: []string{"cdn.example.net"},
+			PreloadsChecked: true, PreloadsInaccessible: 1,
+		},
+		Tables:              model.TableStats{Count: 3, LayoutSuspect: 2},
+		Images:              model.ImageStats{Count: 6, Lazy: 4, MissingDimensions: 2, Srcset: 3, Checked: true, Inaccessible: 1},
+		SVG:                 model.SVGStats{Count: 2, InlineBytes: 4096, Oversized: 1},
+		InlineEventHandlers: 5,
+		JavascriptLinks:     1,
+		InlineStyles:        model.InlineStyles{Attributes: 3, Blocks: 1, CSSBytes: 512},
+		MixedContent: model.MixedContent{
+			Images:   1,
+			Scripts:  1,
+			Examples: []string{"http://cdn.example.com/a.js", "http://cdn.example.com/a.png"},
+		},
+		ThirdPartyDomains: []model.DomainCount{
+			{Domain: "www.googletagmanager.com", Count: 2, Scripts: 2},
+			{Domain: "cdn.example.net", Count: 1, Images: 1},
+		},
+		Trackers:      []string{"Google Tag Manager"},
+		Analytics:     []string{"Google Analytics 4", "Google Tag Manager"},
+		BotProtection: []string{"reCAPTCHA"},
+		Transfer: model.TransferStats{
+			Compressed:       true,
+			ContentEncoding:  "gzip",
+			WireBytes:        1024,
+			DecodedBytes:     4096,
+			CompressionRatio: 0.25,
+		},
+		Timings: model.Timings{TotalMs: 1830, FetchMs: 240, ParseMs: 15, LinkCheckMs: 1570},
+		Fetch: model.FetchStats{
+			DNSMs:     12,
+			ConnectMs: 20,
+			TLSMs:     35,
+			TTFBMs:    140,
+			TotalMs:   180,
+			BodyBytes: 4096,
+			Protocol:  "HTTP/2.0",
+			Server:    "nginx",
+			PoweredBy: "PHP/8.3",
+			Attempts:  1,
+		},
+		SecurityHeaders: &model.SecurityHeaders{
+			StrictTransportSecurity: new("max-age=63072000"),
+			XFrameOptions:           new(""),
+		},
+		TLS: &model.TLSInfo{
+			Version:             "TLS 1.3",
+			CertificateSubject:  "CN=example.com",
+			CertificateIssuer:   "CN=R11,O=Let's Encrypt,C=US",
+			CertificateNotAfter: "2025-01-01T00:00:00Z",
+			DaysUntilExpiry:     10,
+			ExpiresSoon:         true,
+		},
+		Robots: &model.RobotsTxt{
+			Found:      true,
+			Disallowed: true,
+			Sitemaps:   []string{"https://example.com/sitemap.xml"},
+		},
+		Warnings: []string{"1 anchor(s) with an empty href"},
+	}
+}
+
+func TestSchemaGolden(t *testing.T) {
+	for version, render := range schemaRenderers {
+		t.Run("v"+version, func(t *testing.T) {
+			got, err := json.MarshalIndent(render(fullAnalysis()), "", "  ")
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			got = append(got, '\n')
+
+			path := filepath.Join("testdata", "schema_v"+version+".golden.json")
+			if *updateGolden {
+				if err := os.WriteFile(path, got, 0o600); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("schema v%s output changed; older versions are frozen, rerun with -update only for the latest.\ngot:\n%s\nwant:\n%s", version, got, want)
+			}
+		})
+	}
+}
+
+func TestHandleAnalyze_SchemaNegotiation(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		query       string
+		wantStatus  int
+		wantVersion string
+	}{
+		{name: "default is latest", wantStatus: http.StatusOK, wantVersion: latestSchema},
+		{name: "header", header: "1", wantStatus: http.StatusOK, wantVersion: "1"},
+		{name: "query parameter", query: "?schema=v1", wantStatus: http.StatusOK, wantVersion: "1"},
+		{name: "unknown version", header: "99", wantStatus: http.StatusBadRequest},
+		{name: "header wins over query", header: "99", query: "?schema=1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newTestMux(&mockProvider{result: fullAnalysis()})
+			req := httptest.NewRequest(http.MethodPost, "/v1/analyze"+tt.query, strings.NewReader(`{"url": "https://example.com"}`))
+			if tt.header != "" {
+				req.Header.Set("X-PageInsight-Schema", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			mux.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(rec.Body.String(), "Supported versions: 1, 2") {
+					t.Errorf("error body %s should list supported versions", rec.Body.String())
+				}
+				return
+			}
+
+			var resp struct {
+				SchemaVersion string `json:"schema_version"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.SchemaVersion != tt.wantVersion {
+				t.Errorf("schema_version = %q, want %q", resp.SchemaVersion, tt.wantVersion)
+			}
+		})
+	}
+}
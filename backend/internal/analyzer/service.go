@@ -3,17 +3,29 @@ package analyzer
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+	"golang.org/x/sync/singleflight"
 )
 
-// Service orchestrates a PageInsightProvider and logs results.
+// Service orchestrates a PageInsightProvider and logs results. Concurrent
+// identical analyses share one run of the provider.
 type Service struct {
 	provider PageInsightProvider
+	crawler  SiteCrawler
+	history  *History
+	limiter  *Limiter
 	logger   *slog.Logger
+	flights  singleflight.Group
 }
 
 // NewService creates a Service backed by the given provider.
@@ -21,28 +33,180 @@ func NewService(provider PageInsightProvider, logger *slog.Logger) *Service {
 	return &Service{provider: provider, logger: logger}
 }
 
-// Analyze delegates to the provider and logs the outcome.
-func (s *Service) Analyze(ctx context.Context, targetURL string) (*model.PageAnalysis, error) {
-	logger := s.logger.With("url", targetURL, "request_id", requestid.FromContext(ctx))
+// Analyze delegates to the provider and logs the outcome. A per-request
+// opts.Timeout shortens the deadline of ctx.
+func (s *Service) Analyze(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	logger := s.logger.With("url", targetURL, "request_id", requestid.FromContext(ctx), "options", opts)
+	if batchID := batchIDFromContext(ctx); batchID != "" {
+		logger = logger.With("batch_id", batchID)
+	}
+	ctx, cancel := withRequestTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	result, shared, err := s.analyzeShared(ctx, targetURL, opts)
+	if shared {
+		logger = logger.With("shared", true)
+	}
+	if overloaded(err) {
+		s.rejected(logger)
+		return nil, err
+	}
+	result, err = s.report(ctx, logger, result, err)
+	// An analysis with caller-supplied headers may have seen a private
+	// page, which the history would hand to anyone who asks.
+	if err == nil && len(opts.Headers) == 0 {
+		s.history.record(ctx, result)
+	}
+	return result, err
+}
+
+// analyzeShared runs the analysis, joining a run already in flight for the
+// same URL and options, and reports whether the result was shared. The run
+// is detached from the caller that started it, keeping only its deadline and
+// request values, so one caller going away doesn't fail the others; a caller
+// whose own context ends stops waiting. Analyses with caller-supplied headers
+// may see private content and always run on their own, as do those streaming
+// their progress to the caller.
+func (s *Service) analyzeShared(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, bool, error) {
+	key, ok := flightKey(targetURL, opts)
+	if !ok || progress.FromContext(ctx) != nil {
+		result, err := s.run(ctx, targetURL, opts)
+		return result, false, err
+	}
+
+	ch := s.flights.DoChan(key, func() (any, error) {
+		runCtx, cancel := detach(ctx)
+		defer cancel()
+		result, err := s.run(runCtx, targetURL, opts)
+		return result, timeoutError(runCtx, err)
+	})
+	select {
+	case res := <-ch:
+		result, _ := res.Val.(*model.PageAnalysis)
+		return result, res.Shared, res.Err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// flightKey identifies an analysis for sharing: the URL with its scheme and
+// host lowercased and its fragment dropped, plus every option. It returns
+// false for an analysis that must not be shared.
+func flightKey(targetURL string, opts model.AnalyzeOptions) (string, bool) {
+	if len(opts.Headers) > 0 {
+		return "", false
+	}
+	if u, err := url.Parse(targetURL); err == nil {
+		u.Scheme, u.Host, u.Fragment = strings.ToLower(u.Scheme), strings.ToLower(u.Host), ""
+		targetURL = u.String()
+	}
+	return fmt.Sprintf("%s|%t|%t|%t|%t|%q|%q|%t|%s", targetURL,
+		opts.FollowMetaRefresh, opts.CheckPreloads, opts.CheckImages, opts.IncludeLinks,
+		opts.CheckLinks, opts.ExcludeLinks, opts.Force, opts.Timeout), true
+}
+
+// detach returns a context that carries the values and deadline of ctx but
+// is not canceled with it.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// EnableCrawl lets the service crawl sites with the given crawler.
+func (s *Service) EnableCrawl(crawler SiteCrawler) {
+	s.crawler = crawler
+}
 
-	result, err := s.provider.Analyze(ctx, targetURL)
+// run analyzes targetURL on the provider once the limiter admits it. An
+// analysis shared by several callers takes a single slot.
+func (s *Service) run(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	release, err := s.limiter.acquire(ctx)
 	if err != nil {
-		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
-			err = &errs.AppError{
-				Kind:    errs.Timeout,
-				Message: "Analysis timed out. The target URL may be slow to respond.",
-				Cause:   err,
-			}
-		}
+		return nil, err
+	}
+	defer release()
+	return s.provider.Analyze(ctx, targetURL, opts)
+}
 
-		attrs := []any{"error", err}
-		var appErr *errs.AppError
-		if errors.As(err, &appErr) && appErr.UpstreamStatus != 0 {
-			attrs = append(attrs, "target_status", appErr.UpstreamStatus)
-		}
-		logger.Error("analysis failed", attrs...)
+// admit takes a slot of the limiter for work logged by logger, logging the
+// failure to get one.
+func (s *Service) admit(ctx context.Context, logger *slog.Logger) (func(), error) {
+	release, err := s.limiter.acquire(ctx)
+	if overloaded(err) {
+		s.rejected(logger)
 		return nil, err
 	}
+	if err != nil {
+		return nil, s.failure(ctx, logger, err)
+	}
+	return release, nil
+}
+
+// rejected logs work turned away because every slot of the limiter is
+// taken.
+func (s *Service) rejected(logger *slog.Logger) {
+	logger.Warn("rejected at capacity", "in_flight", s.limiter.InFlight(), "limit", s.limiter.Limit())
+}
+
+// LimitConcurrency makes the analyses, uploads and crawls of the service
+// each take a slot of limiter while they run.
+func (s *Service) LimitConcurrency(limiter *Limiter) {
+	s.limiter = limiter
+}
+
+// EnableHistory records every analysis the service completes in history.
+func (s *Service) EnableHistory(history *History) {
+	s.history = history
+}
+
+// Crawl delegates a multi-page crawl to the crawler and logs the outcome. A
+// per-request opts.Analyze.Timeout bounds the whole crawl.
+func (s *Service) Crawl(ctx context.Context, startURL string, opts model.CrawlOptions) (*model.SiteCrawl, error) {
+	logger := s.logger.With("url", startURL, "source", "crawl", "request_id", requestid.FromContext(ctx), "options", opts.Analyze)
+	ctx, cancel := withRequestTimeout(ctx, opts.Analyze.Timeout)
+	defer cancel()
+
+	release, err := s.admit(ctx, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	crawl, err := s.crawler.Crawl(ctx, startURL, opts)
+	if err != nil {
+		return nil, s.failure(ctx, logger, err)
+	}
+	logger.Info("crawl complete",
+		"pages_analyzed", crawl.Summary.PagesAnalyzed,
+		"pages_failed", crawl.Summary.PagesFailed,
+		"inaccessible_links", crawl.Summary.InaccessibleLinks,
+		"truncated", crawl.Summary.Truncated,
+	)
+	return crawl, nil
+}
+
+// AnalyzeHTML delegates an uploaded document to the provider and logs the outcome.
+func (s *Service) AnalyzeHTML(ctx context.Context, body io.Reader, baseURL string) (*model.PageAnalysis, error) {
+	logger := s.logger.With("url", baseURL, "source", "upload", "request_id", requestid.FromContext(ctx))
+
+	release, err := s.admit(ctx, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	result, err := s.provider.AnalyzeHTML(ctx, body, baseURL)
+	return s.report(ctx, logger, result, err)
+}
+
+// report normalizes deadline errors and logs the outcome of an analysis.
+func (s *Service) report(ctx context.Context, logger *slog.Logger, result *model.PageAnalysis, err error) (*model.PageAnalysis, error) {
+	if err != nil {
+		return nil, s.failure(ctx, logger, err)
+	}
 
 	logger.Info("analysis complete",
 		"title", result.Title,
@@ -51,6 +215,63 @@ func (s *Service) Analyze(ctx context.Context, targetURL string) (*model.PageAna
 		"internal_links", result.Links.Internal,
 		"external_links", result.Links.External,
 		"inaccessible_links", result.Links.Inaccessible,
+		"cached_links", result.Links.Cached,
+		"empty_text_links", result.Links.EmptyText,
+		"fetch_attempts", result.Fetch.Attempts,
+		"timings", result.Timings,
 	)
 	return result, nil
 }
+
+// timeoutError turns an error cut short by the deadline of ctx into a
+// Timeout.
+func timeoutError(ctx context.Context, err error) error {
+	var appErr *errs.AppError
+	if err == nil || (errors.As(err, &appErr) && appErr.Kind == errs.Timeout) {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &errs.AppError{
+			Kind:    errs.Timeout,
+			Message: "Analysis timed out. The target URL may be slow to respond.",
+			Cause:   err,
+		}
+	}
+	return err
+}
+
+type batchIDKey struct{}
+
+// withBatchID returns a context whose analyses are logged as part of the
+// batch with the given id.
+func withBatchID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, batchIDKey{}, id)
+}
+
+// batchIDFromContext returns the batch id stored in ctx, or "".
+func batchIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(batchIDKey{}).(string)
+	return id
+}
+
+// withRequestTimeout applies a per-request timeout to ctx. It can only
+// shorten the deadline ctx already has, and zero leaves ctx as it is.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// failure turns an error cut short by the deadline into a Timeout, logs it,
+// and returns it.
+func (s *Service) failure(ctx context.Context, logger *slog.Logger, err error) error {
+	err = timeoutError(ctx, err)
+	var appErr *errs.AppError
+	attrs := []any{"error", err}
+	if errors.As(err, &appErr) && appErr.UpstreamStatus != 0 {
+		attrs = append(attrs, "target_status", appErr.UpstreamStatus)
+	}
+	logger.Error("analysis failed", attrs...)
+	return err
+}
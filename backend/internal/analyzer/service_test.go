@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// gatedProvider blocks each analysis until release is closed, or its context
+// ends, and counts the analyses it started.
+type gatedProvider struct {
+	release chan struct{}
+	started chan struct{}
+	calls   atomic.Int32
+	err     error
+}
+
+func newGatedProvider() *gatedProvider {
+	return &gatedProvider{release: make(chan struct{}), started: make(chan struct{}, 100)}
+}
+
+func (p *gatedProvider) Analyze(ctx context.Context, targetURL string, _ model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	p.calls.Add(1)
+	p.started <- struct{}{}
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &model.PageAnalysis{URL: targetURL, Title: "Shared"}, nil
+}
+
+func (p *gatedProvider) AnalyzeHTML(context.Context, io.Reader, string) (*model.PageAnalysis, error) {
+	return nil, errPrimaryUsed
+}
+
+// analyzeConcurrently starts n identical analyses on svc, waits until the
+// provider is running and the others had time to join it, then releases it.
+func analyzeConcurrently(t *testing.T, svc *Service, p *gatedProvider, n int) ([]*model.PageAnalysis, []error) {
+	t.Helper()
+	results := make([]*model.PageAnalysis, n)
+	failures := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			// The URLs differ only in what the key normalizes away.
+			target := "https://example.com/page"
+			if i%2 == 1 {
+				target = "https://EXAMPLE.com/page#top"
+			}
+			results[i], failures[i] = svc.Analyze(context.Background(), target, model.AnalyzeOptions{})
+		})
+	}
+	<-p.started
+	time.Sleep(100 * time.Millisecond)
+	close(p.release)
+	wg.Wait()
+	return results, failures
+}
+
+func TestService_Analyze_SharesConcurrentRuns(t *testing.T) {
+	provider := newGatedProvider()
+	svc := NewService(provider, slog.Default())
+
+	results, failures := analyzeConcurrently(t, svc, provider, 5)
+
+	if got := provider.calls.Load(); got != 1 {
+		t.Errorf("provider ran %d times, want 1", got)
+	}
+	for i := range results {
+		if failures[i] != nil || results[i] != results[0] {
+			t.Errorf("caller %d got %v, %v, want the shared result", i, results[i], failures[i])
+		}
+	}
+}
+
+func TestService_Analyze_SharesErrors(t *testing.T) {
+	provider := newGatedProvider()
+	provider.err = &errs.AppError{Kind: errs.Unreachable, Message: "cannot reach"}
+	svc := NewService(provider, slog.Default())
+
+	_, failures := analyzeConcurrently(t, svc, provider, 4)
+
+	if got := provider.calls.Load(); got != 1 {
+		t.Errorf("provider ran %d times, want 1", got)
+	}
+	for i, err := range failures {
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.Unreachable {
+			t.Errorf("caller %d err = %v, want the shared Unreachable error", i, err)
+		}
+	}
+}
+
+func TestService_Analyze_CallerCancelDoesNotStopOthers(t *testing.T) {
+	provider := newGatedProvider()
+	svc := NewService(provider, slog.Default())
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := svc.Analyze(leaderCtx, "https://example.com/", model.AnalyzeOptions{})
+		leaderErr <- err
+	}()
+	<-provider.started
+
+	follower := make(chan *model.PageAnalysis, 1)
+	go func() {
+		result, _ := svc.Analyze(context.Background(), "https://example.com/", model.AnalyzeOptions{})
+		follower <- result
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller err = %v, want context.Canceled", err)
+	}
+	close(provider.release)
+	if result := <-follower; result == nil || result.Title != "Shared" {
+		t.Errorf("remaining caller got %v, want the result of the run the first caller started", result)
+	}
+	if got := provider.calls.Load(); got != 1 {
+		t.Errorf("provider ran %d times, want 1", got)
+	}
+}
+
+func TestService_Analyze_LogsTimings(t *testing.T) {
+	var logs bytes.Buffer
+	provider := &mockProvider{result: &model.PageAnalysis{
+		URL:     "https://example.com",
+		Timings: model.Timings{TotalMs: 900, FetchMs: 120, ParseMs: 30, LinkCheckMs: 750},
+	}}
+	svc := NewService(provider, slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	if _, err := svc.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{}); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	want := `"timings":{"total_ms":900,"fetch_ms":120,"parse_ms":30,"link_check_ms":750}`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logs = %s, want %s", logs.String(), want)
+	}
+}
+
+func TestFlightKey(t *testing.T) {
+	base, _ := flightKey("https://Example.com/a#x", model.AnalyzeOptions{})
+	if same, _ := flightKey("https://example.com/a", model.AnalyzeOptions{}); same != base {
+		t.Errorf("keys %q and %q differ, want host case and fragment ignored", base, same)
+	}
+	if other, _ := flightKey("https://example.com/a", model.AnalyzeOptions{CheckImages: true}); other == base {
+		t.Error("analyses with different options share a key")
+	}
+	if _, ok := flightKey("https://example.com/a", model.AnalyzeOptions{Headers: model.RequestHeaders{"Cookie": "a"}}); ok {
+		t.Error("an analysis with caller-supplied headers is shared")
+	}
+}
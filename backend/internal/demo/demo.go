@@ -0,0 +1,72 @@
+// Package demo provides a built-in sample page and deterministic stubs so the
+// analysis pipeline can be exercised without reaching the network.
+package demo
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/pageinsight"
+)
+
+const (
+	// Target is the analyze request URL that selects the sample page.
+	Target = "demo"
+	// Path is where the sample page is served.
+	Path = "/demo/page"
+	// URL is the address the sample page is analyzed as when requested via Target.
+	URL = "https://page-insight.test" + Path
+)
+
+// Page is the embedded sample HTML document.
+//
+//go:embed page.html
+var Page []byte
+
+// inaccessiblePaths are the sample page links the stub checker reports as broken.
+var inaccessiblePaths = map[string]struct{}{
+	"/docs/missing": {},
+	"/gone":         {},
+}
+
+// NewEngine returns an Engine that analyzes the sample page with the real
+// parser, whatever URL it is asked for.
+func NewEngine() *pageinsight.Engine {
+	return pageinsight.NewEngine(Fetcher{}, LinkChecker{})
+}
+
+// Fetcher serves the sample page for every URL.
+type Fetcher struct{}
+
+// Fetch returns the embedded sample page.
+func (Fetcher) Fetch(_ context.Context, _ string) (*pageinsight.Response, error) {
+	return &pageinsight.Response{
+		Body:       io.NopCloser(bytes.NewReader(Page)),
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+// LinkChecker marks a fixed set of sample page links inaccessible without
+// making any requests.
+type LinkChecker struct{}
+
+// CheckLinks reports the links whose path is in inaccessiblePaths as 404s and
+// the rest as 200s.
+func (LinkChecker) CheckLinks(_ context.Context, links []string) pageinsight.LinkReport {
+	report := pageinsight.LinkReport{Checked: len(links)}
+	for _, link := range links {
+		status := http.StatusOK
+		if u, err := url.Parse(link); err == nil {
+			if _, bad := inaccessiblePaths[u.Path]; bad {
+				status = http.StatusNotFound
+				report.Inaccessible++
+			}
+		}
+		report.Results = append(report.Results, pageinsight.StatusResult(link, status))
+	}
+	return report
+}
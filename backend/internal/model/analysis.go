@@ -1,13 +1,107 @@
 package model
 
-// PageAnalysis holds the complete result of analyzing a web page.
+import "log/slog"
+
+// PageAnalysis holds the complete result of analyzing a web page. FinalURL
+// is where the fetch of URL ended up after Redirects redirects. Cached is
+// set when the page had not changed since an earlier analysis, which is
+// returned as it was apart from its Timings. ContentHash identifies the body
+// of a fetched page. LikelySoft404 flags a page served with a success status
+// that reads like a not-found page.
 type PageAnalysis struct {
-	URL          string         `json:"url"`
-	HTMLVersion  string         `json:"html_version"`
-	Title        string         `json:"title"`
-	Headings     map[string]int `json:"headings"`
-	Links        LinkStats      `json:"links"`
-	HasLoginForm bool           `json:"has_login_form"`
+	URL                      string           `json:"url"`
+	FinalURL                 string           `json:"final_url,omitempty"`
+	Redirects                int              `json:"redirect_count"`
+	Cached                   bool             `json:"cached"`
+	ContentHash              *ContentHash     `json:"content_hash,omitempty"`
+	HTMLVersion              string           `json:"html_version"`
+	Doctype                  string           `json:"doctype,omitempty"`
+	Title                    string           `json:"title"`
+	TitleLength              int              `json:"title_length"`
+	MultipleTitles           bool             `json:"multiple_titles"`
+	Headings                 map[string]int   `json:"headings"`
+	FirstH1                  string           `json:"first_h1"`
+	Dates                    PageDates        `json:"dates"`
+	TextRatio                float64          `json:"text_to_html_ratio"`
+	DOM                      DOMStats         `json:"dom"`
+	LikelyRequiresJavascript bool             `json:"likely_requires_javascript"`
+	LikelySoft404            bool             `json:"likely_soft_404"`
+	Accessibility            Accessibility    `json:"accessibility"`
+	Links                    LinkStats        `json:"links"`
+	LinksDetail              []LinkDetail     `json:"links_detail,omitempty"`
+	BrokenFragmentSamples    []string         `json:"broken_fragment_samples,omitempty"`
+	Media                    MediaStats       `json:"media"`
+	HasLoginForm             bool             `json:"has_login_form"`
+	LoginForm                string           `json:"login_form"`
+	HasSearchForm            bool             `json:"has_search_form"`
+	SearchAction             string           `json:"search_action,omitempty"`
+	PWA                      PWASignals       `json:"pwa"`
+	MetaRefresh              *MetaRefresh     `json:"meta_refresh,omitempty"`
+	Pagination               Pagination       `json:"pagination"`
+	ResourceHints            ResourceHints    `json:"resource_hints"`
+	Tables                   TableStats       `json:"tables"`
+	Images                   ImageStats       `json:"images"`
+	SVG                      SVGStats         `json:"svg"`
+	InlineEventHandlers      int              `json:"inline_event_handlers"`
+	JavascriptLinks          int              `json:"javascript_links"`
+	InlineStyles             InlineStyles     `json:"inline_styles"`
+	MixedContent             MixedContent     `json:"mixed_content"`
+	ThirdPartyDomains        []DomainCount    `json:"third_party_domains,omitempty"`
+	Trackers                 []string         `json:"trackers,omitempty"`
+	Analytics                []string         `json:"analytics,omitempty"`
+	BotProtection            []string         `json:"bot_protection,omitempty"`
+	Transfer                 TransferStats    `json:"transfer"`
+	Fetch                    FetchStats       `json:"fetch"`
+	Timings                  Timings          `json:"timings"`
+	SecurityHeaders          *SecurityHeaders `json:"security_headers,omitempty"`
+	TLS                      *TLSInfo         `json:"tls,omitempty"`
+	Robots                   *RobotsTxt       `json:"robots,omitempty"`
+	Warnings                 []string         `json:"warnings,omitempty"`
+}
+
+// RobotsTxt reports the analyzed site's robots.txt, when the server is
+// configured to check it. Found is false when the site has none that could
+// be read. Disallowed is set when its rules forbid fetching the page, which
+// was analyzed anyway. Sitemaps lists its sitemap directives.
+type RobotsTxt struct {
+	Found      bool     `json:"found"`
+	Disallowed bool     `json:"disallowed"`
+	Sitemaps   []string `json:"sitemaps,omitempty"`
+}
+
+// DOMStats measures the size of the element tree. MarkupWarnings counts
+// structural problems such as stray or missing end tags, with a few described
+// in MarkupSamples.
+type DOMStats struct {
+	Nodes          int      `json:"node_count"`
+	MaxDepth       int      `json:"max_depth"`
+	MarkupWarnings int      `json:"markup_warning_count"`
+	MarkupSamples  []string `json:"markup_samples,omitempty"`
+}
+
+// PageDates are the published and modified dates a page declares, as RFC 3339
+// strings, each with the source it came from: "json-ld", "opengraph" or
+// "time". Fields are empty when the page gives no valid date.
+type PageDates struct {
+	PublishedAt     string `json:"published_at,omitempty"`
+	PublishedSource string `json:"published_source,omitempty"`
+	ModifiedAt      string `json:"modified_at,omitempty"`
+	ModifiedSource  string `json:"modified_source,omitempty"`
+}
+
+// Accessibility collects static accessibility checks. UnlabeledInputs counts
+// form controls, other than hidden inputs and buttons, that have no <label>,
+// aria-label or aria-labelledby. EmptyHeadings counts headings with no text,
+// or only whitespace or images without alt text. Landmarks counts ARIA
+// landmarks by role, whether set with a role attribute or implied by
+// elements such as <nav> and <main>; NoLandmarks flags a page without any.
+// Roles counts the values of every role attribute.
+type Accessibility struct {
+	UnlabeledInputs int            `json:"unlabeled_input_count"`
+	EmptyHeadings   int            `json:"empty_heading_count"`
+	Landmarks       map[string]int `json:"landmarks,omitempty"`
+	NoLandmarks     bool           `json:"no_landmarks"`
+	Roles           map[string]int `json:"roles,omitempty"`
 }
 
 // LinkStats breaks down the links found on a page.
@@ -15,11 +109,312 @@ type LinkStats struct {
 	Internal     int `json:"internal_count"`
 	External     int `json:"external_count"`
 	Inaccessible int `json:"inaccessible_count"`
+	// InternalInaccessible and ExternalInaccessible split Inaccessible by
+	// whether the broken link points at the analyzed site. Counts for links
+	// that were not checked, because the request restricted link checking,
+	// are -1; Inaccessible is -1 only when no links were checked.
+	InternalInaccessible int `json:"internal_inaccessible_count"`
+	ExternalInaccessible int `json:"external_inaccessible_count"`
+	// The failure breakdown counts inaccessible links by cause. Connection
+	// errors cover refused, blocked and otherwise failed connections; HTTP
+	// errors are 4xx and 5xx responses.
+	TLSErrors        int `json:"tls_errors"`
+	DNSErrors        int `json:"dns_errors"`
+	Timeouts         int `json:"timeouts"`
+	ConnectionErrors int `json:"connection_errors"`
+	HTTPErrors       int `json:"http_errors"`
+	// RateLimited counts links that kept answering 429 Too Many Requests (or
+	// LinkedIn's 999) after a retry. They are not counted as inaccessible.
+	RateLimited int `json:"rate_limited_count"`
+	// Skipped counts links on hosts the server is configured to block, which
+	// are not requested and not counted as inaccessible.
+	Skipped int `json:"skipped_count"`
+	// Cached counts links whose verdict was reused from a recent check of
+	// the same URL instead of being requested again.
+	Cached int `json:"cached_count"`
+	// Checked counts the unique link targets checked. Partial is set when
+	// the analysis was cancelled or ran out of time before all of them were,
+	// so the inaccessible counts only cover the links checked so far.
+	Checked int  `json:"checked_count"`
+	Partial bool `json:"partial"`
+	// Sampled is set when the page had more links than the link checker's
+	// cap and a uniform random sample of them was checked instead of the
+	// first ones; EstimatedInaccessible then extrapolates Inaccessible to all
+	// the links. Otherwise it equals Inaccessible.
+	Sampled               bool `json:"sampled"`
+	EstimatedInaccessible int  `json:"estimated_inaccessible_count"`
+	// Excluded counts the unique link targets matching an exclude_links
+	// pattern of the request, which were counted but never checked.
+	Excluded int `json:"excluded_count"`
+	// LatencyP50Ms and LatencyP95Ms are percentiles of how long the links
+	// that were requested took to check; cached links and links skipped for
+	// a failing host are left out. SlowestLinks lists the slowest of them.
+	LatencyP50Ms int64      `json:"latency_p50_ms"`
+	LatencyP95Ms int64      `json:"latency_p95_ms"`
+	SlowestLinks []SlowLink `json:"slowest_links,omitempty"`
+	EmptyText    int        `json:"empty_text_count"`
+	Fragment     int        `json:"fragment_count"`
+	// BrokenFragments counts same-page links whose #fragment matches no id
+	// or <a name> on the page; BrokenFragmentSamples in PageAnalysis lists a
+	// few of them.
+	BrokenFragments int `json:"broken_fragment_count"`
+	// UnsafeTargetBlank counts target="_blank" links without rel="noopener"
+	// or rel="noreferrer", which expose the page to tab-nabbing.
+	UnsafeTargetBlank int `json:"unsafe_target_blank_count"`
+}
+
+// SlowLink is a link target with how long checking it took.
+type SlowLink struct {
+	URL       string `json:"url"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// LinkDetail describes one link on the page. Rel lists the lowercase tokens
+// of its rel attribute. Status and Failure are only set when Checked is true:
+// Status is the final HTTP status, 0 when no response arrived, and Failure
+// is the category of why the link is inaccessible: "dns", "timeout", "tls",
+// "connection-refused", "blocked-private-ip", "http-4xx", "http-5xx",
+// "rate-limited", "blocked-host", "invalid-url" or "unreachable". Redirects
+// counts the redirects followed while checking, and FinalURL is where they
+// led.
+// ElapsedMs is how long the check took, 0 when no request was sent.
+type LinkDetail struct {
+	URL       string   `json:"url"`
+	Internal  bool     `json:"internal"`
+	Rel       []string `json:"rel,omitempty"`
+	Text      string   `json:"text"`
+	Checked   bool     `json:"checked"`
+	Status    int      `json:"status,omitempty"`
+	Failure   string   `json:"failure,omitempty"`
+	Redirects int      `json:"redirects,omitempty"`
+	FinalURL  string   `json:"final_url,omitempty"`
+	ElapsedMs int64    `json:"elapsed_ms,omitempty"`
+}
+
+// MediaStats counts media elements and the video/audio source URLs they
+// reference. Inaccessible is only meaningful when Checked is true, since
+// media URLs are not link checked by default.
+type MediaStats struct {
+	Videos       int  `json:"video_count"`
+	Audios       int  `json:"audio_count"`
+	Pictures     int  `json:"picture_count"`
+	Internal     int  `json:"internal_count"`
+	External     int  `json:"external_count"`
+	Checked      bool `json:"checked"`
+	Inaccessible int  `json:"inaccessible_count"`
+}
+
+// PWASignals are static hints that a page is a progressive web app. The
+// manifest URL is link checked, so a missing manifest shows up.
+type PWASignals struct {
+	ManifestURL           string `json:"manifest_url,omitempty"`
+	ManifestInaccessible  bool   `json:"manifest_inaccessible"`
+	MentionsServiceWorker bool   `json:"mentions_service_worker"`
+}
+
+// MetaRefresh describes a <meta http-equiv="refresh"> on the page. URL is
+// empty when the page reloads itself. Followed is set when the analysis
+// followed the refresh and describes the page it pointed to.
+type MetaRefresh struct {
+	DelaySeconds int    `json:"delay_seconds"`
+	URL          string `json:"url,omitempty"`
+	Followed     bool   `json:"followed"`
+}
+
+// Pagination holds the resolved <link rel="next"> and <link rel="prev">
+// targets of a paginated listing, empty when absent. Both are link checked.
+type Pagination struct {
+	Next             string `json:"next,omitempty"`
+	Prev             string `json:"prev,omitempty"`
+	NextInaccessible bool   `json:"next_inaccessible"`
+	PrevInaccessible bool   `json:"prev_inaccessible"`
 }
 
-// ErrorResponse is the JSON shape returned on failure.
+// ResourceHints counts <link> preload, prefetch, dns-prefetch and preconnect
+// hints. Hosts lists the distinct hosts they target, so stale hints stand out.
+// Preload targets are link checked only when requested, so
+// PreloadsInaccessible is only meaningful when PreloadsChecked is true.
+type ResourceHints struct {
+	Preload              int      `json:"preload_count"`
+	Prefetch             int      `json:"prefetch_count"`
+	DNSPrefetch          int      `json:"dns_prefetch_count"`
+	Preconnect           int      `json:"preconnect_count"`
+	Hosts                []string `json:"hosts,omitempty"`
+	PreloadsChecked      bool     `json:"preloads_checked"`
+	PreloadsInaccessible int      `json:"preloads_inaccessible_count"`
+}
+
+// TableStats counts <table> elements. LayoutSuspect counts tables that look
+// like layout scaffolding: no header cells or caption, or another table nested
+// inside.
+type TableStats struct {
+	Count         int `json:"count"`
+	LayoutSuspect int `json:"layout_suspect_count"`
+}
+
+// ImageStats summarizes how <img> elements are loaded. MissingDimensions
+// counts images without both width and height attributes, which cause layout
+// shifts while they load. Image URLs, srcset candidates included, are link
+// checked only when requested, so Inaccessible is only meaningful when Checked
+// is true.
+type ImageStats struct {
+	Count             int  `json:"count"`
+	Lazy              int  `json:"lazy_count"`
+	MissingDimensions int  `json:"missing_dimensions_count"`
+	Srcset            int  `json:"srcset_count"`
+	Checked           bool `json:"checked"`
+	Inaccessible      int  `json:"inaccessible_count"`
+}
+
+// SVGStats summarizes inline <svg> usage. InlineBytes is the raw markup size
+// of all inline SVGs combined.
+type SVGStats struct {
+	Count       int `json:"count"`
+	InlineBytes int `json:"inline_bytes"`
+	Oversized   int `json:"oversized_count"`
+}
+
+// InlineStyles counts CSS written into the page: elements with a style
+// attribute and <style> blocks. CSSBytes is the size of both combined.
+type InlineStyles struct {
+	Attributes int `json:"style_attributes"`
+	Blocks     int `json:"style_blocks"`
+	CSSBytes   int `json:"css_bytes"`
+}
+
+// MixedContent counts resources an https page loads over plain http, by
+// resource type, with a few example URLs.
+type MixedContent struct {
+	Images      int      `json:"images"`
+	Scripts     int      `json:"scripts"`
+	Stylesheets int      `json:"stylesheets"`
+	Iframes     int      `json:"iframes"`
+	FormActions int      `json:"form_actions"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// DomainCount is the number of external scripts and images loaded from one
+// host. Count is their total.
+type DomainCount struct {
+	Domain  string `json:"domain"`
+	Count   int    `json:"count"`
+	Scripts int    `json:"scripts"`
+	Images  int    `json:"images"`
+}
+
+// TransferStats describes how the page was delivered over the wire.
+// WireBytes is omitted when the compressed size is unknown.
+type TransferStats struct {
+	Compressed       bool    `json:"compressed"`
+	ContentEncoding  string  `json:"content_encoding,omitempty"`
+	WireBytes        int64   `json:"wire_bytes,omitempty"`
+	DecodedBytes     int64   `json:"decoded_bytes"`
+	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+}
+
+// FetchStats measures the fetch of the page: the DNS lookup, connect and TLS
+// handshake of its connection, zero when one was reused, the time to its
+// first response byte and to the end of its body, and the decoded body size.
+// Truncated is set when the page went past the body size limit and only its
+// start was analyzed. Protocol is the HTTP version used, like HTTP/2.0, and
+// Server and PoweredBy echo the Server and X-Powered-By headers. Attempts
+// counts the requests made for the page, retries included.
+type FetchStats struct {
+	DNSMs     int64  `json:"dns_ms"`
+	ConnectMs int64  `json:"connect_ms"`
+	TLSMs     int64  `json:"tls_ms"`
+	TTFBMs    int64  `json:"ttfb_ms"`
+	TotalMs   int64  `json:"total_ms"`
+	BodyBytes int64  `json:"body_bytes"`
+	Truncated bool   `json:"truncated"`
+	Protocol  string `json:"protocol,omitempty"`
+	Server    string `json:"server,omitempty"`
+	PoweredBy string `json:"powered_by,omitempty"`
+	Attempts  int    `json:"attempts,omitempty"`
+}
+
+// ContentHash is the hex SHA-256 of the decoded page body as it was read.
+// Truncated is set when the body went past the size limit and only its
+// start was hashed; such a hash only matches another truncated one.
+type ContentHash struct {
+	SHA256    string `json:"sha256"`
+	Truncated bool   `json:"truncated"`
+}
+
+// SecurityHeaders holds the security-related headers of the page response.
+// A header the page did not send is null; one sent without a value is an
+// empty string. Values longer than 512 bytes are cut short.
+type SecurityHeaders struct {
+	StrictTransportSecurity *string `json:"strict_transport_security"`
+	ContentSecurityPolicy   *string `json:"content_security_policy"`
+	XFrameOptions           *string `json:"x_frame_options"`
+	XContentTypeOptions     *string `json:"x_content_type_options"`
+	ReferrerPolicy          *string `json:"referrer_policy"`
+	PermissionsPolicy       *string `json:"permissions_policy"`
+}
+
+// TLSInfo describes the TLS connection of an https page and its leaf
+// certificate, whose expiry is an RFC 3339 string. ExpiresSoon is set when
+// the certificate expires within 14 days, or already has.
+type TLSInfo struct {
+	Version             string `json:"version"`
+	CertificateSubject  string `json:"certificate_subject,omitempty"`
+	CertificateIssuer   string `json:"certificate_issuer,omitempty"`
+	CertificateNotAfter string `json:"certificate_not_after,omitempty"`
+	DaysUntilExpiry     int    `json:"days_until_expiry"`
+	ExpiresSoon         bool   `json:"expires_soon"`
+}
+
+// ErrorResponse is the JSON shape returned on failure. UpstreamStatus is the
+// status the target answered with, when it answered with an error.
 type ErrorResponse struct {
-	Error      string `json:"error"`
-	StatusCode int    `json:"status_code"`
-	Message    string `json:"message"`
+	Error          string `json:"error"`
+	StatusCode     int    `json:"status_code"`
+	UpstreamStatus int    `json:"upstream_status,omitempty"`
+	Message        string `json:"message"`
+	Detail         any    `json:"detail,omitempty"`
+}
+
+// UpstreamErrorDetail describes the error page the target answered with. Its
+// title, shortened and stripped of control characters, often tells a WAF or
+// CDN block page apart from an error of the site itself.
+type UpstreamErrorDetail struct {
+	Title string `json:"title"`
+}
+
+// TimeoutDetail explains where an analysis was when its deadline passed.
+// The link counts are only set when the deadline hit during link checking.
+type TimeoutDetail struct {
+	Phase           string        `json:"phase"`
+	PhaseElapsedMs  int64         `json:"phase_elapsed_ms"`
+	CompletedPhases []PhaseTiming `json:"completed_phases"`
+	LinksChecked    int           `json:"links_checked,omitempty"`
+	LinksTotal      int           `json:"links_total,omitempty"`
+}
+
+// Timings is where the time of an analysis went: its total and the time
+// spent fetching the page, parsing it and checking its links. A phase that
+// ran twice, like the fetch of a followed meta refresh, counts both runs; one
+// that didn't run is zero.
+type Timings struct {
+	TotalMs     int64 `json:"total_ms"`
+	FetchMs     int64 `json:"fetch_ms"`
+	ParseMs     int64 `json:"parse_ms"`
+	LinkCheckMs int64 `json:"link_check_ms"`
+}
+
+// LogValue logs the timings under the same names as the response.
+func (t Timings) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int64("total_ms", t.TotalMs),
+		slog.Int64("fetch_ms", t.FetchMs),
+		slog.Int64("parse_ms", t.ParseMs),
+		slog.Int64("link_check_ms", t.LinkCheckMs),
+	)
+}
+
+// PhaseTiming is the duration of one completed analysis phase.
+type PhaseTiming struct {
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"duration_ms"`
 }
@@ -0,0 +1,52 @@
+package model
+
+// CrawlOptions are the settings for a multi-page crawl. Analyze applies to
+// every page crawled.
+type CrawlOptions struct {
+	// Depth is how many links away from the start page the crawl goes; 0
+	// analyzes the start page only.
+	Depth int
+	// MaxPages caps how many pages are analyzed, the start page included.
+	MaxPages int
+	// IgnoreQueryParams treats links that differ only by their query as one
+	// page.
+	IgnoreQueryParams bool
+	// StripParams names query parameters, such as utm_source or sort, that
+	// don't make a link a different page.
+	StripParams []string
+	Analyze     AnalyzeOptions
+}
+
+// Crawl defaults, used when a request leaves the depth or page limit out.
+const (
+	DefaultCrawlDepth = 1
+	DefaultCrawlPages = 10
+)
+
+// SiteCrawl holds the results of crawling a site from one start page.
+type SiteCrawl struct {
+	URL     string        `json:"url"`
+	Pages   []CrawledPage `json:"pages"`
+	Summary CrawlSummary  `json:"summary"`
+}
+
+// CrawledPage is one page of a crawl, found Depth links away from the start
+// page. Error is set instead of Analysis when the page could not be analyzed.
+type CrawledPage struct {
+	URL      string        `json:"url"`
+	Depth    int           `json:"depth"`
+	Analysis *PageAnalysis `json:"analysis,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// CrawlSummary aggregates the pages of a crawl. InaccessibleLinks sums each
+// page's count, so a broken link on every page counts once per page.
+// Truncated is set when the page limit or the deadline stopped the crawl
+// before every page in reach was analyzed.
+type CrawlSummary struct {
+	PagesAnalyzed     int      `json:"pages_analyzed"`
+	PagesFailed       int      `json:"pages_failed"`
+	InaccessibleLinks int      `json:"inaccessible_links"`
+	PagesMissingTitle []string `json:"pages_missing_title,omitempty"`
+	Truncated         bool     `json:"truncated"`
+}
@@ -0,0 +1,10 @@
+package model
+
+// StoredAnalysis is an entry of the analysis history. CreatedAt is an RFC
+// 3339 string; the analysis itself is read by its ID.
+type StoredAnalysis struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	RequestID string `json:"request_id,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
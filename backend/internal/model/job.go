@@ -0,0 +1,22 @@
+package model
+
+// Job states, in the order a job goes through them. A job ends either done
+// or failed.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// AnalysisJob is an analysis run in the background. CreatedAt is an RFC 3339
+// string. Result is set once the job is done, in the response schema it was
+// submitted with, and Error once it failed.
+type AnalysisJob struct {
+	ID        string         `json:"id"`
+	Status    string         `json:"status"`
+	URL       string         `json:"url"`
+	CreatedAt string         `json:"created_at"`
+	Result    any            `json:"result,omitempty"`
+	Error     *ErrorResponse `json:"error,omitempty"`
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AnalyzeOptions are per-request settings for an analysis. The zero value is
+// the default behavior.
+type AnalyzeOptions struct {
+	// FollowMetaRefresh analyzes the target of a zero-delay meta refresh
+	// instead of the stub page that contains it. Only one refresh is followed.
+	FollowMetaRefresh bool
+	// CheckPreloads link checks the targets of <link rel="preload"> hints, so
+	// preloads broken by a deploy show up.
+	CheckPreloads bool
+	// CheckImages link checks image URLs, including every srcset candidate,
+	// which can break after a deploy while the fallback src still works.
+	CheckImages bool
+	// IncludeLinks adds per-link detail to the result, capped at the link
+	// checker's limit.
+	IncludeLinks bool
+	// CheckLinks selects which links are link checked: one of the
+	// CheckLinks constants, with "" meaning CheckLinksAll.
+	CheckLinks string
+	// ExcludeLinks lists glob or /regexp/ patterns; links whose URL matches
+	// one are counted but not checked.
+	ExcludeLinks []string
+	// Force analyzes a response whose Content-Type isn't HTML, for servers
+	// that mislabel their pages.
+	Force bool
+	// Timeout, when positive, ends the analysis sooner than the server's
+	// own deadline.
+	Timeout time.Duration
+	// Headers are sent with the page fetch, and with link checks on the
+	// page's own host, to analyze pages behind a login.
+	Headers RequestHeaders
+}
+
+// LogValue logs the options an analysis ran with, keeping the header values
+// out.
+func (o AnalyzeOptions) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Bool("follow_meta_refresh", o.FollowMetaRefresh),
+		slog.Bool("check_preloads", o.CheckPreloads),
+		slog.Bool("check_images", o.CheckImages),
+		slog.Bool("include_links", o.IncludeLinks),
+		slog.String("check_links", o.CheckLinks),
+		slog.Bool("force", o.Force),
+		slog.Duration("timeout", o.Timeout),
+	}
+	if len(o.ExcludeLinks) > 0 {
+		attrs = append(attrs, slog.Any("exclude_links", o.ExcludeLinks))
+	}
+	if len(o.Headers) > 0 {
+		attrs = append(attrs, slog.Any("headers", o.Headers))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// RequestHeaders are caller-supplied request headers, keyed by name. They
+// often carry credentials, so they print and log with their values redacted.
+type RequestHeaders map[string]string
+
+const redacted = "[REDACTED]"
+
+// LogValue keeps the header values out of logs.
+func (h RequestHeaders) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(h))
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		attrs = append(attrs, slog.String(name, redacted))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// String lists the header names, keeping the values out of anything that
+// formats the options.
+func (h RequestHeaders) String() string {
+	names := slices.Sorted(maps.Keys(h))
+	for i, name := range names {
+		names[i] = name + ": " + redacted
+	}
+	return "map[" + strings.Join(names, " ") + "]"
+}
+
+// CheckLinks values.
+const (
+	CheckLinksAll      = "all"
+	CheckLinksInternal = "internal"
+	CheckLinksExternal = "external"
+	CheckLinksNone     = "none"
+)
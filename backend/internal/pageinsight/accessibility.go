@@ -0,0 +1,127 @@
+package pageinsight
+
+import "strings"
+
+// unlabeledExemptTypes are <input> types that need no label: hidden inputs
+// are never shown, and buttons are named by their value or alt text.
+var unlabeledExemptTypes = map[string]bool{
+	"hidden": true, "submit": true, "button": true, "reset": true, "image": true,
+}
+
+// labelTracker finds form controls without an accessible label. A <label for>
+// may come after the control it names, so controls labeled only by id are
+// reconciled at the end of the document.
+type labelTracker struct {
+	labelFor  map[string]bool // ids named by a <label for>
+	inLabel   int             // open <label> elements, which label what they wrap
+	pending   []string        // ids of controls still waiting for a <label for>
+	unlabeled int             // controls with no label and no id
+}
+
+// control records an <input>, <select> or <textarea>. ariaLabeled is set when
+// it has a non-empty aria-label or aria-labelledby.
+func (t *labelTracker) control(typ, id string, ariaLabeled bool) {
+	if unlabeledExemptTypes[strings.ToLower(strings.TrimSpace(typ))] || ariaLabeled || t.inLabel > 0 {
+		return
+	}
+	if id == "" {
+		t.unlabeled++
+		return
+	}
+	t.pending = append(t.pending, id)
+}
+
+func (t *labelTracker) openLabel(forID string) {
+	t.inLabel++
+	if forID == "" {
+		return
+	}
+	if t.labelFor == nil {
+		t.labelFor = make(map[string]bool)
+	}
+	t.labelFor[forID] = true
+}
+
+func (t *labelTracker) closeLabel() {
+	if t.inLabel > 0 {
+		t.inLabel--
+	}
+}
+
+// result counts the controls left without a label.
+func (t *labelTracker) result() int {
+	n := t.unlabeled
+	for _, id := range t.pending {
+		if !t.labelFor[id] {
+			n++
+		}
+	}
+	return n
+}
+
+// landmarkRoles are the ARIA landmark roles.
+var landmarkRoles = map[string]bool{
+	"banner": true, "complementary": true, "contentinfo": true, "form": true,
+	"main": true, "navigation": true, "region": true, "search": true,
+}
+
+// implicitLandmarks maps HTML elements to the landmark role they carry without
+// a role attribute. <form> and <section> are only landmarks when named, so
+// they are not counted implicitly.
+var implicitLandmarks = map[string]string{
+	"nav": "navigation", "main": "main", "aside": "complementary", "search": "search",
+	"header": "banner", "footer": "contentinfo",
+}
+
+// sectioningElements scope a <header> or <footer> to themselves, so inside
+// one those elements are not the page banner or contentinfo.
+var sectioningElements = map[string]bool{
+	"article": true, "aside": true, "main": true, "nav": true, "section": true,
+}
+
+// landmarkTracker counts landmarks, whether from an explicit role or implied
+// by the element, and every role attribute used.
+type landmarkTracker struct {
+	landmarks  map[string]int
+	roles      map[string]int
+	sectioning int // open sectioning elements
+}
+
+// element records a start tag. role is the raw role attribute, empty when
+// there is none; an explicit role overrides the element's implicit one.
+func (t *landmarkTracker) element(tag []byte, role string, selfClosing bool) {
+	var landmark string
+	if fields := strings.Fields(strings.ToLower(role)); len(fields) > 0 {
+		// Further tokens are fallbacks for roles a browser does not know.
+		role = fields[0]
+		t.roles = increment(t.roles, role)
+		if landmarkRoles[role] {
+			landmark = role
+		}
+	} else if implicit := implicitLandmarks[string(tag)]; implicit != "" {
+		scoped := implicit == "banner" || implicit == "contentinfo"
+		if !scoped || t.sectioning == 0 {
+			landmark = implicit
+		}
+	}
+	if landmark != "" {
+		t.landmarks = increment(t.landmarks, landmark)
+	}
+	if sectioningElements[string(tag)] && !selfClosing {
+		t.sectioning++
+	}
+}
+
+func (t *landmarkTracker) endTag(tag []byte) {
+	if sectioningElements[string(tag)] && t.sectioning > 0 {
+		t.sectioning--
+	}
+}
+
+func increment(m map[string]int, key string) map[string]int {
+	if m == nil {
+		m = make(map[string]int)
+	}
+	m[key]++
+	return m
+}
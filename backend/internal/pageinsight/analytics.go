@@ -0,0 +1,94 @@
+package pageinsight
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Google Analytics generations, told apart by their measurement ids.
+const (
+	analyticsGA4       = "Google Analytics 4"
+	analyticsUniversal = "Universal Analytics"
+)
+
+// analyticsSignature identifies one analytics product by substrings of an
+// external script URL or of inline script code, all lowercase.
+type analyticsSignature struct {
+	product string
+	src     []string
+	inline  []string
+}
+
+// analyticsSignatures are checked in order, which is also the order products
+// are reported in.
+var analyticsSignatures = []analyticsSignature{
+	{product: analyticsGA4, src: []string{"googletagmanager.com/gtag/js?id=g-"}},
+	{
+		product: analyticsUniversal,
+		src:     []string{"google-analytics.com/analytics.js", "google-analytics.com/ga.js", "googletagmanager.com/gtag/js?id=ua-"},
+		inline:  []string{"google-analytics.com/analytics.js", "ga('create'", `ga("create"`},
+	},
+	{
+		product: "Google Tag Manager",
+		src:     []string{"googletagmanager.com/gtm.js"},
+		inline:  []string{"googletagmanager.com/gtm.js"},
+	},
+	{product: "Matomo", src: []string{"/matomo.js", "/piwik.js"}, inline: []string{"_paq.push"}},
+	{product: "Plausible", src: []string{"plausible.io/js/"}, inline: []string{"window.plausible"}},
+	{product: "Fathom", src: []string{"usefathom.com/script.js"}, inline: []string{"usefathom.com"}},
+}
+
+// gtagConfig matches gtag('config', ...) calls; the measurement id prefix
+// tells GA4 ("G-") from Universal Analytics ("UA-").
+var gtagConfig = regexp.MustCompile(`gtag\(\s*['"]config['"]\s*,\s*['"](g|ua)-`)
+
+// analyticsDetector records which analytics products a page installs.
+type analyticsDetector struct {
+	found map[string]bool
+}
+
+func (d *analyticsDetector) add(product string) {
+	if d.found == nil {
+		d.found = make(map[string]bool)
+	}
+	d.found[product] = true
+}
+
+func (d *analyticsDetector) scriptSrc(src string) {
+	if src == "" {
+		return
+	}
+	src = strings.ToLower(src)
+	for _, sig := range analyticsSignatures {
+		if containsAny(src, sig.src) {
+			d.add(sig.product)
+		}
+	}
+}
+
+func (d *analyticsDetector) inlineScript(code string) {
+	code = strings.ToLower(code)
+	for _, sig := range analyticsSignatures {
+		if containsAny(code, sig.inline) {
+			d.add(sig.product)
+		}
+	}
+	for _, m := range gtagConfig.FindAllStringSubmatch(code, -1) {
+		if m[1] == "g" {
+			d.add(analyticsGA4)
+		} else {
+			d.add(analyticsUniversal)
+		}
+	}
+}
+
+// products lists the detected products, nil when there are none.
+func (d *analyticsDetector) products() []string {
+	var products []string
+	for _, sig := range analyticsSignatures {
+		if d.found[sig.product] {
+			products = append(products, sig.product)
+		}
+	}
+	return products
+}
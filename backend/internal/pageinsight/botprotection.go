@@ -0,0 +1,76 @@
+package pageinsight
+
+import (
+	"slices"
+	"strings"
+)
+
+// botSignature identifies one CAPTCHA or bot-protection widget by lowercase
+// substrings of its script or iframe URLs, or by the class of the container
+// element it renders into.
+type botSignature struct {
+	product string
+	src     []string
+	classes []string
+}
+
+// botSignatures are reported in this order.
+var botSignatures = []botSignature{
+	{
+		product: "reCAPTCHA",
+		src:     []string{"google.com/recaptcha/", "gstatic.com/recaptcha/", "recaptcha.net/recaptcha/"},
+		classes: []string{"g-recaptcha"},
+	},
+	{product: "hCaptcha", src: []string{"hcaptcha.com/"}, classes: []string{"h-captcha"}},
+	{product: "Cloudflare Turnstile", src: []string{"challenges.cloudflare.com/turnstile/"}, classes: []string{"cf-turnstile"}},
+	{product: "Friendly Captcha", src: []string{"friendlycaptcha"}, classes: []string{"frc-captcha"}},
+	{product: "Arkose Labs", src: []string{"arkoselabs.com/", "funcaptcha.com/"}},
+	{product: "AWS WAF CAPTCHA", src: []string{".captcha.awswaf.com/", ".token.awswaf.com/"}},
+}
+
+// botDetector records which bot-protection widgets a page embeds.
+type botDetector struct {
+	found map[string]bool
+}
+
+func (d *botDetector) add(product string) {
+	if d.found == nil {
+		d.found = make(map[string]bool)
+	}
+	d.found[product] = true
+}
+
+// resourceSrc checks the URL of a <script> or <iframe>.
+func (d *botDetector) resourceSrc(src string) {
+	if src == "" {
+		return
+	}
+	src = strings.ToLower(src)
+	for _, sig := range botSignatures {
+		if containsAny(src, sig.src) {
+			d.add(sig.product)
+		}
+	}
+}
+
+// class checks the class attribute of any element.
+func (d *botDetector) class(value string) {
+	for _, class := range strings.Fields(value) {
+		for _, sig := range botSignatures {
+			if slices.Contains(sig.classes, class) {
+				d.add(sig.product)
+			}
+		}
+	}
+}
+
+// products lists the detected widgets, nil when there are none.
+func (d *botDetector) products() []string {
+	var products []string
+	for _, sig := range botSignatures {
+		if d.found[sig.product] {
+			products = append(products, sig.product)
+		}
+	}
+	return products
+}
@@ -1,38 +1,86 @@
 package pageinsight
 
 import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
 )
 
 // Fetcher defines how the client retrieves raw HTML.
 type Fetcher interface {
-	Fetch(ctx context.Context, url string) (body io.ReadCloser, statusCode int, err error)
+	Fetch(ctx context.Context, url string) (*Response, error)
 }
 
-// limitedReadCloser reads from a LimitReader but closes the original body.
-type limitedReadCloser struct {
-	io.Reader
-	io.Closer
+// Response is a fetched page. Body yields the decoded HTML and must be closed
+// by the caller.
+type Response struct {
+	Body       io.ReadCloser
+	StatusCode int
+	Proto      string
+	Header     http.Header
+	// TLS is the connection state of an https response, nil otherwise.
+	TLS             *tls.ConnectionState
+	ContentType     string
+	ContentEncoding string
+	// WireBytes reports how many bytes have been read off the network so far,
+	// before decompression. It is only final once Body has been fully read,
+	// and is nil when the fetcher cannot tell.
+	WireBytes func() int64
+	// FinalURL is the URL the body was served from after following
+	// Redirects redirects. It is empty when the fetcher cannot tell, in which
+	// case the requested URL is assumed.
+	FinalURL  string
+	Redirects int
+	// Attempts is how many times the page was requested, retries included,
+	// or zero when the fetcher cannot tell.
+	Attempts int
+	// Timing and Truncated describe the fetch and whether the body was cut
+	// off at the size limit. Both are only final once Body has been fully
+	// read, and are nil when the fetcher cannot tell.
+	Timing    func() FetchTiming
+	Truncated func() bool
 }
 
 // HTTPClient implements Fetcher using a real HTTP client.
 type HTTPClient struct {
-	client *http.Client
+	client       *http.Client
+	userAgent    string
+	maxBodyBytes int64
+	maxAttempts  int
 }
 
 const (
 	maxRedirects = 5
-	userAgent    = "PageInsightBot/1.0"
+	// defaultFetchTimeout bounds a page fetch unless another timeout is
+	// configured.
+	defaultFetchTimeout = 10 * time.Second
+	// userAgent identifies page fetches and link checks unless another one is
+	// configured.
+	userAgent = "PageInsightBot/1.0"
+	// pageAccept is the Accept header of page fetches: the media types that
+	// are analyzed without "force".
+	pageAccept = "text/html,application/xhtml+xml"
 )
 
 var (
 	errTooManyRedirects = errors.New("too many redirects")
 	errBlockedRedirect  = errors.New("redirect to non-http(s) scheme blocked")
+	errBadEncoding      = errors.New("malformed compressed response")
+	errUnknownEncoding  = errors.New("unsupported content encoding")
 )
 
 // NewHTTPClient returns a Fetcher backed by an http.Client with a 10s timeout,
@@ -41,9 +89,11 @@ var (
 func NewHTTPClient() *HTTPClient {
 	return &HTTPClient{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: defaultFetchTimeout,
 			Transport: &http.Transport{
-				DialContext:         safeDialer().DialContext,
+				DialContext: safeDialer().DialContext,
+				// A custom DialContext turns HTTP/2 off unless it is asked for.
+				ForceAttemptHTTP2:   true,
 				MaxConnsPerHost:     10,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
@@ -53,7 +103,63 @@ func NewHTTPClient() *HTTPClient {
 	}
 }
 
-// safeRedirectPolicy validates redirect targets and limits the redirect chain length.
+// SetUserAgent replaces the User-Agent sent with page fetches, so operators
+// can add a contact URL as crawler etiquette asks. An empty ua restores the
+// default.
+func (c *HTTPClient) SetUserAgent(ua string) {
+	c.userAgent = ua
+}
+
+// SetTimeout bounds each page fetch, redirects and body read included. Zero
+// or less restores the 10s default.
+func (c *HTTPClient) SetTimeout(d time.Duration) {
+	if d <= 0 {
+		d = defaultFetchTimeout
+	}
+	c.client.Timeout = d
+}
+
+// SetProxy sends page fetches through the HTTP(S) proxy at proxyURL. Targets
+// are then checked against private and reserved addresses before each
+// request, as the connections themselves only reach the proxy.
+func (c *HTTPClient) SetProxy(proxyURL *url.URL) {
+	if t, ok := c.client.Transport.(*http.Transport); ok {
+		c.client.Transport = proxyTransport(t, proxyURL, net.DefaultResolver)
+	}
+}
+
+// SetMaxAttempts sets how many times a page fetch is tried when it fails on
+// a connection error, a temporary DNS failure or a 502, 503 or 504. Zero or
+// less restores the default of 2; 1 turns retries off.
+func (c *HTTPClient) SetMaxAttempts(n int) {
+	c.maxAttempts = n
+}
+
+// SetMaxBodyBytes sets how many decoded bytes of a page are read; the rest is
+// cut off and reported as truncated. Zero or less restores the 10 MB default.
+func (c *HTTPClient) SetMaxBodyBytes(n int64) {
+	c.maxBodyBytes = n
+}
+
+// setRequestHeaders identifies an outbound request with ua, or the default
+// user agent when ua is empty, sets its Accept header, and forwards the
+// X-Request-ID of the analysis it belongs to, so the target's logs can be
+// matched to ours. Caller-supplied headers are added for the analyzed page's
+// host, without overriding these.
+func setRequestHeaders(req *http.Request, ua, accept string) {
+	scopePageHeaders(req)
+	if ua == "" {
+		ua = userAgent
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Accept", accept)
+	if id := requestid.FromContext(req.Context()); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+}
+
+// safeRedirectPolicy validates redirect targets, including against the
+// redirect guard of the fetch, and limits the redirect chain length.
 func safeRedirectPolicy(req *http.Request, via []*http.Request) error {
 	if len(via) >= maxRedirects {
 		return fmt.Errorf("%w: stopped after %d", errTooManyRedirects, maxRedirects)
@@ -61,30 +167,121 @@ func safeRedirectPolicy(req *http.Request, via []*http.Request) error {
 	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
 		return fmt.Errorf("%w: %s", errBlockedRedirect, req.URL.Scheme)
 	}
+	if err := guardRedirect(req); err != nil {
+		return err
+	}
+	scopePageHeaders(req)
 	return nil
 }
 
 // Fetch retrieves the page at the given URL and returns its body.
-func (c *HTTPClient) Fetch(ctx context.Context, targetURL string) (io.ReadCloser, int, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+// Compression is requested explicitly (rather than left to http.Transport)
+// so the compressed and decompressed sizes can both be measured, and so a
+// compressed body is decoded even when a proxy sends one unasked.
+func (c *HTTPClient) Fetch(ctx context.Context, targetURL string) (*Response, error) {
+	trace := newFetchTrace()
+	traced := httptrace.WithClientTrace(ctx, trace.clientTrace())
+	attempts := c.maxAttempts
+	if attempts <= 0 {
+		attempts = defaultFetchAttempts
+	}
+	resp, attempt, err := c.doWithRetry(ctx, attempts, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(traced, http.MethodGet, targetURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		setRequestHeaders(req, c.userAgent, pageAccept)
+		setConditionalHeaders(req)
+		req.Header.Set("Accept-Encoding", "gzip, deflate")
+		return req, nil
+	})
 	if err != nil {
-		return nil, 0, err
+		if attempt > 1 {
+			return nil, fmt.Errorf("%w (after %d attempts)", err, attempt)
+		}
+		return nil, err
 	}
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Accept", "text/html")
 
-	resp, err := c.client.Do(req) //nolint:bodyclose // body is returned to caller via limitedReadCloser
-	if err != nil {
-		return nil, 0, err
+	wire := &countingReader{r: resp.Body}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+
+	// A 304 or 204 has no body to decode, whatever encoding it names.
+	decoded := io.Reader(wire)
+	if !bodilessStatus(resp.StatusCode) {
+		decoded, err = decodeBody(wire, encoding)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
 	}
 
-	// Limit response body to 10 MB to prevent memory exhaustion from
-	// extremely large or infinite responses.
-	const maxResponseBody = 10 << 20
-	limited := &limitedReadCloser{
-		Reader: io.LimitReader(resp.Body, maxResponseBody),
-		Closer: resp.Body,
+	limit := c.maxBodyBytes
+	if limit <= 0 {
+		limit = defaultMaxResponseBody
 	}
+	body := &pageBody{r: decoded, closer: resp.Body, trace: trace, remaining: limit}
+	return &Response{
+		Body:            body,
+		StatusCode:      resp.StatusCode,
+		Proto:           resp.Proto,
+		Header:          resp.Header,
+		TLS:             resp.TLS,
+		ContentType:     resp.Header.Get("Content-Type"),
+		ContentEncoding: encoding,
+		WireBytes:       wire.Count,
+		FinalURL:        resp.Request.URL.String(),
+		Redirects:       redirectCount(resp),
+		Attempts:        attempt,
+		Timing:          trace.timing,
+		Truncated:       body.Truncated,
+	}, nil
+}
+
+// bodilessStatus reports whether a response with status never has a body.
+func bodilessStatus(status int) bool {
+	return status == http.StatusNoContent || status == http.StatusNotModified || (status >= 100 && status < 200)
+}
 
-	return limited, resp.StatusCode, nil
+// decodeBody wraps body in a decompressor for its Content-Encoding. A body
+// in an encoding that was not asked for and can't be decoded is an error
+// rather than bytes the parser would make nonsense of.
+func decodeBody(body io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errBadEncoding, err)
+		}
+		return gz, nil
+	case "deflate":
+		// deflate is meant to be zlib-wrapped, but some servers send raw
+		// deflate data; the zlib header tells them apart.
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errBadEncoding, err)
+		}
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errBadEncoding, err)
+			}
+			return zr, nil
+		}
+		return flate.NewReader(buffered), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownEncoding, encoding)
+	}
+}
+
+// redirectCount counts the redirects followed to get resp, walking back from
+// the final request to the responses that redirected to it.
+func redirectCount(resp *http.Response) int {
+	n := 0
+	for r := resp.Request.Response; r != nil; r = r.Request.Response {
+		n++
+	}
+	return n
 }
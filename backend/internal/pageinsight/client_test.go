@@ -1,13 +1,22 @@
 package pageinsight
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
 )
 
 func TestNewHTTPClient(t *testing.T) {
@@ -18,6 +27,38 @@ func TestNewHTTPClient(t *testing.T) {
 	if c.client == nil {
 		t.Fatal("internal http.Client is nil")
 	}
+
+	// Both transports dial through the safe dialer, which disables HTTP/2
+	// unless it is forced back on.
+	for name, rt := range map[string]http.RoundTripper{
+		"page fetcher": c.client.Transport,
+		"link checker": NewLinkChecker(LinkCheckerOptions{}).client.Transport,
+	} {
+		if tr, ok := rt.(*http.Transport); !ok || !tr.ForceAttemptHTTP2 {
+			t.Errorf("%s transport does not attempt HTTP/2", name)
+		}
+	}
+}
+
+func TestEngine_Analyze_Protocol(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Server", "nginx")
+		w.Header().Set("X-Powered-By", "PHP/8.3")
+		_, _ = fmt.Fprint(w, "<html></html>")
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	engine := NewEngine(&HTTPClient{client: ts.Client()}, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), ts.URL, model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Fetch.Protocol != "HTTP/2.0" || result.Fetch.Server != "nginx" || result.Fetch.PoweredBy != "PHP/8.3" {
+		t.Errorf("protocol = %q, server = %q, powered by = %q, want HTTP/2.0, nginx and PHP/8.3",
+			result.Fetch.Protocol, result.Fetch.Server, result.Fetch.PoweredBy)
+	}
 }
 
 func TestHTTPClient_Fetch(t *testing.T) {
@@ -25,8 +66,8 @@ func TestHTTPClient_Fetch(t *testing.T) {
 		if r.Header.Get("User-Agent") != userAgent {
 			t.Errorf("User-Agent = %q, want %q", r.Header.Get("User-Agent"), userAgent)
 		}
-		if r.Header.Get("Accept") != "text/html" {
-			t.Errorf("Accept = %q, want %q", r.Header.Get("Accept"), "text/html")
+		if r.Header.Get("Accept") != pageAccept {
+			t.Errorf("Accept = %q, want %q", r.Header.Get("Accept"), pageAccept)
 		}
 		w.WriteHeader(http.StatusOK)
 		_, _ = fmt.Fprint(w, "<html><body>Hello</body></html>")
@@ -34,17 +75,17 @@ func TestHTTPClient_Fetch(t *testing.T) {
 	defer ts.Close()
 
 	c := &HTTPClient{client: ts.Client()}
-	body, status, err := c.Fetch(context.Background(), ts.URL)
+	resp, err := c.Fetch(context.Background(), ts.URL)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	defer func() { _ = body.Close() }()
+	defer func() { _ = resp.Body.Close() }()
 
-	if status != http.StatusOK {
-		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
 	}
 
-	data, err := io.ReadAll(body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		t.Fatalf("failed to read body: %v", err)
 	}
@@ -53,9 +94,70 @@ func TestHTTPClient_Fetch(t *testing.T) {
 	}
 }
 
+func TestHTTPClient_Fetch_FinalURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/www", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/www", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/home", http.StatusFound)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, "<html></html>")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	for _, tt := range []struct {
+		path          string
+		wantFinal     string
+		wantRedirects int
+	}{
+		{path: "/", wantFinal: ts.URL + "/home", wantRedirects: 2},
+		{path: "/home", wantFinal: ts.URL + "/home", wantRedirects: 0},
+	} {
+		resp, err := c.Fetch(context.Background(), ts.URL+tt.path)
+		if err != nil {
+			t.Fatalf("Fetch(%s): %v", tt.path, err)
+		}
+		_ = resp.Body.Close()
+		if resp.FinalURL != tt.wantFinal || resp.Redirects != tt.wantRedirects {
+			t.Errorf("Fetch(%s) final URL = %q after %d redirects, want %q after %d",
+				tt.path, resp.FinalURL, resp.Redirects, tt.wantFinal, tt.wantRedirects)
+		}
+	}
+}
+
+func TestHTTPClient_Fetch_ConfiguredHeaders(t *testing.T) {
+	const ua = "PageInsightBot/1.0 (+https://example.org/bot)"
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	c.SetUserAgent(ua)
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	resp, err := c.Fetch(ctx, ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if got.Get("User-Agent") != ua {
+		t.Errorf("User-Agent = %q, want %q", got.Get("User-Agent"), ua)
+	}
+	if got.Get("X-Request-ID") != "req-42" {
+		t.Errorf("X-Request-ID = %q, want %q", got.Get("X-Request-ID"), "req-42")
+	}
+}
+
 func TestHTTPClient_Fetch_InvalidURL(t *testing.T) {
 	c := NewHTTPClient()
-	_, _, err := c.Fetch(context.Background(), "://bad-url")
+	_, err := c.Fetch(context.Background(), "://bad-url")
 	if err == nil {
 		t.Fatal("expected error for invalid URL, got nil")
 	}
@@ -71,12 +173,217 @@ func TestHTTPClient_Fetch_CancelledContext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	_, _, err := c.Fetch(ctx, ts.URL)
+	_, err := c.Fetch(ctx, ts.URL)
 	if err == nil {
 		t.Fatal("expected error for cancelled context, got nil")
 	}
 }
 
+func TestHTTPClient_Fetch_ByteAccounting(t *testing.T) {
+	page := strings.Repeat("<p>hello world</p>", 500)
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte(page))
+	_ = gz.Close()
+
+	tests := []struct {
+		name         string
+		handler      http.HandlerFunc
+		wantEncoding string
+		wantWire     int64
+	}{
+		{
+			name: "gzip",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+					t.Errorf("Accept-Encoding = %q, want gzip", r.Header.Get("Accept-Encoding"))
+				}
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(compressed.Bytes())
+			},
+			wantEncoding: "gzip",
+			wantWire:     int64(compressed.Len()),
+		},
+		{
+			name: "uncompressed",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = fmt.Fprint(w, page)
+			},
+			wantEncoding: "",
+			wantWire:     int64(len(page)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			c := &HTTPClient{client: ts.Client()}
+			resp, err := c.Fetch(context.Background(), ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if string(data) != page {
+				t.Errorf("decoded body length = %d, want %d", len(data), len(page))
+			}
+			if resp.ContentEncoding != tt.wantEncoding {
+				t.Errorf("ContentEncoding = %q, want %q", resp.ContentEncoding, tt.wantEncoding)
+			}
+			if got := resp.WireBytes(); got != tt.wantWire {
+				t.Errorf("WireBytes = %d, want %d", got, tt.wantWire)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_Fetch_Deflate(t *testing.T) {
+	page := "<html><head><title>Deflated</title></head></html>"
+
+	var wrapped bytes.Buffer
+	zw := zlib.NewWriter(&wrapped)
+	_, _ = zw.Write([]byte(page))
+	_ = zw.Close()
+	var raw bytes.Buffer
+	fw, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	_, _ = fw.Write([]byte(page))
+	_ = fw.Close()
+
+	for name, body := range map[string][]byte{"zlib": wrapped.Bytes(), "raw": raw.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "deflate") {
+					t.Errorf("Accept-Encoding = %q, want deflate", r.Header.Get("Accept-Encoding"))
+				}
+				w.Header().Set("Content-Encoding", "deflate")
+				_, _ = w.Write(body)
+			}))
+			defer ts.Close()
+
+			c := &HTTPClient{client: ts.Client()}
+			resp, err := c.Fetch(context.Background(), ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil || string(data) != page {
+				t.Errorf("body = %q, %v, want the decoded page", data, err)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_GzipPage(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, _ = gz.Write([]byte("<!DOCTYPE html><html><head><title>Compressed</title></head><body></body></html>"))
+	_ = gz.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer ts.Close()
+
+	engine := NewEngine(&HTTPClient{client: ts.Client()}, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), ts.URL, model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Title != "Compressed" || result.HTMLVersion != "HTML5" || !result.Transfer.Compressed {
+		t.Errorf("title = %q, version = %q, compressed = %v, want the decoded page",
+			result.Title, result.HTMLVersion, result.Transfer.Compressed)
+	}
+}
+
+func TestHTTPClient_Fetch_DecompressedSizeLimit(t *testing.T) {
+	// 20 MB of zeros compress to a few KB; only the 10 MB default may come out.
+	var bomb bytes.Buffer
+	gz := gzip.NewWriter(&bomb)
+	_, _ = gz.Write(make([]byte, 20<<20))
+	_ = gz.Close()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(bomb.Bytes())
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	resp, err := c.Fetch(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n != defaultMaxResponseBody {
+		t.Errorf("decoded %d bytes, want the 10 MB limit", n)
+	}
+}
+
+func TestHTTPClient_Fetch_UnknownEncoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = fmt.Fprint(w, "\x1b\x00")
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	_, err := c.Fetch(context.Background(), ts.URL)
+	if !errors.Is(err, errUnknownEncoding) {
+		t.Fatalf("err = %v, want errUnknownEncoding", err)
+	}
+}
+
+func TestHTTPClient_Fetch_MalformedGzip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = fmt.Fprint(w, "not gzip")
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	_, err := c.Fetch(context.Background(), ts.URL)
+	if !errors.Is(err, errBadEncoding) {
+		t.Fatalf("err = %v, want errBadEncoding", err)
+	}
+}
+
+func TestHTTPClient_Fetch_BodilessGzip(t *testing.T) {
+	for _, status := range []int{http.StatusNotModified, http.StatusNoContent} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.WriteHeader(status)
+			}))
+			defer ts.Close()
+
+			c := &HTTPClient{client: ts.Client()}
+			resp, err := c.Fetch(context.Background(), ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != status {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, status)
+			}
+			if data, err := io.ReadAll(resp.Body); err != nil || len(data) != 0 {
+				t.Errorf("body = %q, %v, want it empty", data, err)
+			}
+		})
+	}
+}
+
 func TestSafeRedirectPolicy(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,208 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// Upper bounds on a crawl, so one request can't keep the server busy for
+// long. Both are reached well within the analyze timeout on a typical site.
+const (
+	maxCrawlDepth = 3
+	maxCrawlPages = 25
+)
+
+// Crawler analyzes a site page by page, starting from one URL and following
+// its internal links breadth first. Every page gets the same analysis as
+// Engine.Analyze, so robots.txt and the host blocklist apply to each.
+type Crawler struct {
+	engine *Engine
+	delay  time.Duration
+}
+
+// NewCrawler returns a Crawler that analyzes pages with engine, waiting delay
+// between two page fetches from the same host.
+func NewCrawler(engine *Engine, delay time.Duration) *Crawler {
+	return &Crawler{engine: engine, delay: delay}
+}
+
+// crawlTarget is a page waiting to be analyzed.
+type crawlTarget struct {
+	url   string
+	depth int
+}
+
+// Crawl analyzes the start page and the internal pages within opts.Depth
+// links of it, up to opts.MaxPages pages. Links marked rel="nofollow" and
+// links matching opts.Analyze.ExcludeLinks are not followed. The crawl fails
+// only when the start page does; a later page that fails is reported with
+// its error. Links that differ only by query parameters opts ignores count
+// as one page, so they are analyzed once. When ctx ends, the pages analyzed
+// so far are returned. A start URL without a scheme is crawled over https.
+func (c *Crawler) Crawl(ctx context.Context, startURL string, opts model.CrawlOptions) (*model.SiteCrawl, error) {
+	if opts.Depth < 0 || opts.Depth > maxCrawlDepth {
+		return nil, &errs.AppError{
+			Kind:    errs.InvalidInput,
+			Message: fmt.Sprintf("The crawl depth must be between 0 and %d.", maxCrawlDepth),
+		}
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = model.DefaultCrawlPages
+	}
+	if opts.MaxPages > maxCrawlPages {
+		return nil, &errs.AppError{
+			Kind:    errs.InvalidInput,
+			Message: fmt.Sprintf("A crawl can analyze at most %d pages.", maxCrawlPages),
+		}
+	}
+	if guessed, ok := guessScheme(startURL); ok {
+		startURL = guessed
+	}
+	if _, err := parseTargetURL(startURL); err != nil {
+		return nil, err
+	}
+	exclude, err := compileLinkFilter(opts.Analyze.ExcludeLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	crawl := &model.SiteCrawl{URL: startURL, Pages: []model.CrawledPage{}}
+	site := crawlHost(startURL)
+	filter := QueryFilter{IgnoreQuery: opts.IgnoreQueryParams, StripParams: opts.StripParams}
+	visited := map[string]bool{filter.key(startURL): true}
+	queue := []crawlTarget{{url: startURL}}
+	pacer := newHostPacer(c.delay)
+	for len(queue) > 0 {
+		if len(crawl.Pages) >= opts.MaxPages {
+			crawl.Summary.Truncated = true
+			break
+		}
+		next := queue[0]
+		queue = queue[1:]
+		if !pacer.wait(ctx, crawlHost(next.url)) {
+			crawl.Summary.Truncated = true
+			break
+		}
+
+		result, links, err := c.engine.analyze(ctx, next.url, opts.Analyze)
+		if err != nil {
+			if len(crawl.Pages) == 0 {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				// Cut short by the deadline, the page says nothing about the
+				// site; the crawl ends with what it has.
+				crawl.Summary.Truncated = true
+				break
+			}
+			crawl.Pages = append(crawl.Pages, model.CrawledPage{URL: next.url, Depth: next.depth, Error: crawlError(err)})
+			continue
+		}
+		if len(crawl.Pages) == 0 && result.FinalURL != "" {
+			// A start page that redirects, say to www., names the site.
+			site = crawlHost(result.FinalURL)
+		}
+		crawl.Pages = append(crawl.Pages, model.CrawledPage{URL: next.url, Depth: next.depth, Analysis: result})
+		// Redirects and a followed meta refresh land on other URLs, which are
+		// now visited too.
+		visited[filter.key(result.URL)] = true
+		if result.FinalURL != "" {
+			visited[filter.key(result.FinalURL)] = true
+		}
+
+		if next.depth >= opts.Depth {
+			continue
+		}
+		for _, link := range links {
+			// The link is analyzed as written; only the visited key and the
+			// host comparison see it normalized.
+			key := filter.key(link.URL)
+			if visited[key] || crawlHost(link.URL) != site || nofollow(link.Rel) || exclude.excludes(link.URL) {
+				continue
+			}
+			visited[key] = true
+			queue = append(queue, crawlTarget{url: link.URL, depth: next.depth + 1})
+		}
+	}
+
+	summarizeCrawl(crawl)
+	return crawl, nil
+}
+
+// summarizeCrawl fills in the site-level aggregates of a crawl.
+func summarizeCrawl(crawl *model.SiteCrawl) {
+	for _, page := range crawl.Pages {
+		if page.Analysis == nil {
+			crawl.Summary.PagesFailed++
+			continue
+		}
+		crawl.Summary.PagesAnalyzed++
+		// A negative count means the page's links were not checked.
+		crawl.Summary.InaccessibleLinks += max(page.Analysis.Links.Inaccessible, 0)
+		if strings.TrimSpace(page.Analysis.Title) == "" {
+			crawl.Summary.PagesMissingTitle = append(crawl.Summary.PagesMissingTitle, page.URL)
+		}
+	}
+}
+
+// crawlHost is the normalized host, with any non-default port, of a link.
+// Pages are crawled only on the start page's host.
+func crawlHost(link string) string {
+	u, err := url.Parse(normalizeURL(link))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// nofollow reports whether a link's rel attribute asks crawlers not to
+// follow it.
+func nofollow(rel string) bool {
+	return slices.Contains(strings.Fields(strings.ToLower(rel)), "nofollow")
+}
+
+// crawlError describes why a crawled page could not be analyzed.
+func crawlError(err error) string {
+	var appErr *errs.AppError
+	if errors.As(err, &appErr) && appErr.Message != "" {
+		return appErr.Message
+	}
+	return "An unexpected error occurred."
+}
+
+// hostPacer spaces out the page fetches of one crawl to each host.
+type hostPacer struct {
+	delay time.Duration
+	last  map[string]time.Time
+}
+
+func newHostPacer(delay time.Duration) *hostPacer {
+	return &hostPacer{delay: delay, last: make(map[string]time.Time)}
+}
+
+// wait blocks until host may be fetched again and records the fetch. It
+// returns false if ctx ends first.
+func (p *hostPacer) wait(ctx context.Context, host string) bool {
+	if last, ok := p.last[host]; ok && p.delay > 0 {
+		timer := time.NewTimer(time.Until(last.Add(p.delay)))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	p.last[host] = time.Now()
+	return true
+}
@@ -0,0 +1,302 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// crawlSite is a small site: the home page links to two pages, one of which
+// links further down, plus an external and a nofollow link that are not
+// crawled.
+var crawlSite = pagesFetcher{
+	"https://example.com/": `<html><head><title>Home</title></head><body>
+		<a href="/about">About</a>
+		<a href="/blog">Blog</a>
+		<a href="/about#team">Team</a>
+		<a href="/login" rel="nofollow">Log in</a>
+		<a href="https://other.example/">Elsewhere</a>
+	</body></html>`,
+	"https://example.com/about":     `<html><head><title>About</title></head><body><a href="/">Home</a></body></html>`,
+	"https://example.com/blog":      `<html><body><a href="/blog/post">Post</a><a href="/missing">Gone</a></body></html>`,
+	"https://example.com/blog/post": `<html><head><title>Post</title></head></html>`,
+}
+
+func crawledURLs(crawl *model.SiteCrawl) []string {
+	urls := make([]string, 0, len(crawl.Pages))
+	for _, page := range crawl.Pages {
+		urls = append(urls, page.URL)
+	}
+	return urls
+}
+
+func TestCrawler_Crawl(t *testing.T) {
+	tests := []struct {
+		name          string
+		depth         int
+		maxPages      int
+		wantURLs      []string
+		wantTruncated bool
+	}{
+		{
+			name:     "start page only",
+			depth:    0,
+			wantURLs: []string{"https://example.com/"},
+		},
+		{
+			name:     "one link deep",
+			depth:    1,
+			wantURLs: []string{"https://example.com/", "https://example.com/about", "https://example.com/blog"},
+		},
+		{
+			name:  "two links deep",
+			depth: 2,
+			wantURLs: []string{
+				"https://example.com/", "https://example.com/about", "https://example.com/blog",
+				"https://example.com/blog/post", "https://example.com/missing",
+			},
+		},
+		{
+			name:          "page limit",
+			depth:         2,
+			maxPages:      2,
+			wantURLs:      []string{"https://example.com/", "https://example.com/about"},
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{}), 0)
+			crawl, err := crawler.Crawl(context.Background(), "https://example.com/", model.CrawlOptions{Depth: tt.depth, MaxPages: tt.maxPages})
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if got := crawledURLs(crawl); !slices.Equal(got, tt.wantURLs) {
+				t.Errorf("pages = %v, want %v", got, tt.wantURLs)
+			}
+			if crawl.Summary.Truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", crawl.Summary.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+// wwwFetcher redirects example.com to www.example.com and serves crawlSite
+// there.
+type wwwFetcher struct{}
+
+func (wwwFetcher) Fetch(ctx context.Context, target string) (*Response, error) {
+	final := strings.Replace(target, "https://example.com", "https://www.example.com", 1)
+	resp, err := crawlSite.Fetch(ctx, strings.Replace(final, "www.", "", 1))
+	if err != nil {
+		return nil, err
+	}
+	resp.FinalURL = final
+	if final != target {
+		resp.Redirects = 1
+	}
+	return resp, nil
+}
+
+func TestCrawler_Crawl_FollowsStartRedirect(t *testing.T) {
+	crawler := NewCrawler(NewEngine(wwwFetcher{}, &mockLinkChecker{}), 0)
+	crawl, err := crawler.Crawl(context.Background(), "https://example.com/", model.CrawlOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	want := []string{"https://example.com/", "https://www.example.com/about", "https://www.example.com/blog"}
+	if got := crawledURLs(crawl); !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestCrawler_Crawl_Summary(t *testing.T) {
+	crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{inaccessible: 1}), 0)
+	crawl, err := crawler.Crawl(context.Background(), "https://example.com/", model.CrawlOptions{Depth: 2})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+
+	missing := crawl.Pages[4]
+	if missing.Analysis != nil || missing.Error == "" || missing.Depth != 2 {
+		t.Errorf("missing page = %+v, want an error at depth 2", missing)
+	}
+	want := model.CrawlSummary{
+		PagesAnalyzed:     4,
+		PagesFailed:       1,
+		InaccessibleLinks: 3,
+		PagesMissingTitle: []string{"https://example.com/blog"},
+	}
+	if !reflect.DeepEqual(crawl.Summary, want) {
+		t.Errorf("summary = %+v, want %+v", crawl.Summary, want)
+	}
+}
+
+// facetedSite links to one listing page under several query spellings.
+var facetedSite = pagesFetcher{
+	"https://example.com/": `<html><head><title>Shop</title></head><body>
+		<a href="/shoes?page=1">Shoes</a>
+		<a href="/shoes?page=1&sort=price">By price</a>
+		<a href="/shoes?sort=name&page=1">By name</a>
+		<a href="/shoes?utm_source=ad&page=1">Ad</a>
+		<a href="/shoes?page=2">More shoes</a>
+		<a href="/about">About</a>
+	</body></html>`,
+	"https://example.com/shoes?page=1": `<html><head><title>Shoes</title></head></html>`,
+	"https://example.com/shoes?page=2": `<html><head><title>More shoes</title></head></html>`,
+	"https://example.com/about":        `<html><head><title>About</title></head></html>`,
+}
+
+func TestCrawler_Crawl_QueryVariants(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          model.CrawlOptions
+		wantURLs      []string
+		wantTruncated bool
+	}{
+		{
+			name:          "every query spelling is a page",
+			opts:          model.CrawlOptions{Depth: 1, MaxPages: 3},
+			wantURLs:      []string{"https://example.com/", "https://example.com/shoes?page=1", "https://example.com/shoes?page=1&sort=price"},
+			wantTruncated: true,
+		},
+		{
+			name: "stripped params",
+			opts: model.CrawlOptions{Depth: 1, MaxPages: 3, StripParams: []string{"sort", "utm_source"}},
+			wantURLs: []string{
+				"https://example.com/", "https://example.com/shoes?page=1", "https://example.com/shoes?page=2",
+			},
+			wantTruncated: true,
+		},
+		{
+			name: "stripped params within the page limit",
+			opts: model.CrawlOptions{Depth: 1, MaxPages: 10, StripParams: []string{"sort", "utm_source"}},
+			wantURLs: []string{
+				"https://example.com/", "https://example.com/shoes?page=1", "https://example.com/shoes?page=2",
+				"https://example.com/about",
+			},
+		},
+		{
+			name:     "query ignored",
+			opts:     model.CrawlOptions{Depth: 1, MaxPages: 10, IgnoreQueryParams: true},
+			wantURLs: []string{"https://example.com/", "https://example.com/shoes?page=1", "https://example.com/about"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := NewCrawler(NewEngine(facetedSite, &mockLinkChecker{}), 0)
+			crawl, err := crawler.Crawl(context.Background(), "https://example.com/", tt.opts)
+			if err != nil {
+				t.Fatalf("Crawl: %v", err)
+			}
+			if got := crawledURLs(crawl); !slices.Equal(got, tt.wantURLs) {
+				t.Errorf("pages = %v, want %v", got, tt.wantURLs)
+			}
+			if crawl.Summary.Truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", crawl.Summary.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestCrawler_Crawl_FetchesLinksAsWritten(t *testing.T) {
+	// The docs page only answers with its trailing slash, so dropping it
+	// would turn the page into a 404.
+	site := pagesFetcher{
+		"https://example.com/":      `<html><body><a href="/docs/">Docs</a><a href="/docs">Docs again</a></body></html>`,
+		"https://example.com/docs/": `<html><head><title>Docs</title></head></html>`,
+	}
+	crawler := NewCrawler(NewEngine(site, &mockLinkChecker{}), 0)
+	crawl, err := crawler.Crawl(context.Background(), "https://example.com/", model.CrawlOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if got, want := crawledURLs(crawl), []string{"https://example.com/", "https://example.com/docs/"}; !slices.Equal(got, want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+	if docs := crawl.Pages[1]; docs.Analysis == nil || docs.Analysis.Title != "Docs" {
+		t.Errorf("docs page = %+v, want it analyzed", docs)
+	}
+}
+
+func TestCrawler_Crawl_StartPageFails(t *testing.T) {
+	crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{}), 0)
+	_, err := crawler.Crawl(context.Background(), "https://example.com/missing", model.CrawlOptions{Depth: 1})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.Unreachable {
+		t.Errorf("err = %v, want the start page's Unreachable error", err)
+	}
+}
+
+func TestCrawler_Crawl_ExcludedLinksNotFollowed(t *testing.T) {
+	crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{}), 0)
+	crawl, err := crawler.Crawl(context.Background(), "https://example.com/", model.CrawlOptions{
+		Depth:   1,
+		Analyze: model.AnalyzeOptions{ExcludeLinks: []string{"*/blog*"}},
+	})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if got, want := crawledURLs(crawl), []string{"https://example.com/", "https://example.com/about"}; !slices.Equal(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestCrawler_Crawl_InvalidLimits(t *testing.T) {
+	crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{}), 0)
+	for _, opts := range []model.CrawlOptions{
+		{Depth: -1},
+		{Depth: maxCrawlDepth + 1},
+		{Depth: 1, MaxPages: maxCrawlPages + 1},
+	} {
+		_, err := crawler.Crawl(context.Background(), "https://example.com/", opts)
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput {
+			t.Errorf("Crawl(%+v) err = %v, want InvalidInput", opts, err)
+		}
+	}
+}
+
+func TestCrawler_Crawl_StopsAtDeadline(t *testing.T) {
+	// The politeness delay outlasts the deadline, so only the start page is
+	// analyzed before the crawl gives up.
+	crawler := NewCrawler(NewEngine(crawlSite, &mockLinkChecker{}), time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	crawl, err := crawler.Crawl(ctx, "https://example.com/", model.CrawlOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("Crawl: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Crawl took %v, want it to end at the deadline", elapsed)
+	}
+	if len(crawl.Pages) != 1 || !crawl.Summary.Truncated {
+		t.Errorf("pages = %v, truncated = %v, want the start page and a truncated crawl", crawledURLs(crawl), crawl.Summary.Truncated)
+	}
+}
+
+func TestHostPacer_Wait(t *testing.T) {
+	pacer := newHostPacer(30 * time.Millisecond)
+	ctx := context.Background()
+	if !pacer.wait(ctx, "example.com") || !pacer.wait(ctx, "other.example") {
+		t.Fatal("first fetch of a host should not wait")
+	}
+	start := time.Now()
+	if !pacer.wait(ctx, "example.com") {
+		t.Fatal("wait returned false")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("second fetch of a host waited %v, want about the delay", elapsed)
+	}
+}
@@ -0,0 +1,53 @@
+package pageinsight
+
+import (
+	"net/url"
+	"slices"
+)
+
+// QueryFilter controls which query parameters distinguish one page from
+// another when deduplicating URLs in a crawl, so faceted-search variants
+// such as ?sort= or ?utm_source= are not visited as separate pages.
+type QueryFilter struct {
+	// IgnoreQuery treats URLs that differ only by their query as one page.
+	IgnoreQuery bool
+	// StripParams lists parameters removed before comparison; all others are kept.
+	StripParams []string
+}
+
+// VisitKey returns the key identifying u in a crawl's visited set. The
+// fragment is always dropped, and the remaining query parameters are sorted
+// so the key does not depend on their order in the link.
+func (f QueryFilter) VisitKey(u *url.URL) string {
+	key := *u
+	key.Fragment, key.RawFragment = "", ""
+
+	if f.IgnoreQuery {
+		key.RawQuery, key.ForceQuery = "", false
+		return key.String()
+	}
+
+	values, err := url.ParseQuery(key.RawQuery)
+	if err != nil {
+		// Keep malformed queries verbatim rather than guessing at their meaning.
+		return key.String()
+	}
+	for name := range values {
+		if slices.Contains(f.StripParams, name) {
+			delete(values, name)
+		}
+	}
+	key.RawQuery = values.Encode() // Encode sorts by key
+	key.ForceQuery = false
+	return key.String()
+}
+
+// key is VisitKey for a link that has not been normalized yet.
+func (f QueryFilter) key(link string) string {
+	normalized := normalizeURL(link)
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return normalized
+	}
+	return f.VisitKey(u)
+}
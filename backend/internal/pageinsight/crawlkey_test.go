@@ -0,0 +1,73 @@
+package pageinsight
+
+import "testing"
+
+func TestQueryFilter_VisitKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter QueryFilter
+		url    string
+		want   string
+	}{
+		{
+			name: "keeps query by default, sorted",
+			url:  "https://example.com/list?sort=asc&page=2",
+			want: "https://example.com/list?page=2&sort=asc",
+		},
+		{
+			name: "drops fragment",
+			url:  "https://example.com/list?page=2#top",
+			want: "https://example.com/list?page=2",
+		},
+		{
+			name:   "ignore all query params",
+			filter: QueryFilter{IgnoreQuery: true},
+			url:    "https://example.com/list?sort=asc&page=2",
+			want:   "https://example.com/list",
+		},
+		{
+			name:   "strip selected params and keep the rest",
+			filter: QueryFilter{StripParams: []string{"utm_source", "sort"}},
+			url:    "https://example.com/list?utm_source=mail&page=2&sort=asc&filter=red",
+			want:   "https://example.com/list?filter=red&page=2",
+		},
+		{
+			name:   "repeated params keep their relative order",
+			filter: QueryFilter{StripParams: []string{"sort"}},
+			url:    "https://example.com/list?tag=b&sort=x&tag=a",
+			want:   "https://example.com/list?tag=b&tag=a",
+		},
+		{
+			name:   "stripping every param leaves no trailing question mark",
+			filter: QueryFilter{StripParams: []string{"sort"}},
+			url:    "https://example.com/list?sort=asc",
+			want:   "https://example.com/list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.VisitKey(mustParseURL(tt.url)); got != tt.want {
+				t.Errorf("VisitKey(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryFilter_VisitKey_CollapsesVariants(t *testing.T) {
+	filter := QueryFilter{StripParams: []string{"sort", "utm_source"}}
+	variants := []string{
+		"https://example.com/shoes?page=1",
+		"https://example.com/shoes?page=1&sort=price",
+		"https://example.com/shoes?sort=name&page=1",
+		"https://example.com/shoes?utm_source=ad&page=1#reviews",
+	}
+
+	seen := make(map[string]struct{})
+	for _, v := range variants {
+		seen[filter.VisitKey(mustParseURL(v))] = struct{}{}
+	}
+	if len(seen) != 1 {
+		t.Errorf("variants produced %d keys, want 1: %v", len(seen), seen)
+	}
+}
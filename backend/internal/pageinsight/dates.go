@@ -0,0 +1,143 @@
+package pageinsight
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// Date sources reported in model.PageDates, in order of preference.
+const (
+	dateSourceJSONLD    = "json-ld"
+	dateSourceOpenGraph = "opengraph"
+	dateSourceTime      = "time"
+)
+
+// maxJSONLDBytes caps how much of one JSON-LD block is kept; larger blocks are
+// not decoded.
+const maxJSONLDBytes = 1 << 20 // 1 MB
+
+// dateLayouts are the formats accepted for page dates, most specific first.
+// Dates without a time zone are taken as UTC.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// normalizeDate parses a date in any of dateLayouts and formats it as
+// RFC 3339. ok is false for values that are not dates.
+func normalizeDate(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Format(time.RFC3339), true
+		}
+	}
+	return "", false
+}
+
+// dateCandidates holds the first valid published and modified dates one
+// source gave.
+type dateCandidates struct {
+	published string
+	modified  string
+}
+
+func (c *dateCandidates) set(modified bool, raw string) {
+	field := &c.published
+	if modified {
+		field = &c.modified
+	}
+	if *field != "" {
+		return
+	}
+	if date, ok := normalizeDate(raw); ok {
+		*field = date
+	}
+}
+
+// dateTracker collects page dates from every source and picks the preferred
+// one at the end.
+type dateTracker struct {
+	jsonLD    dateCandidates
+	openGraph dateCandidates
+	timeElem  dateCandidates
+}
+
+// meta records an article:published_time or article:modified_time property.
+func (t *dateTracker) meta(property, content string) {
+	switch strings.ToLower(strings.TrimSpace(property)) {
+	case "article:published_time":
+		t.openGraph.set(false, content)
+	case "article:modified_time":
+		t.openGraph.set(true, content)
+	}
+}
+
+// timeElement records a <time datetime>. It is a modified date only when
+// marked up as one with itemprop="dateModified".
+func (t *dateTracker) timeElement(datetime, itemprop string) {
+	t.timeElem.set(strings.EqualFold(strings.TrimSpace(itemprop), "dateModified"), datetime)
+}
+
+// jsonLDBlock records the dates of one <script type="application/ld+json">.
+// Blocks that are not valid JSON are ignored, as search engines do.
+func (t *dateTracker) jsonLDBlock(data string) {
+	var doc any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return
+	}
+	t.jsonLD.walk(doc)
+}
+
+// walk searches a decoded JSON-LD value, including @graph arrays and nested
+// objects, for datePublished and dateModified.
+func (c *dateCandidates) walk(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if s, ok := v["datePublished"].(string); ok {
+			c.set(false, s)
+		}
+		if s, ok := v["dateModified"].(string); ok {
+			c.set(true, s)
+		}
+		// Sorted keys keep the first date found stable across runs.
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			c.walk(v[key])
+		}
+	case []any:
+		for _, child := range v {
+			c.walk(child)
+		}
+	}
+}
+
+// result picks each date from the most preferred source that has it.
+func (t *dateTracker) result() model.PageDates {
+	var dates model.PageDates
+	sources := []struct {
+		name string
+		c    *dateCandidates
+	}{
+		{dateSourceJSONLD, &t.jsonLD},
+		{dateSourceOpenGraph, &t.openGraph},
+		{dateSourceTime, &t.timeElem},
+	}
+	for _, src := range sources {
+		if dates.PublishedAt == "" && src.c.published != "" {
+			dates.PublishedAt, dates.PublishedSource = src.c.published, src.name
+		}
+		if dates.ModifiedAt == "" && src.c.modified != "" {
+			dates.ModifiedAt, dates.ModifiedSource = src.c.modified, src.name
+		}
+	}
+	return dates
+}
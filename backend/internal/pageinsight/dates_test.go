@@ -0,0 +1,31 @@
+package pageinsight
+
+import "testing"
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{raw: "2024-03-05T10:20:30+02:00", want: "2024-03-05T10:20:30+02:00", wantOK: true},
+		{raw: "2024-03-05T10:20:30.123Z", want: "2024-03-05T10:20:30Z", wantOK: true},
+		{raw: "2024-03-05T10:20:30+0200", want: "2024-03-05T10:20:30+02:00", wantOK: true},
+		{raw: " 2024-03-05T10:20:30 ", want: "2024-03-05T10:20:30Z", wantOK: true},
+		{raw: "2024-03-05T10:20", want: "2024-03-05T10:20:00Z", wantOK: true},
+		{raw: "2024-03-05 10:20:30", want: "2024-03-05T10:20:30Z", wantOK: true},
+		{raw: "2024-03-05", want: "2024-03-05T00:00:00Z", wantOK: true},
+		{raw: "", wantOK: false},
+		{raw: "yesterday", wantOK: false},
+		{raw: "2024-13-45", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := normalizeDate(tt.raw)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("normalizeDate(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hostResolver looks up the addresses of a hostname; *net.Resolver is one.
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// resolvedHost is the outcome of looking up one hostname.
+type resolvedHost struct {
+	addrs []string
+	err   error
+}
+
+// resolvedHosts maps the hostnames of one link check run to their addresses.
+// It is filled before the workers start and only read afterwards.
+type resolvedHosts map[string]resolvedHost
+
+// resolveHosts looks up the hostnames of links concurrently, so a page with
+// dozens of links per host does one lookup per host instead of one per link.
+// IP literals need no lookup and are left out. Each lookup gets timeout.
+func resolveHosts(ctx context.Context, r hostResolver, links []string, concurrency int, timeout time.Duration) resolvedHosts {
+	var names []string
+	seen := make(map[string]bool)
+	for _, link := range links {
+		name := linkHostname(link)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, err := netip.ParseAddr(name); err != nil {
+			names = append(names, name)
+		}
+	}
+
+	hosts := make(resolvedHosts, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(concurrency, 1))
+	for _, name := range names {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			addrs, err := r.LookupHost(lookupCtx, name)
+			mu.Lock()
+			hosts[name] = resolvedHost{addrs: addrs, err: err}
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	return hosts
+}
+
+// notFound returns the lookup error for a link whose hostname does not
+// exist, nil otherwise. Other lookup errors, like timeouts, may be transient,
+// so those links are still requested and resolve again at dial time.
+func (h resolvedHosts) notFound(link string) error {
+	host := h[linkHostname(link)]
+	var dnsErr *net.DNSError
+	if errors.As(host.err, &dnsErr) && dnsErr.IsNotFound {
+		return host.err
+	}
+	return nil
+}
+
+// linkHostname is the lowercased, punycoded hostname of a link, without its
+// port.
+func linkHostname(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return asciiHostname(u.Hostname())
+}
+
+type resolvedHostsKey struct{}
+
+// withResolvedHosts lets dialResolved find the addresses resolved for a run.
+func withResolvedHosts(ctx context.Context, hosts resolvedHosts) context.Context {
+	return context.WithValue(ctx, resolvedHostsKey{}, hosts)
+}
+
+// dialResolved dials the addresses resolved up front for the host, in order,
+// instead of looking it up again. The dialer still vets every address as it
+// connects, so the private-IP and DNS-rebinding protection is unchanged.
+// Hosts that were not resolved up front are dialed as usual.
+func dialResolved(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	hosts, _ := ctx.Value(resolvedHostsKey{}).(resolvedHosts)
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return dialer.DialContext(ctx, network, address)
+	}
+	resolved, ok := hosts[host]
+	if !ok || resolved.err != nil || len(resolved.addrs) == 0 {
+		return dialer.DialContext(ctx, network, address)
+	}
+	var lastErr error
+	for _, addr := range resolved.addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
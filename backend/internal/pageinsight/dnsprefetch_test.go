@@ -0,0 +1,141 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers lookups from a map, with a not-found error for names
+// missing from it, and counts the lookups per name.
+type fakeResolver struct {
+	addrs map[string][]string
+
+	mu      sync.Mutex
+	lookups map[string]int
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[host]++
+	if addrs, ok := r.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestResolveHosts_OncePerHost(t *testing.T) {
+	r := &fakeResolver{addrs: map[string][]string{"example.com": {"93.184.215.14"}}}
+	links := []string{
+		"https://example.com/a",
+		"https://EXAMPLE.com/b",
+		"https://example.com:8443/c",
+		"https://gone.example/",
+		"https://93.184.215.14/",
+		"https://[2001:db8::1]/",
+	}
+
+	hosts := resolveHosts(context.Background(), r, links, 4, time.Second)
+
+	want := map[string]int{"example.com": 1, "gone.example": 1}
+	if len(r.lookups) != len(want) {
+		t.Errorf("lookups = %v, want %v", r.lookups, want)
+	}
+	for name, n := range want {
+		if r.lookups[name] != n {
+			t.Errorf("lookups[%s] = %d, want %d", name, r.lookups[name], n)
+		}
+	}
+	if err := hosts.notFound("https://gone.example/page"); err == nil {
+		t.Error("notFound(gone.example) = nil, want the lookup error")
+	}
+	if err := hosts.notFound("https://example.com/page"); err != nil {
+		t.Errorf("notFound(example.com) = %v, want nil", err)
+	}
+}
+
+func TestResolveHosts_TransientErrorIsNotFailFast(t *testing.T) {
+	hosts := resolvedHosts{"slow.example": {err: &net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true}}}
+	if err := hosts.notFound("https://slow.example/"); err != nil {
+		t.Errorf("notFound = %v, want nil for a timed out lookup", err)
+	}
+}
+
+func TestCheckLinks_PrefetchesDNS(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	server, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultLinkCheckerOptions()
+	opts.CacheTTL = 0
+	dialer := &net.Dialer{}
+	lc := newLinkChecker(opts, &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialResolved(ctx, dialer, network, address)
+		},
+	})
+	r := &fakeResolver{addrs: map[string][]string{"svc.test": {server.Hostname()}}}
+	lc.resolver = r
+
+	base := "http://svc.test:" + server.Port()
+	results := lc.CheckLinksDetailed(context.Background(), []string{base + "/a", base + "/b", "http://gone.test/x"})
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for _, res := range results[:2] {
+		if res.Status != http.StatusOK {
+			t.Errorf("%s: status = %d (%s), want 200 through the resolved address", res.URL, res.Status, res.Failure)
+		}
+	}
+	if gone := results[2]; gone.Failure != failureDNS || gone.Elapsed != 0 {
+		t.Errorf("gone.test = %+v, want a dns failure without a request", gone)
+	}
+	if r.lookups["svc.test"] != 1 || r.lookups["gone.test"] != 1 {
+		t.Errorf("lookups = %v, want one per host", r.lookups)
+	}
+	if len(paths) != 2 {
+		t.Errorf("server saw %v, want only the svc.test links", paths)
+	}
+}
+
+func TestDialResolved_StillBlocksPrivateAddresses(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// A hostname that resolved to loopback up front is still vetted by the
+	// safe dialer when connecting.
+	ctx := withResolvedHosts(context.Background(), resolvedHosts{"rebind.test": {addrs: []string{"127.0.0.1"}}})
+	conn, err := dialResolved(ctx, safeDialer(), "tcp", net.JoinHostPort("rebind.test", port))
+	if err == nil {
+		_ = conn.Close()
+		t.Fatal("dial succeeded, want a blocked address error")
+	}
+	if !errors.Is(err, errBlockedAddress) {
+		t.Errorf("err = %v, want errBlockedAddress", err)
+	}
+}
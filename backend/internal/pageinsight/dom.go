@@ -0,0 +1,95 @@
+package pageinsight
+
+import "fmt"
+
+const (
+	// largeDOMNodes is the element count above which Lighthouse flags a page
+	// for an excessive DOM size.
+	largeDOMNodes = 1500
+	// maxMarkupSamples caps how many markup problems are described.
+	maxMarkupSamples = 10
+)
+
+// voidElements never have content or an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// autoClosing elements are implicitly closed by a new sibling of the same
+// name, as in <li>one<li>two.
+var autoClosing = map[string]bool{
+	"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true, "th": true, "option": true,
+}
+
+// optionalEndTag elements may legitimately be left unclosed.
+var optionalEndTag = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "tr": true, "td": true, "th": true, "thead": true,
+	"tbody": true, "tfoot": true, "colgroup": true, "caption": true, "rt": true, "rp": true,
+}
+
+// domTracker counts elements and measures nesting depth from the token
+// stream. It keeps a stack of open element names so a stray or mismatched end
+// tag cannot throw the depth off: an end tag closes the nearest open element
+// of the same name, and is ignored if there is none.
+//
+// The same stack gives a rough markup quality signal, the problems the
+// tokenizer silently recovers from: end tags that implicitly close other
+// elements, stray end tags, and elements still open at the end of the
+// document. Elements whose end tag is optional are never reported.
+type domTracker struct {
+	nodes    int
+	maxDepth int
+	open     []string
+
+	problems int
+	samples  []string
+}
+
+func (d *domTracker) start(tag []byte, selfClosing bool) {
+	d.nodes++
+	name := string(tag)
+	if voidElements[name] || selfClosing {
+		d.maxDepth = max(d.maxDepth, len(d.open)+1)
+		return
+	}
+	if autoClosing[name] && len(d.open) > 0 && d.open[len(d.open)-1] == name {
+		d.open = d.open[:len(d.open)-1]
+	}
+	d.open = append(d.open, name)
+	d.maxDepth = max(d.maxDepth, len(d.open))
+}
+
+func (d *domTracker) end(tag []byte) {
+	name := string(tag)
+	for i := len(d.open) - 1; i >= 0; i-- {
+		if d.open[i] != name {
+			continue
+		}
+		for _, inner := range d.open[i+1:] {
+			if !optionalEndTag[inner] {
+				d.problem("</%s> closed an unclosed <%s>", name, inner)
+			}
+		}
+		d.open = d.open[:i]
+		return
+	}
+	d.problem("stray </%s> with no open element", name)
+}
+
+// finish reports the elements left open at the end of the document.
+func (d *domTracker) finish() {
+	for _, name := range d.open {
+		if !optionalEndTag[name] {
+			d.problem("<%s> never closed", name)
+		}
+	}
+}
+
+func (d *domTracker) problem(format string, args ...any) {
+	d.problems++
+	if len(d.samples) < maxMarkupSamples {
+		d.samples = append(d.samples, fmt.Sprintf(format, args...))
+	}
+}
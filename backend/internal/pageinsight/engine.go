@@ -2,21 +2,40 @@ package pageinsight
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
 )
 
 // linkChecker defines how the engine validates link accessibility.
 type linkChecker interface {
-	CheckLinks(ctx context.Context, links []string) int
+	CheckLinks(ctx context.Context, links []string) LinkReport
 }
 
 // Engine orchestrates page fetching, HTML parsing, and link checking.
 type Engine struct {
 	fetcher     Fetcher
 	linkChecker linkChecker
+	checkMedia  bool
+	// collapseSlashes makes link targets differing only by repeated
+	// slashes in their path one target.
+	collapseSlashes bool
+	blockedHosts    *hostBlocklist
+	self            *selfGuard
+	robots          *robotsChecker
+	pageCache       *pageCache
 }
 
 // NewEngine returns an Engine backed by the given Fetcher and link checker.
@@ -27,9 +46,374 @@ func NewEngine(fetcher Fetcher, lc linkChecker) *Engine {
 	}
 }
 
+// EnableMediaChecks makes the engine check the video and audio sources of a
+// page along with its links. Large media files are safe to probe: the link
+// checker's GET fallback only requests the first byte.
+func (e *Engine) EnableMediaChecks() {
+	e.checkMedia = true
+}
+
+// CollapseDuplicateSlashes treats link targets whose paths differ only by
+// repeated slashes, like /docs//guide and /docs/guide, as one link when
+// deduplicating them for checking. It is off by default, as some sites tell
+// such paths apart.
+func (e *Engine) CollapseDuplicateSlashes() {
+	e.collapseSlashes = true
+}
+
+// BlockHosts refuses to analyze pages on the given hosts: exact hostnames, or
+// wildcards like *.corp.example.com for every subdomain of one. The link
+// checker has its own list for the links it skips.
+func (e *Engine) BlockHosts(hosts []string) {
+	e.blockedHosts = newHostBlocklist(hosts)
+}
+
+// PreventSelfAnalysis refuses to analyze pages on this server, so it can't be
+// made to call itself: pages on the given hostnames, exact or wildcards like
+// BlockHosts takes, and on any host resolving to an address of the machine.
+func (e *Engine) PreventSelfAnalysis(hostnames []string) {
+	e.self = newSelfGuard(hostnames)
+}
+
+// CheckRobotsTxt makes the engine read the robots.txt of each analyzed site,
+// matching its groups against the product token of ua. With RobotsEnforce a
+// page the site disallows is not fetched; with RobotsWarn it is analyzed with
+// a warning. Any other policy turns the check off.
+func (e *Engine) CheckRobotsTxt(policy, ua string) {
+	if policy != RobotsWarn && policy != RobotsEnforce {
+		e.robots = nil
+		return
+	}
+	e.robots = &robotsChecker{policy: policy, token: robotsToken(ua), cache: newRobotsCache()}
+}
+
+// EnablePageCache keeps up to size recent analyses for ttl. A repeat
+// analysis of a cached page with the same options revalidates it with
+// If-None-Match and If-Modified-Since, and returns the cached result, marked
+// as such, when the site answers 304 Not Modified. A ttl or size of zero
+// turns the cache off.
+func (e *Engine) EnablePageCache(ttl time.Duration, size int) {
+	e.pageCache = newPageCache(ttl, size)
+}
+
 // Analyze fetches a URL, parses the HTML, and checks links.
-func (e *Engine) Analyze(ctx context.Context, targetURL string) (*model.PageAnalysis, error) {
-	parsed, err := url.Parse(targetURL)
+func (e *Engine) Analyze(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, error) {
+	result, _, err := e.analyze(ctx, targetURL, opts)
+	return result, err
+}
+
+// analyze is Analyze, also returning the links of the analyzed page so a
+// Crawler can follow them. A URL pasted without its scheme is analyzed over
+// https, or over http when the https connection can't be made; the result's
+// URL says which.
+func (e *Engine) analyze(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, []Link, error) {
+	guessed, ok := guessScheme(targetURL)
+	if !ok {
+		return e.analyzeURL(ctx, targetURL, opts)
+	}
+	result, links, err := e.analyzeURL(ctx, guessed, opts)
+	if err != nil && ctx.Err() == nil && httpsUnavailable(err) {
+		guessed = "http://" + strings.TrimPrefix(guessed, "https://")
+		result, links, err = e.analyzeURL(ctx, guessed, opts)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	result.Warnings = append(result.Warnings, fmt.Sprintf("the URL had no scheme; %s was analyzed", guessed))
+	return result, links, nil
+}
+
+// httpsUnavailable reports whether an analysis failed because the site
+// can't be reached over https, at the TLS handshake or the connection.
+func httpsUnavailable(err error) bool {
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.Unreachable || appErr.Cause == nil {
+		return false
+	}
+	switch failureCategory(appErr.Cause) {
+	case failureTLS, failureConnectionRefused:
+		return true
+	default:
+		return false
+	}
+}
+
+// analyzeURL analyzes an absolute http(s) URL.
+func (e *Engine) analyzeURL(ctx context.Context, targetURL string, opts model.AnalyzeOptions) (*model.PageAnalysis, []Link, error) {
+	phases := newPhaseTimer()
+	parsed, err := parseTargetURL(targetURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A blocked host or this server is refused before anything is fetched,
+	// robots.txt included, and so is every redirect from here on.
+	if err := e.refuseHost(ctx, parsed.Hostname()); err != nil {
+		return nil, nil, err
+	}
+	ctx = withRedirectGuard(ctx, e.refuseHost)
+	exclude, err := compileLinkFilter(opts.ExcludeLinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	robots, err := e.checkRobots(ctx, parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Caller-supplied headers apply from the page fetch on; robots.txt was
+	// fetched without them, as any crawler would.
+	ctx = withPageHeaders(ctx, parsed, opts.Headers)
+
+	cacheKey, cacheable := pageCacheKey(targetURL, opts)
+	var cached *pageCacheEntry
+	if cacheable {
+		if entry, ok := e.pageCache.get(cacheKey); ok {
+			cached = entry
+			ctx = withConditional(ctx, entry)
+		}
+	}
+
+	page, err := e.fetchPage(ctx, targetURL, parsed, opts, phases)
+	if err != nil {
+		return nil, nil, err
+	}
+	if page.notModified {
+		result := cached.cachedAnalysis(targetURL)
+		result.Timings = phases.timings()
+		return result, slices.Clone(cached.links), nil
+	}
+
+	// A zero-delay refresh is effectively a redirect. Follow it once when asked
+	// to, so the analysis describes the real page instead of the stub.
+	var followed *model.MetaRefresh
+	if refresh := page.parse.MetaRefresh; opts.FollowMetaRefresh && refresh != nil &&
+		refresh.DelaySeconds == 0 && refresh.URL != "" && refresh.URL != targetURL {
+		if next, err := parseTargetURL(refresh.URL); err == nil {
+			if err := e.refuseHost(ctx, next.Hostname()); err != nil {
+				return nil, nil, err
+			}
+			target, err := e.fetchPage(ctx, refresh.URL, next, opts, phases)
+			if err != nil {
+				return nil, nil, err
+			}
+			followed = &model.MetaRefresh{URL: refresh.URL, Followed: true}
+			targetURL, page = refresh.URL, target
+		}
+	}
+
+	result, err := e.buildAnalysis(ctx, page.parse, opts, exclude, phases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result.URL = targetURL
+	result.FinalURL = page.finalURL
+	result.Redirects = page.redirects
+	result.Transfer = page.transfer
+	result.Fetch = page.fetch
+	result.ContentHash = page.contentHash
+	result.SecurityHeaders = page.security
+	result.TLS = page.tls
+	result.Warnings = append(result.Warnings, page.transferWarnings...)
+	result.Robots = robots
+	if reason := softNotFound(page.parse); reason != "" {
+		result.LikelySoft404 = true
+		result.Warnings = append(result.Warnings, "the page was served with a success status but looks like a not-found page: "+reason)
+	}
+	switch {
+	case robots != nil && robots.Disallowed && !robots.Found:
+		result.Warnings = append(result.Warnings, "robots.txt could not be loaded, which disallows fetching this page; it was analyzed anyway")
+	case robots != nil && robots.Disallowed:
+		result.Warnings = append(result.Warnings, "robots.txt disallows fetching this page; it was analyzed anyway")
+	}
+	if followed != nil {
+		if result.MetaRefresh != nil {
+			result.Warnings = append(result.Warnings, "the refreshed page has its own meta refresh, which was not followed")
+		}
+		result.MetaRefresh = followed
+	}
+	result.Timings = phases.timings()
+	if cacheable {
+		e.pageCache.put(cacheKey, cloneAnalysis(result), slices.Clone(page.parse.Links), page.header)
+	}
+	return result, page.parse.Links, nil
+}
+
+// checkRobots reads the robots.txt rules for target when the engine checks
+// them, refusing a disallowed page under RobotsEnforce. It returns nil when
+// robots.txt is not checked.
+func (e *Engine) checkRobots(ctx context.Context, target *url.URL) (*model.RobotsTxt, error) {
+	if e.robots == nil {
+		return nil, nil
+	}
+	rules := e.robots.robotsFor(ctx, e.fetcher, target)
+	allowed := rules.allows(requestPath(target))
+	if !allowed && e.robots.policy == RobotsEnforce {
+		message := fmt.Sprintf("The site's robots.txt does not allow %s to fetch this page.", e.robots.token)
+		if rules.unreachable {
+			message = fmt.Sprintf("The site's robots.txt could not be loaded, so %s does not fetch this page; try again later.", e.robots.token)
+		}
+		return nil, &errs.AppError{Kind: errs.Forbidden, Message: message}
+	}
+	return rules.report(allowed), nil
+}
+
+// fetchedPage is a fetched and parsed page, before its links are checked.
+// finalURL is where redirects led, which links were resolved against.
+// notModified is set instead when a revalidated page had not changed.
+type fetchedPage struct {
+	notModified      bool
+	header           http.Header
+	parse            *ParseResult
+	transfer         model.TransferStats
+	transferWarnings []string
+	fetch            model.FetchStats
+	contentHash      *model.ContentHash
+	security         *model.SecurityHeaders
+	tls              *model.TLSInfo
+	finalURL         string
+	redirects        int
+}
+
+// refuseHost returns the error a page on hostname is refused with because
+// the host is blocked or is this server, or nil.
+func (e *Engine) refuseHost(ctx context.Context, hostname string) error {
+	if e.blockedHosts.blocks(hostname) {
+		return blockedHostError(hostname)
+	}
+	if e.self.targets(ctx, hostname) {
+		return &errs.AppError{
+			Kind:    errs.InvalidInput,
+			Message: "The URL points at this server, which does not analyze itself.",
+			Cause:   errSelfTarget,
+		}
+	}
+	return nil
+}
+
+// blockedHostError refuses a page on a host the operator blocked.
+func blockedHostError(hostname string) *errs.AppError {
+	return &errs.AppError{
+		Kind:    errs.InvalidInput,
+		Message: fmt.Sprintf("Analyzing %s is not allowed on this server.", hostname),
+		Cause:   errBlockedHost,
+	}
+}
+
+// fetchPage fetches and parses targetURL. Links are classified against the
+// URL the page was served from, so a redirect to another host does not turn
+// the page's own links external. A response that isn't labeled as HTML is
+// refused unless opts.Force is set, and so is a redirect the redirect guard
+// of ctx refuses. The caller checks targetURL's own host with refuseHost.
+func (e *Engine) fetchPage(ctx context.Context, targetURL string, parsed *url.URL, opts model.AnalyzeOptions, phases *phaseTimer) (*fetchedPage, error) {
+	phases.begin(phaseFetch)
+	progress.Report(ctx, progress.Event{Stage: progress.Fetching})
+	resp, err := e.fetcher.Fetch(ctx, targetURL)
+	if err != nil {
+		if timedOut(ctx) {
+			return nil, phases.timeout(err, 0, 0)
+		}
+		var refused *errs.AppError
+		if errors.As(err, &refused) {
+			// A redirect the guard refused.
+			return nil, refused
+		}
+		return nil, &errs.AppError{
+			Kind:    errs.Unreachable,
+			Message: unreachableMessage(err),
+			Cause:   err,
+		}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		appErr := &errs.AppError{
+			Kind:           errs.Unreachable,
+			UpstreamStatus: resp.StatusCode,
+			Message:        "The provided URL returned an error status.",
+		}
+		if title := errorPageTitle(resp.Body); title != "" {
+			appErr.Detail = &model.UpstreamErrorDetail{Title: title}
+		}
+		return nil, appErr
+	}
+	if resp.StatusCode == http.StatusNotModified && ctx.Value(conditionalKey{}) != nil {
+		return &fetchedPage{notModified: true}, nil
+	}
+	if mediaType := responseMediaType(resp.ContentType); !opts.Force && !isHTMLMediaType(mediaType) {
+		return nil, &errs.AppError{
+			Kind:    errs.UnsupportedContent,
+			Message: fmt.Sprintf("The provided URL returned %s content, not an HTML page. Send \"force\": true to analyze it anyway.", mediaType),
+		}
+	}
+
+	base, finalURL := parsed, targetURL
+	if resp.FinalURL != "" && resp.FinalURL != targetURL {
+		if final, err := url.Parse(resp.FinalURL); err == nil {
+			base, finalURL = final, resp.FinalURL
+		}
+	}
+
+	// The body is hashed as it streams into the parser. Every analysis
+	// reports all parse features, whatever its options, so nothing is
+	// skipped.
+	hash := sha256.New()
+	body := &countingReader{r: io.TeeReader(resp.Body, hash)}
+	parseResult, err := parseBody(ctx, body, base, DefaultParseOptions(), phases)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, transferWarnings := evaluateTransfer(resp, body.Count())
+	fetch := fetchStats(resp, body.Count())
+	if fetch.Truncated {
+		transferWarnings = append(transferWarnings, fmt.Sprintf("the page is larger than the %s response size limit; only its start was analyzed", byteSize(fetch.BodyBytes)))
+	}
+	return &fetchedPage{
+		parse:            parseResult,
+		transfer:         transfer,
+		transferWarnings: transferWarnings,
+		fetch:            fetch,
+		contentHash:      &model.ContentHash{SHA256: hex.EncodeToString(hash.Sum(nil)), Truncated: fetch.Truncated},
+		security:         securityHeaders(resp.Header),
+		tls:              tlsDetails(resp.TLS, time.Now()),
+		finalURL:         finalURL,
+		redirects:        resp.Redirects,
+		header:           resp.Header,
+	}, nil
+}
+
+// AnalyzeHTML parses an HTML document supplied by the caller and checks its
+// links, without fetching anything for the page itself. Relative links are
+// resolved against baseURL; when baseURL is empty they cannot be resolved and
+// are skipped.
+func (e *Engine) AnalyzeHTML(ctx context.Context, body io.Reader, baseURL string) (*model.PageAnalysis, error) {
+	base := &url.URL{}
+	if baseURL != "" {
+		parsed, err := parseTargetURL(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		base = parsed
+	}
+
+	phases := newPhaseTimer()
+	parseResult, err := parseBody(ctx, body, base, DefaultParseOptions(), phases)
+	if err != nil {
+		return nil, err
+	}
+	result, err := e.buildAnalysis(ctx, parseResult, model.AnalyzeOptions{}, nil, phases)
+	if err != nil {
+		return nil, err
+	}
+	result.URL = baseURL
+	result.Timings = phases.timings()
+	return result, nil
+}
+
+// parseTargetURL validates that raw is an absolute http(s) URL.
+func parseTargetURL(raw string) (*url.URL, error) {
+	parsed, err := url.Parse(raw)
 	if err != nil {
 		return nil, &errs.AppError{
 			Kind:    errs.InvalidInput,
@@ -49,62 +433,387 @@ func (e *Engine) Analyze(ctx context.Context, targetURL string) (*model.PageAnal
 			Message: "Only http and https URLs are supported.",
 		}
 	}
+	return parsed, nil
+}
 
-	body, statusCode, err := e.fetcher.Fetch(ctx, targetURL)
-	if err != nil {
-		return nil, &errs.AppError{
-			Kind:    errs.Unreachable,
-			Message: "The provided URL could not be reached. Check the address.",
-			Cause:   err,
-		}
-	}
-	defer func() { _ = body.Close() }()
-
-	if statusCode >= 400 {
-		return nil, &errs.AppError{
-			Kind:           errs.Unreachable,
-			UpstreamStatus: statusCode,
-			Message:        "The provided URL returned an error status.",
-		}
+// linkCheckContext gives link checking its own deadline, a little ahead of
+// the analysis deadline, so links still being checked when time runs out
+// leave room to return what the page had instead of failing the analysis.
+// It holds back a tenth of the time left, at most linkCheckReserve.
+func linkCheckContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
 	}
+	reserve := min(time.Until(deadline)/10, linkCheckReserve)
+	return context.WithDeadline(ctx, deadline.Add(-reserve))
+}
 
-	parseResult, err := Parse(body, parsed)
+// parseBody parses an HTML document in the parse phase.
+func parseBody(ctx context.Context, body io.Reader, base *url.URL, opts ParseOptions, phases *phaseTimer) (*ParseResult, error) {
+	phases.begin(phaseParse)
+	progress.Report(ctx, progress.Event{Stage: progress.Parsing})
+	parseResult, err := ParseWithOptions(body, base, opts)
 	if err != nil {
+		if timedOut(ctx) {
+			return nil, phases.timeout(err, 0, 0)
+		}
 		return nil, &errs.AppError{
 			Kind:    errs.ParsingFailed,
 			Message: "Failed to parse the HTML content.",
 			Cause:   err,
 		}
 	}
+	return parseResult, nil
+}
 
-	// Deduplicate links for accessibility checking.
-	seen := make(map[string]struct{}, len(parseResult.Links))
-	uniqueURLs := make([]string, 0, len(parseResult.Links))
-	var internalCount, externalCount int
+// buildAnalysis checks the links of a parsed page, except those exclude
+// matches, and assembles the result.
+func (e *Engine) buildAnalysis(ctx context.Context, parseResult *ParseResult, opts model.AnalyzeOptions, exclude *linkFilter, phases *phaseTimer) (*model.PageAnalysis, error) {
+	var internalCount, externalCount, emptyTextCount int
 	for _, link := range parseResult.Links {
 		if link.IsInternal {
 			internalCount++
 		} else {
 			externalCount++
 		}
-		if _, dup := seen[link.URL]; !dup {
-			seen[link.URL] = struct{}{}
-			uniqueURLs = append(uniqueURLs, link.URL)
+		if link.Text == "" {
+			emptyTextCount++
+		}
+	}
+	checkInternal, checkExternal := linkScope(opts.CheckLinks)
+	uniqueURLs, excluded := exclude.split(uniqueTargets(linksInScope(parseResult.Links, checkInternal, checkExternal), e.linkKey))
+
+	media := model.MediaStats{
+		Videos:   parseResult.Media.Video,
+		Audios:   parseResult.Media.Audio,
+		Pictures: parseResult.Media.Picture,
+	}
+	for _, link := range parseResult.MediaLinks {
+		if link.IsInternal {
+			media.Internal++
+		} else {
+			media.External++
 		}
 	}
+	var mediaURLs []string
+	if e.checkMedia {
+		mediaURLs = uniqueTargets(parseResult.MediaLinks, e.linkKey)
+	}
+
+	var preloadURLs, imageURLs []string
+	if opts.CheckPreloads {
+		preloadURLs = parseResult.PreloadURLs
+	}
+	if opts.CheckImages {
+		imageURLs = uniqueTargets(parseResult.ImageLinks, e.linkKey)
+	}
 
-	inaccessible := e.linkChecker.CheckLinks(ctx, uniqueURLs)
+	// Links, media, the manifest, pagination links, preloads and images are
+	// checked as separate batches so each gets its own inaccessible count.
+	pagination := parseResult.Pagination
+	phases.begin(phaseLinkCheck)
+	batches := [][]string{
+		uniqueURLs,
+		mediaURLs,
+		singleURL(parseResult.ManifestURL),
+		singleURL(pagination.Next),
+		singleURL(pagination.Prev),
+		preloadURLs,
+		imageURLs,
+	}
+	linkCtx, cancel := linkCheckContext(ctx)
+	defer cancel()
+	var planned int
+	for _, batch := range batches {
+		planned += len(batch)
+	}
+	linkCtx = withLinkProgress(linkCtx, planned)
+	reports := make([]LinkReport, len(batches))
+	var checked, total int
+	var cutShort bool
+	for i, batch := range batches {
+		total += len(batch)
+		if len(batch) == 0 {
+			continue
+		}
+		if timedOut(linkCtx) {
+			cutShort = true
+			continue
+		}
+		reports[i] = e.linkChecker.CheckLinks(linkCtx, batch)
+		checked += reports[i].Checked
+		cutShort = cutShort || (reports[i].Partial && timedOut(linkCtx))
+	}
+	if timedOut(ctx) {
+		return nil, phases.timeout(ctx.Err(), checked, total)
+	}
+	report, mediaReport, manifestReport := reports[0], reports[1], reports[2]
+	internalInaccessible, externalInaccessible := inaccessibleByScope(parseResult.Links, report.Results, e.linkKey)
+	failures := failureCounts(report.Results)
+	latency := measureLatency(report.Results)
+	inaccessible := report.Inaccessible
+	if !checkInternal {
+		internalInaccessible = notChecked
+	}
+	if !checkExternal {
+		externalInaccessible = notChecked
+	}
+	if !checkInternal && !checkExternal {
+		inaccessible = notChecked
+	}
+	estimated := inaccessible
+	if report.Sampled {
+		estimated = extrapolate(inaccessible, report.Checked, len(uniqueURLs))
+	}
+	var linksDetail []model.LinkDetail
+	if opts.IncludeLinks {
+		linksDetail = linkDetails(parseResult.Links, report.Results, e.linkKey)
+	}
+	pagination.NextInaccessible = reports[3].Inaccessible > 0
+	pagination.PrevInaccessible = reports[4].Inaccessible > 0
+	media.Checked = e.checkMedia
+	media.Inaccessible = mediaReport.Inaccessible
+	hints := parseResult.ResourceHints
+	hints.PreloadsChecked = opts.CheckPreloads
+	hints.PreloadsInaccessible = reports[5].Inaccessible
+	images := parseResult.Images
+	images.Checked = opts.CheckImages
+	images.Inaccessible = reports[6].Inaccessible
+	warnings := parseResult.Warnings
+	if cutShort {
+		warnings = append(slices.Clip(warnings), fmt.Sprintf("link checking ran out of time after %d of %d links; the link counts cover only those", checked, total))
+	}
 
 	return &model.PageAnalysis{
-		URL:         targetURL,
-		HTMLVersion: parseResult.HTMLVersion,
-		Title:       parseResult.Title,
-		Headings:    parseResult.Headings,
+		HTMLVersion:    parseResult.HTMLVersion,
+		Doctype:        parseResult.Doctype,
+		Title:          parseResult.Title,
+		TitleLength:    utf8.RuneCountInString(parseResult.Title),
+		MultipleTitles: parseResult.TitleCount > 1,
+		Headings:       parseResult.Headings,
+		FirstH1:        parseResult.FirstH1,
+		Dates:          parseResult.Dates,
+		TextRatio:      parseResult.TextRatio,
+		DOM: model.DOMStats{
+			Nodes:          parseResult.DOMNodes,
+			MaxDepth:       parseResult.DOMDepth,
+			MarkupWarnings: parseResult.MarkupWarnings,
+			MarkupSamples:  parseResult.MarkupSamples,
+		},
+		LikelyRequiresJavascript: parseResult.LikelyRequiresJavascript,
+		Accessibility: model.Accessibility{
+			UnlabeledInputs: parseResult.UnlabeledInputs,
+			EmptyHeadings:   parseResult.EmptyHeadings,
+			Landmarks:       parseResult.Landmarks,
+			NoLandmarks:     len(parseResult.Landmarks) == 0,
+			Roles:           parseResult.Roles,
+		},
 		Links: model.LinkStats{
-			Internal:     internalCount,
-			External:     externalCount,
-			Inaccessible: inaccessible,
+			Internal:              internalCount,
+			External:              externalCount,
+			Inaccessible:          inaccessible,
+			InternalInaccessible:  internalInaccessible,
+			ExternalInaccessible:  externalInaccessible,
+			TLSErrors:             failures[failureTLS],
+			DNSErrors:             failures[failureDNS],
+			Timeouts:              failures[failureTimeout],
+			ConnectionErrors:      failures[failureConnectionRefused] + failures[failureBlockedPrivateIP] + failures[failureUnreachable],
+			HTTPErrors:            failures[failureHTTP4xx] + failures[failureHTTP5xx],
+			RateLimited:           report.RateLimited,
+			Skipped:               report.Skipped,
+			Cached:                report.Cached,
+			Checked:               report.Checked,
+			Excluded:              excluded,
+			LatencyP50Ms:          latency.p50.Milliseconds(),
+			LatencyP95Ms:          latency.p95.Milliseconds(),
+			SlowestLinks:          slowLinks(latency.slowest),
+			Partial:               report.Partial || cutShort,
+			Sampled:               report.Sampled,
+			EstimatedInaccessible: estimated,
+			EmptyText:             emptyTextCount,
+			Fragment:              len(parseResult.Fragments),
+			BrokenFragments:       parseResult.BrokenFragments,
+			UnsafeTargetBlank:     parseResult.UnsafeTargetBlank,
+		},
+		LinksDetail:           linksDetail,
+		BrokenFragmentSamples: parseResult.BrokenFragmentSamples,
+		Media:                 media,
+		HasLoginForm:          parseResult.HasLoginForm,
+		LoginForm:             parseResult.LoginForm,
+		HasSearchForm:         parseResult.HasSearchForm,
+		SearchAction:          parseResult.SearchAction,
+		MetaRefresh:           parseResult.MetaRefresh,
+		PWA: model.PWASignals{
+			ManifestURL:           parseResult.ManifestURL,
+			ManifestInaccessible:  manifestReport.Inaccessible > 0,
+			MentionsServiceWorker: parseResult.MentionsServiceWorker,
+		},
+		Pagination:    pagination,
+		ResourceHints: hints,
+		Tables: model.TableStats{
+			Count:         parseResult.Tables,
+			LayoutSuspect: parseResult.LayoutTables,
+		},
+		Images: images,
+		SVG: model.SVGStats{
+			Count:       parseResult.SVGCount,
+			InlineBytes: parseResult.SVGBytes,
+			Oversized:   parseResult.OversizedSVG,
 		},
-		HasLoginForm: parseResult.HasLoginForm,
+		InlineEventHandlers: parseResult.InlineEventHandlers,
+		JavascriptLinks:     parseResult.JavascriptLinks,
+		InlineStyles:        parseResult.InlineStyles,
+		MixedContent:        parseResult.MixedContent,
+		ThirdPartyDomains:   parseResult.ThirdPartyDomains,
+		Trackers:            parseResult.Trackers,
+		Analytics:           parseResult.Analytics,
+		BotProtection:       parseResult.BotProtection,
+		Warnings:            warnings,
 	}, nil
 }
+
+// linkCheckReserve caps the time link checking leaves before the analysis
+// deadline.
+const linkCheckReserve = time.Second
+
+// notChecked is reported for an inaccessible count whose links were not
+// checked, so it can't be mistaken for zero broken links.
+const notChecked = -1
+
+// linkScope says whether internal and external links are checked for a
+// CheckLinks option.
+func linkScope(checkLinks string) (internal, external bool) {
+	switch checkLinks {
+	case model.CheckLinksInternal:
+		return true, false
+	case model.CheckLinksExternal:
+		return false, true
+	case model.CheckLinksNone:
+		return false, false
+	default:
+		return true, true
+	}
+}
+
+// linksInScope keeps the internal links, the external ones, or both.
+func linksInScope(links []Link, internal, external bool) []Link {
+	if internal && external {
+		return links
+	}
+	var kept []Link
+	for _, link := range links {
+		if link.IsInternal && internal || !link.IsInternal && external {
+			kept = append(kept, link)
+		}
+	}
+	return kept
+}
+
+// inaccessibleByScope splits the inaccessible link targets into internal and
+// external ones. A target appearing several times is counted once, like in
+// the checker's total.
+func inaccessibleByScope(links []Link, results []LinkResult, key func(string) string) (internal, external int) {
+	isInternal := make(map[string]bool, len(links))
+	for _, link := range links {
+		target := key(link.URL)
+		if _, seen := isInternal[target]; !seen {
+			isInternal[target] = link.IsInternal
+		}
+	}
+	for _, r := range results {
+		if !r.inaccessible() {
+			continue
+		}
+		if isInternal[key(r.URL)] {
+			internal++
+		} else {
+			external++
+		}
+	}
+	return internal, external
+}
+
+// failureCounts counts the inaccessible results by failure category.
+func failureCounts(results []LinkResult) map[string]int {
+	var counts map[string]int
+	for _, r := range results {
+		if r.inaccessible() {
+			counts = increment(counts, r.Failure)
+		}
+	}
+	return counts
+}
+
+// linkDetails merges the parsed links, up to defaultMaxLinks of them, with their
+// check results. Results are matched by key, as uniqueTargets checked only one
+// spelling of each target.
+func linkDetails(links []Link, results []LinkResult, key func(string) string) []model.LinkDetail {
+	byURL := make(map[string]LinkResult, len(results))
+	for _, r := range results {
+		byURL[key(r.URL)] = r
+	}
+	details := make([]model.LinkDetail, 0, min(len(links), defaultMaxLinks))
+	for _, link := range links[:min(len(links), defaultMaxLinks)] {
+		detail := model.LinkDetail{URL: link.URL, Internal: link.IsInternal, Text: link.Text}
+		if rel := strings.Fields(strings.ToLower(link.Rel)); len(rel) > 0 {
+			detail.Rel = rel
+		}
+		if r, ok := byURL[key(link.URL)]; ok {
+			detail.Checked, detail.Status, detail.Failure = true, r.Status, r.Failure
+			detail.Redirects, detail.FinalURL = r.Redirects, r.FinalURL
+			detail.ElapsedMs = r.Elapsed.Milliseconds()
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// singleURL returns a one-URL batch, or none if u is empty.
+func singleURL(u string) []string {
+	if u == "" {
+		return nil
+	}
+	return []string{u}
+}
+
+// uniqueTargets returns the distinct URLs of links for accessibility checking.
+// Links are deduplicated by key, so spellings of one URL, such as /page#a and
+// /page/ or an uppercase host, are checked once, as the page first spelled
+// it. The fragment, which is never sent, is dropped.
+func uniqueTargets(links []Link, key func(string) string) []string {
+	seen := make(map[string]struct{}, len(links))
+	targets := make([]string, 0, len(links))
+	for _, link := range links {
+		k := key(link.URL)
+		if _, dup := seen[k]; !dup {
+			seen[k] = struct{}{}
+			target, _, _ := strings.Cut(link.URL, "#")
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// linkKey is the key link targets are deduplicated by: the normalized URL,
+// with repeated slashes collapsed when the engine is set to.
+func (e *Engine) linkKey(link string) string {
+	key := normalizeURL(link)
+	if e.collapseSlashes {
+		key = collapseSlashes(key)
+	}
+	return key
+}
+
+// responseMediaType is the lowercased media type of a Content-Type header,
+// without its parameters.
+func responseMediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// isHTMLMediaType reports whether a page with mediaType is analyzed. A
+// response without a Content-Type is given the benefit of the doubt.
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
@@ -2,15 +2,32 @@ package pageinsight
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
 )
 
-var errConnectionRefused = errors.New("connection refused")
+var (
+	errConnectionRefused = errors.New("connection refused")
+	errFetchShouldNotRun = errors.New("fetch should not run")
+)
 
 // mockFetcher implements Fetcher for testing.
 type mockFetcher struct {
@@ -19,22 +36,84 @@ type mockFetcher struct {
 	err        error
 }
 
-func (m *mockFetcher) Fetch(_ context.Context, _ string) (io.ReadCloser, int, error) {
+func (m *mockFetcher) Fetch(_ context.Context, _ string) (*Response, error) {
 	if m.err != nil {
-		return nil, m.statusCode, m.err
+		return nil, m.err
+	}
+	return &Response{
+		Body:       io.NopCloser(strings.NewReader(m.body)),
+		StatusCode: m.statusCode,
+	}, nil
+}
+
+// pagesFetcher serves a fixed body per URL and 404s for anything else.
+type pagesFetcher map[string]string
+
+func (f pagesFetcher) Fetch(_ context.Context, url string) (*Response, error) {
+	body, ok := f[url]
+	status := 200
+	if !ok {
+		status = 404
 	}
-	return io.NopCloser(strings.NewReader(m.body)), m.statusCode, nil
+	return &Response{Body: io.NopCloser(strings.NewReader(body)), StatusCode: status}, nil
 }
 
-// mockLinkChecker implements linkChecker for testing.
+// mockLinkChecker implements linkChecker for testing. When block is set it
+// checks one link and then waits for the context to end.
 type mockLinkChecker struct {
 	inaccessible int
 	receivedURLs []string
+	block        bool
+}
+
+func (m *mockLinkChecker) CheckLinks(ctx context.Context, links []string) LinkReport {
+	m.receivedURLs = append(m.receivedURLs, links...)
+	if m.block {
+		<-ctx.Done()
+		return LinkReport{Checked: 1, Partial: true}
+	}
+	return LinkReport{Inaccessible: m.inaccessible, Checked: len(links)}
+}
+
+// urlChecker reports the URLs in the map as 404s and the rest as 200s.
+type urlChecker map[string]bool
+
+func (c urlChecker) CheckLinks(_ context.Context, links []string) LinkReport {
+	report := LinkReport{Checked: len(links)}
+	for _, link := range links {
+		status := http.StatusOK
+		if c[link] {
+			status = http.StatusNotFound
+			report.Inaccessible++
+		}
+		report.Results = append(report.Results, StatusResult(link, status))
+	}
+	return report
+}
+
+// blockingFetcher waits for the context to end before failing.
+type blockingFetcher struct{}
+
+func (blockingFetcher) Fetch(ctx context.Context, _ string) (*Response, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
 }
 
-func (m *mockLinkChecker) CheckLinks(_ context.Context, links []string) int {
-	m.receivedURLs = links
-	return m.inaccessible
+// stallingFetcher returns a body that stalls mid-document until the context ends.
+type stallingFetcher struct{}
+
+func (stallingFetcher) Fetch(ctx context.Context, _ string) (*Response, error) {
+	return &Response{
+		Body:       io.NopCloser(io.MultiReader(strings.NewReader("<html><body>"), &stallingReader{ctx: ctx})),
+		StatusCode: 200,
+	}, nil
+}
+
+type stallingReader struct{ ctx context.Context }
+
+func (r *stallingReader) Read(_ []byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
 }
 
 func TestEngine_Analyze_Success(t *testing.T) {
@@ -45,7 +124,7 @@ func TestEngine_Analyze_Success(t *testing.T) {
 
 	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, &mockLinkChecker{})
 
-	result, err := engine.Analyze(context.Background(), "https://example.com")
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -65,12 +144,111 @@ func TestEngine_Analyze_Success(t *testing.T) {
 	if result.URL != "https://example.com" {
 		t.Errorf("URL = %q, want %q", result.URL, "https://example.com")
 	}
+	if result.FirstH1 != "Hello" {
+		t.Errorf("FirstH1 = %q, want %q", result.FirstH1, "Hello")
+	}
+}
+
+// redirectFetcher serves body as if the request had been redirected to
+// final.
+type redirectFetcher struct {
+	body      string
+	final     string
+	redirects int
+}
+
+func (f *redirectFetcher) Fetch(_ context.Context, _ string) (*Response, error) {
+	return &Response{
+		Body:       io.NopCloser(strings.NewReader(f.body)),
+		StatusCode: http.StatusOK,
+		FinalURL:   f.final,
+		Redirects:  f.redirects,
+	}, nil
+}
+
+func TestEngine_Analyze_CrossHostRedirect(t *testing.T) {
+	html := `<html><body>
+		<a href="/about">About</a>
+		<a href="https://www.example.com/contact">Contact</a>
+		<a href="http://example.com/legacy">Legacy</a>
+	</body></html>`
+	fetcher := &redirectFetcher{body: html, final: "https://www.example.com/home", redirects: 1}
+	engine := NewEngine(fetcher, &mockLinkChecker{})
+
+	result, err := engine.Analyze(context.Background(), "http://example.com", model.AnalyzeOptions{IncludeLinks: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.URL != "http://example.com" || result.FinalURL != "https://www.example.com/home" || result.Redirects != 1 {
+		t.Errorf("URL = %q, final URL = %q, redirects = %d, want the requested URL, the final one and 1 redirect",
+			result.URL, result.FinalURL, result.Redirects)
+	}
+	// The page's links are resolved against, and compared with, the host the
+	// page was served from.
+	if result.Links.Internal != 2 || result.Links.External != 1 {
+		t.Errorf("internal = %d, external = %d, want 2 and 1", result.Links.Internal, result.Links.External)
+	}
+	if got := result.LinksDetail[0].URL; got != "https://www.example.com/about" {
+		t.Errorf("relative link resolved to %q, want it on the final host", got)
+	}
+}
+
+// typedFetcher serves body with the given Content-Type.
+type typedFetcher struct {
+	body        string
+	contentType string
+}
+
+func (f *typedFetcher) Fetch(_ context.Context, _ string) (*Response, error) {
+	return &Response{
+		Body:        io.NopCloser(strings.NewReader(f.body)),
+		StatusCode:  http.StatusOK,
+		ContentType: f.contentType,
+	}, nil
+}
+
+func TestEngine_Analyze_ContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		force       bool
+		wantErr     bool
+	}{
+		{contentType: "text/html; charset=utf-8"},
+		{contentType: "TEXT/HTML"},
+		{contentType: "application/xhtml+xml"},
+		{contentType: ""},
+		{contentType: "application/pdf", wantErr: true},
+		{contentType: "image/jpeg", wantErr: true},
+		{contentType: "text/plain; charset=utf-8", wantErr: true},
+		{contentType: "text/plain", force: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			fetcher := &typedFetcher{body: "<html><head><title>T</title></head></html>", contentType: tt.contentType}
+			engine := NewEngine(fetcher, &mockLinkChecker{})
+			_, err := engine.Analyze(context.Background(), "https://example.com/file", model.AnalyzeOptions{Force: tt.force})
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) || appErr.Kind != errs.UnsupportedContent {
+				t.Fatalf("err = %v, want an UnsupportedContent AppError", err)
+			}
+			if want := responseMediaType(tt.contentType); !strings.Contains(appErr.Message, want) {
+				t.Errorf("message = %q, want it to name %s", appErr.Message, want)
+			}
+		})
+	}
 }
 
 func TestEngine_Analyze_FetchError(t *testing.T) {
 	engine := NewEngine(&mockFetcher{err: errConnectionRefused, statusCode: 0}, &mockLinkChecker{})
 
-	_, err := engine.Analyze(context.Background(), "https://down.example.com")
+	_, err := engine.Analyze(context.Background(), "https://down.example.com", model.AnalyzeOptions{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -96,7 +274,7 @@ func TestEngine_Analyze_DeduplicatesLinks(t *testing.T) {
 	lc := &mockLinkChecker{}
 	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, lc)
 
-	result, err := engine.Analyze(context.Background(), "https://example.com")
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -118,7 +296,7 @@ func TestEngine_Analyze_DeduplicatesLinks(t *testing.T) {
 func TestEngine_Analyze_InvalidURL(t *testing.T) {
 	engine := NewEngine(&mockFetcher{}, &mockLinkChecker{})
 
-	_, err := engine.Analyze(context.Background(), "not-a-valid-url")
+	_, err := engine.Analyze(context.Background(), "not-a-valid-url", model.AnalyzeOptions{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -135,7 +313,7 @@ func TestEngine_Analyze_InvalidURL(t *testing.T) {
 func TestEngine_Analyze_NonHTTPScheme(t *testing.T) {
 	engine := NewEngine(&mockFetcher{}, &mockLinkChecker{})
 
-	_, err := engine.Analyze(context.Background(), "ftp://example.com/file")
+	_, err := engine.Analyze(context.Background(), "ftp://example.com/file", model.AnalyzeOptions{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -152,7 +330,7 @@ func TestEngine_Analyze_NonHTTPScheme(t *testing.T) {
 func TestEngine_Analyze_HTTPStatusError(t *testing.T) {
 	engine := NewEngine(&mockFetcher{body: "not found", statusCode: 404}, &mockLinkChecker{})
 
-	_, err := engine.Analyze(context.Background(), "https://example.com/missing")
+	_, err := engine.Analyze(context.Background(), "https://example.com/missing", model.AnalyzeOptions{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -167,6 +345,25 @@ func TestEngine_Analyze_HTTPStatusError(t *testing.T) {
 	if appErr.UpstreamStatus != 404 {
 		t.Errorf("UpstreamStatus = %d, want 404", appErr.UpstreamStatus)
 	}
+	if appErr.Detail != nil {
+		t.Errorf("Detail = %v, want none for an error page without a title", appErr.Detail)
+	}
+}
+
+func TestEngine_Analyze_ErrorPageTitle(t *testing.T) {
+	page := "<!DOCTYPE html><html><head><title>\n  Access denied \u202e| Example WAF\x07\n</title></head><body>Blocked</body></html>"
+	engine := NewEngine(&mockFetcher{body: page, statusCode: 403}, &mockLinkChecker{})
+
+	_, err := engine.Analyze(context.Background(), "https://example.com/", model.AnalyzeOptions{})
+
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.UpstreamStatus != 403 {
+		t.Fatalf("err = %v, want an AppError with upstream status 403", err)
+	}
+	detail, ok := appErr.Detail.(*model.UpstreamErrorDetail)
+	if !ok || detail.Title != "Access denied | Example WAF" {
+		t.Errorf("Detail = %+v, want the sanitized title of the error page", appErr.Detail)
+	}
 }
 
 func TestEngine_Analyze_LoginFormDetected(t *testing.T) {
@@ -176,7 +373,7 @@ func TestEngine_Analyze_LoginFormDetected(t *testing.T) {
 
 	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, &mockLinkChecker{})
 
-	result, err := engine.Analyze(context.Background(), "https://example.com/login")
+	result, err := engine.Analyze(context.Background(), "https://example.com/login", model.AnalyzeOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -196,7 +393,7 @@ func TestEngine_Analyze_InaccessibleCount(t *testing.T) {
 		&mockLinkChecker{inaccessible: 1},
 	)
 
-	result, err := engine.Analyze(context.Background(), "https://example.com")
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -204,3 +401,1096 @@ func TestEngine_Analyze_InaccessibleCount(t *testing.T) {
 		t.Errorf("Inaccessible = %d, want 1", result.Links.Inaccessible)
 	}
 }
+
+func TestEngine_Analyze_EmptyTextCount(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>T</title></head><body>
+	<a href="https://example.com/a">A</a>
+	<a href="https://example.com/b"></a>
+	<a href="https://example.com/c"><img src="c.png"></a>
+	</body></html>`
+
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, &mockLinkChecker{})
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Links.EmptyText != 2 {
+		t.Errorf("EmptyText = %d, want 2", result.Links.EmptyText)
+	}
+}
+
+func TestEngine_Analyze_StripsFragmentsBeforeDedup(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>T</title></head><body>
+	<a href="#intro">Intro</a>
+	<a href="/page#a">A</a>
+	<a href="/page#b">B</a>
+	<a href="/page">Page</a>
+	<a href="https://other.com/x#y">X</a>
+	</body></html>`
+
+	lc := &mockLinkChecker{}
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, lc)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"https://example.com/page", "https://other.com/x"}
+	if len(lc.receivedURLs) != len(want) {
+		t.Fatalf("checker received %v, want %v", lc.receivedURLs, want)
+	}
+	for i, u := range want {
+		if lc.receivedURLs[i] != u {
+			t.Errorf("receivedURLs[%d] = %q, want %q", i, lc.receivedURLs[i], u)
+		}
+	}
+	if result.Links.Fragment != 1 {
+		t.Errorf("Fragment = %d, want 1", result.Links.Fragment)
+	}
+	if result.Links.Internal != 3 {
+		t.Errorf("Internal = %d, want 3", result.Links.Internal)
+	}
+}
+
+func TestEngine_Analyze_MediaChecks(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>T</title></head><body>
+	<a href="/page">Page</a>
+	<video src="/intro.mp4"></video>
+	<video><source src="https://cdn.other.com/clip.webm"><source src="/intro.mp4"></video>
+	<audio src="/theme.mp3"></audio>
+	<picture><source srcset="/hero.avif"><img src="/hero.jpg" alt="Hero"></picture>
+	</body></html>`
+
+	tests := []struct {
+		name     string
+		enable   bool
+		wantURLs []string
+	}{
+		{name: "disabled", wantURLs: []string{"https://example.com/page"}},
+		{
+			name:   "enabled",
+			enable: true,
+			wantURLs: []string{
+				"https://example.com/page",
+				"https://example.com/intro.mp4",
+				"https://cdn.other.com/clip.webm",
+				"https://example.com/theme.mp3",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := &mockLinkChecker{}
+			engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, lc)
+			if tt.enable {
+				engine.EnableMediaChecks()
+			}
+
+			result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(lc.receivedURLs) != len(tt.wantURLs) {
+				t.Fatalf("checker received %v, want %v", lc.receivedURLs, tt.wantURLs)
+			}
+			for i, u := range tt.wantURLs {
+				if lc.receivedURLs[i] != u {
+					t.Errorf("receivedURLs[%d] = %q, want %q", i, lc.receivedURLs[i], u)
+				}
+			}
+
+			want := model.MediaStats{Videos: 2, Audios: 1, Pictures: 1, Internal: 3, External: 1, Checked: tt.enable}
+			if result.Media != want {
+				t.Errorf("Media = %+v, want %+v", result.Media, want)
+			}
+			if result.Links.Internal != 1 {
+				t.Errorf("Links.Internal = %d, want 1; media must not count as links", result.Links.Internal)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_ChecksManifest(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>T</title>
+	<link rel="manifest" href="/manifest.json"></head><body></body></html>`
+
+	lc := &mockLinkChecker{inaccessible: 1}
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, lc)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lc.receivedURLs) != 1 || lc.receivedURLs[0] != "https://example.com/manifest.json" {
+		t.Errorf("checker received %v, want only the manifest", lc.receivedURLs)
+	}
+	if !result.PWA.ManifestInaccessible {
+		t.Error("ManifestInaccessible = false, want true")
+	}
+	if result.Links.Inaccessible != 0 {
+		t.Errorf("Links.Inaccessible = %d, want 0; the manifest is not a link", result.Links.Inaccessible)
+	}
+}
+
+func TestEngine_Analyze_MetaRefresh(t *testing.T) {
+	fetcher := pagesFetcher{
+		"https://example.com/old": `<html><head><title>Moved</title>
+			<meta http-equiv="refresh" content="0; url=/new"></head></html>`,
+		"https://example.com/new": `<html><head><title>New page</title>
+			<meta http-equiv="refresh" content="0; url=/newer"></head><body><a href="/x">X</a></body></html>`,
+		"https://example.com/slow": `<html><head><title>Slow</title>
+			<meta http-equiv="refresh" content="30;url=https://example.com/new"></head></html>`,
+		"https://example.com/broken": `<html><head><title>Broken</title>
+			<meta http-equiv="refresh" content="0;url=/gone"></head></html>`,
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		follow      bool
+		wantURL     string
+		wantTitle   string
+		wantRefresh model.MetaRefresh
+		wantErr     errs.Kind
+	}{
+		{
+			name:        "reported without following",
+			url:         "https://example.com/old",
+			wantURL:     "https://example.com/old",
+			wantTitle:   "Moved",
+			wantRefresh: model.MetaRefresh{URL: "https://example.com/new"},
+		},
+		{
+			name:        "zero delay followed once",
+			url:         "https://example.com/old",
+			follow:      true,
+			wantURL:     "https://example.com/new",
+			wantTitle:   "New page",
+			wantRefresh: model.MetaRefresh{URL: "https://example.com/new", Followed: true},
+		},
+		{
+			name:        "delayed refresh not followed",
+			url:         "https://example.com/slow",
+			follow:      true,
+			wantURL:     "https://example.com/slow",
+			wantTitle:   "Slow",
+			wantRefresh: model.MetaRefresh{DelaySeconds: 30, URL: "https://example.com/new"},
+		},
+		{
+			name:    "unreachable target",
+			url:     "https://example.com/broken",
+			follow:  true,
+			wantErr: errs.Unreachable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := &mockLinkChecker{}
+			engine := NewEngine(fetcher, lc)
+
+			result, err := engine.Analyze(context.Background(), tt.url, model.AnalyzeOptions{FollowMetaRefresh: tt.follow})
+			if tt.wantErr != errs.Unknown {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) || appErr.Kind != tt.wantErr {
+					t.Fatalf("error = %v, want kind %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.URL != tt.wantURL || result.Title != tt.wantTitle {
+				t.Errorf("URL, Title = %q, %q, want %q, %q", result.URL, result.Title, tt.wantURL, tt.wantTitle)
+			}
+			if result.MetaRefresh == nil || *result.MetaRefresh != tt.wantRefresh {
+				t.Errorf("MetaRefresh = %+v, want %+v", result.MetaRefresh, tt.wantRefresh)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_ChecksPagination(t *testing.T) {
+	fetcher := pagesFetcher{
+		"https://example.com/list": `<html><head><title>List</title>
+			<link rel="next" href="/list?page=2"><link rel="prev" href="/missing"></head></html>`,
+	}
+	engine := NewEngine(fetcher, urlChecker{"https://example.com/missing": true})
+
+	result, err := engine.Analyze(context.Background(), "https://example.com/list", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := model.Pagination{
+		Next:             "https://example.com/list?page=2",
+		Prev:             "https://example.com/missing",
+		PrevInaccessible: true,
+	}
+	if result.Pagination != want {
+		t.Errorf("Pagination = %+v, want %+v", result.Pagination, want)
+	}
+}
+
+func TestEngine_Analyze_CheckPreloads(t *testing.T) {
+	fetcher := pagesFetcher{
+		"https://example.com/": `<html><head><title>Hints</title>
+			<link rel="preload" href="/app.css" as="style">
+			<link rel="preload" href="/old-font.woff2" as="font">
+			<link rel="preconnect dns-prefetch" href="https://cdn.example.net"></head></html>`,
+	}
+	checker := urlChecker{"https://example.com/old-font.woff2": true}
+
+	tests := []struct {
+		name string
+		opts model.AnalyzeOptions
+		want model.ResourceHints
+	}{
+		{
+			name: "not checked by default",
+			want: model.ResourceHints{Preload: 2, DNSPrefetch: 1, Preconnect: 1, Hosts: []string{"cdn.example.net", "example.com"}},
+		},
+		{
+			name: "checked when requested",
+			opts: model.AnalyzeOptions{CheckPreloads: true},
+			want: model.ResourceHints{
+				Preload: 2, DNSPrefetch: 1, Preconnect: 1, Hosts: []string{"cdn.example.net", "example.com"},
+				PreloadsChecked: true, PreloadsInaccessible: 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(fetcher, checker)
+
+			result, err := engine.Analyze(context.Background(), "https://example.com/", tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result.ResourceHints, tt.want) {
+				t.Errorf("ResourceHints = %+v, want %+v", result.ResourceHints, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_CheckImages(t *testing.T) {
+	fetcher := pagesFetcher{
+		"https://example.com/": `<html><head><title>Images</title></head><body>
+			<img src="/a.jpg" srcset="/a.jpg 1x, /a@2x.jpg 2x">
+			<picture><source srcset="/b.avif"><img src="/b.jpg"></picture></body></html>`,
+	}
+
+	tests := []struct {
+		name     string
+		opts     model.AnalyzeOptions
+		wantURLs []string
+		want     model.ImageStats
+	}{
+		{name: "not checked by default", want: model.ImageStats{Count: 2, MissingDimensions: 2, Srcset: 1}},
+		{
+			name:     "checked when requested",
+			opts:     model.AnalyzeOptions{CheckImages: true},
+			wantURLs: []string{"https://example.com/a.jpg", "https://example.com/a@2x.jpg", "https://example.com/b.avif", "https://example.com/b.jpg"},
+			want:     model.ImageStats{Count: 2, MissingDimensions: 2, Srcset: 1, Checked: true, Inaccessible: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := &mockLinkChecker{inaccessible: 1}
+			engine := NewEngine(fetcher, lc)
+
+			result, err := engine.Analyze(context.Background(), "https://example.com/", tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Images != tt.want {
+				t.Errorf("Images = %+v, want %+v", result.Images, tt.want)
+			}
+			if !slices.Equal(lc.receivedURLs, tt.wantURLs) {
+				t.Errorf("checked URLs = %q, want %q", lc.receivedURLs, tt.wantURLs)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_IncludeLinks(t *testing.T) {
+	fetcher := pagesFetcher{
+		"https://example.com/": `<html><head><title>Links</title></head><body>
+			<a href="/docs#intro">Docs</a>
+			<a href="https://other.com/ad" rel="Sponsored nofollow">Ad</a></body></html>`,
+	}
+	engine := NewEngine(fetcher, urlChecker{"https://other.com/ad": true})
+
+	result, err := engine.Analyze(context.Background(), "https://example.com/", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.LinksDetail != nil {
+		t.Errorf("LinksDetail = %+v, want none unless requested", result.LinksDetail)
+	}
+
+	result, err = engine.Analyze(context.Background(), "https://example.com/", model.AnalyzeOptions{IncludeLinks: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []model.LinkDetail{
+		{URL: "https://example.com/docs#intro", Internal: true, Text: "Docs", Checked: true, Status: 200},
+		{URL: "https://other.com/ad", Rel: []string{"sponsored", "nofollow"}, Text: "Ad", Checked: true, Status: 404, Failure: failureHTTP4xx},
+	}
+	if !reflect.DeepEqual(result.LinksDetail, want) {
+		t.Errorf("LinksDetail = %+v, want %+v", result.LinksDetail, want)
+	}
+}
+
+func TestEngine_AnalyzeHTML(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>Upload</title></head><body>
+	<a href="/docs">Docs</a>
+	<a href="https://other.com/x">X</a>
+	</body></html>`
+
+	tests := []struct {
+		name         string
+		baseURL      string
+		wantInternal int
+		wantExternal int
+	}{
+		{name: "with base URL", baseURL: "https://example.com/page", wantInternal: 1, wantExternal: 1},
+		{name: "without base URL skips relative links", baseURL: "", wantInternal: 0, wantExternal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &mockFetcher{err: errConnectionRefused}
+			engine := NewEngine(fetcher, &mockLinkChecker{})
+
+			result, err := engine.AnalyzeHTML(context.Background(), strings.NewReader(html), tt.baseURL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Title != "Upload" {
+				t.Errorf("Title = %q, want %q", result.Title, "Upload")
+			}
+			if result.URL != tt.baseURL {
+				t.Errorf("URL = %q, want %q", result.URL, tt.baseURL)
+			}
+			if result.Links.Internal != tt.wantInternal || result.Links.External != tt.wantExternal {
+				t.Errorf("Links = %+v, want internal=%d external=%d", result.Links, tt.wantInternal, tt.wantExternal)
+			}
+		})
+	}
+}
+
+func TestEngine_AnalyzeHTML_InvalidBaseURL(t *testing.T) {
+	engine := NewEngine(&mockFetcher{}, &mockLinkChecker{})
+
+	_, err := engine.AnalyzeHTML(context.Background(), strings.NewReader("<html></html>"), "ftp://example.com")
+
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput {
+		t.Fatalf("err = %v, want InvalidInput AppError", err)
+	}
+}
+
+func TestEngine_Analyze_TimeoutPhases(t *testing.T) {
+	tests := []struct {
+		name          string
+		engine        *Engine
+		wantPhase     string
+		wantCompleted int
+	}{
+		{
+			name:      "fetch",
+			engine:    NewEngine(blockingFetcher{}, &mockLinkChecker{}),
+			wantPhase: phaseFetch,
+		},
+		{
+			name:          "parse",
+			engine:        NewEngine(stallingFetcher{}, &mockLinkChecker{}),
+			wantPhase:     phaseParse,
+			wantCompleted: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			_, err := tt.engine.Analyze(ctx, "https://example.com", model.AnalyzeOptions{})
+
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) || appErr.Kind != errs.Timeout {
+				t.Fatalf("err = %v, want Timeout AppError", err)
+			}
+			detail, ok := appErr.Detail.(*model.TimeoutDetail)
+			if !ok {
+				t.Fatalf("Detail = %T, want *model.TimeoutDetail", appErr.Detail)
+			}
+			if detail.Phase != tt.wantPhase {
+				t.Errorf("Phase = %q, want %q", detail.Phase, tt.wantPhase)
+			}
+			if len(detail.CompletedPhases) != tt.wantCompleted {
+				t.Errorf("CompletedPhases = %v, want %d entries", detail.CompletedPhases, tt.wantCompleted)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_LinkCheckOutOfTime(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Many links</title></head><body>
+	<a href="https://example.com/a">A</a>
+	<a href="https://example.com/b">B</a>
+	<a href="https://example.com/c">C</a>
+	<img src="https://example.com/i.png">
+	</body></html>`
+	engine := NewEngine(&mockFetcher{body: page, statusCode: 200}, &mockLinkChecker{block: true})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := engine.Analyze(ctx, "https://example.com", model.AnalyzeOptions{CheckImages: true})
+	if err != nil {
+		t.Fatalf("err = %v, want the partial result", err)
+	}
+	if result.Title != "Many links" {
+		t.Errorf("Title = %q, want the parse results kept", result.Title)
+	}
+	if !result.Links.Partial || result.Links.Checked != 1 {
+		t.Errorf("links partial = %v, checked = %d, want partial with 1 checked", result.Links.Partial, result.Links.Checked)
+	}
+	if !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "1 of 4 links") }) {
+		t.Errorf("warnings = %q, want one saying link checking ran out of time", result.Warnings)
+	}
+}
+
+// delayedFetcher serves body after a delay.
+type delayedFetcher struct {
+	body  string
+	delay time.Duration
+}
+
+func (f delayedFetcher) Fetch(_ context.Context, _ string) (*Response, error) {
+	time.Sleep(f.delay)
+	return &Response{Body: io.NopCloser(strings.NewReader(f.body)), StatusCode: 200}, nil
+}
+
+func TestEngine_Analyze_Timings(t *testing.T) {
+	engine := NewEngine(delayedFetcher{body: "<html><title>Timed</title></html>", delay: 30 * time.Millisecond}, &mockLinkChecker{})
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := result.Timings
+	if got.FetchMs < 30 {
+		t.Errorf("FetchMs = %d, want at least the 30ms the fetch took", got.FetchMs)
+	}
+	if got.TotalMs < got.FetchMs+got.ParseMs+got.LinkCheckMs {
+		t.Errorf("TotalMs = %d, want at least the sum of the phases in %+v", got.TotalMs, got)
+	}
+}
+
+func TestEngine_Analyze_ContentHash(t *testing.T) {
+	page := "<html><head><title>Hashed</title></head><body>" + strings.Repeat("<p>content</p>", 20) + "</body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, page)
+	}))
+	defer server.Close()
+
+	full := sha256.Sum256([]byte(page))
+	start := sha256.Sum256([]byte(page[:64]))
+	tests := []struct {
+		name     string
+		limit    int64
+		want     string
+		truncate bool
+	}{
+		{"whole page", 0, hex.EncodeToString(full[:]), false},
+		{"past the size limit", 64, hex.EncodeToString(start[:]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(&HTTPClient{client: server.Client(), maxBodyBytes: tt.limit}, &mockLinkChecker{})
+			result, err := engine.Analyze(context.Background(), server.URL, model.AnalyzeOptions{})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if got := result.ContentHash; got == nil || got.SHA256 != tt.want || got.Truncated != tt.truncate {
+				t.Errorf("ContentHash = %+v, want {%s %v}", got, tt.want, tt.truncate)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_TitleQuality(t *testing.T) {
+	html := `<!DOCTYPE html><html><head><title>Café
+	menu</title><title>Café menu</title></head><body></body></html>`
+
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Title != "Café menu" {
+		t.Errorf("Title = %q, want %q", result.Title, "Café menu")
+	}
+	if result.TitleLength != 9 {
+		t.Errorf("TitleLength = %d, want 9 (characters, not bytes)", result.TitleLength)
+	}
+	if !result.MultipleTitles {
+		t.Error("MultipleTitles = false, want true")
+	}
+}
+
+func TestEngine_Analyze_CancelledLinkCheckIsPartial(t *testing.T) {
+	html := `<html><body><a href="/a">A</a><a href="/b">B</a></body></html>`
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, &mockLinkChecker{block: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	result, err := engine.Analyze(ctx, "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Links.Partial || result.Links.Checked != 1 {
+		t.Errorf("Links = {Checked: %d, Partial: %v}, want {1, true}", result.Links.Checked, result.Links.Partial)
+	}
+}
+
+func TestEngine_Analyze_InaccessibleByScope(t *testing.T) {
+	html := `<html><body>
+		<a href="/ok">OK</a>
+		<a href="/missing">Missing</a>
+		<a href="/missing#again">Missing again</a>
+		<a href="/gone">Gone</a>
+		<a href="https://other.example/dead">Dead</a>
+		<a href="https://other.example/fine">Fine</a>
+	</body></html>`
+	checker := urlChecker{
+		"https://example.com/missing": true,
+		"https://example.com/gone":    true,
+		"https://other.example/dead":  true,
+	}
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, checker)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	links := result.Links
+	if links.Inaccessible != 3 || links.InternalInaccessible != 2 || links.ExternalInaccessible != 1 {
+		t.Errorf("Inaccessible, internal, external = %d, %d, %d, want 3, 2, 1",
+			links.Inaccessible, links.InternalInaccessible, links.ExternalInaccessible)
+	}
+}
+
+func TestEngine_Analyze_CheckLinksScope(t *testing.T) {
+	html := `<html><body>
+		<a href="/missing">Missing</a>
+		<a href="https://other.example/dead">Dead</a>
+	</body></html>`
+	tests := []struct {
+		checkLinks                            string
+		wantChecked                           []string
+		wantTotal, wantInternal, wantExternal int
+	}{
+		{model.CheckLinksAll, []string{"https://example.com/missing", "https://other.example/dead"}, 2, 1, 1},
+		{model.CheckLinksInternal, []string{"https://example.com/missing"}, 1, 1, notChecked},
+		{model.CheckLinksExternal, []string{"https://other.example/dead"}, 1, notChecked, 1},
+		{model.CheckLinksNone, nil, notChecked, notChecked, notChecked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.checkLinks, func(t *testing.T) {
+			checker := urlChecker{"https://example.com/missing": true, "https://other.example/dead": true}
+			var checked []string
+			recorder := linkCheckerFunc(func(ctx context.Context, links []string) LinkReport {
+				checked = append(checked, links...)
+				return checker.CheckLinks(ctx, links)
+			})
+			engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, recorder)
+
+			result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{CheckLinks: tt.checkLinks})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			slices.Sort(checked)
+			if !slices.Equal(checked, tt.wantChecked) {
+				t.Errorf("checked %v, want %v", checked, tt.wantChecked)
+			}
+			links := result.Links
+			if links.Inaccessible != tt.wantTotal || links.InternalInaccessible != tt.wantInternal || links.ExternalInaccessible != tt.wantExternal {
+				t.Errorf("Inaccessible, internal, external = %d, %d, %d, want %d, %d, %d",
+					links.Inaccessible, links.InternalInaccessible, links.ExternalInaccessible,
+					tt.wantTotal, tt.wantInternal, tt.wantExternal)
+			}
+			if links.Internal != 1 || links.External != 1 {
+				t.Errorf("Internal, External = %d, %d, want 1, 1 regardless of scope", links.Internal, links.External)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_ExcludeLinks(t *testing.T) {
+	html := `<html><body>
+		<a href="/account">Account</a>
+		<a href="/logout">Log out</a>
+		<a href="/logout#again">Log out again</a>
+		<a href="https://calendar.example/export.ics">Export</a>
+	</body></html>`
+	checker := &mockLinkChecker{}
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, checker)
+
+	opts := model.AnalyzeOptions{ExcludeLinks: []string{"*/logout", `/\.ics$/`}}
+	result, err := engine.Analyze(context.Background(), "https://example.com", opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"https://example.com/account"}; !slices.Equal(checker.receivedURLs, want) {
+		t.Errorf("checker received %v, want %v", checker.receivedURLs, want)
+	}
+	if result.Links.Excluded != 2 {
+		t.Errorf("Excluded = %d, want 2 unique targets", result.Links.Excluded)
+	}
+	if result.Links.Internal != 3 || result.Links.External != 1 {
+		t.Errorf("Internal, External = %d, %d, want 3, 1", result.Links.Internal, result.Links.External)
+	}
+}
+
+func TestEngine_Analyze_InvalidExcludePattern(t *testing.T) {
+	fetcher := &mockFetcher{err: errFetchShouldNotRun}
+	engine := NewEngine(fetcher, &mockLinkChecker{})
+
+	_, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{ExcludeLinks: []string{"/(/"}})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput {
+		t.Fatalf("err = %v, want an InvalidInput AppError before fetching", err)
+	}
+}
+
+func TestEngine_Analyze_ExtrapolatesSample(t *testing.T) {
+	html := `<html><body>
+		<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a><a href="/d">D</a>
+	</body></html>`
+	// The checker samples two of the four links and finds one broken.
+	sampler := linkCheckerFunc(func(_ context.Context, links []string) LinkReport {
+		return LinkReport{
+			Inaccessible: 1,
+			Checked:      2,
+			Sampled:      true,
+			Results:      []LinkResult{StatusResult(links[0], http.StatusNotFound), StatusResult(links[2], http.StatusOK)},
+		}
+	})
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, sampler)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Links.Sampled || result.Links.EstimatedInaccessible != 2 {
+		t.Errorf("Sampled, EstimatedInaccessible = %v, %d, want true, 2",
+			result.Links.Sampled, result.Links.EstimatedInaccessible)
+	}
+}
+
+func TestEngine_Analyze_BlockedHost(t *testing.T) {
+	engine := NewEngine(&mockFetcher{err: errFetchShouldNotRun}, &mockLinkChecker{})
+	engine.BlockHosts([]string{"admin.example.com", "*.corp.example"})
+
+	for _, target := range []string{"https://admin.example.com/", "https://wiki.corp.example/page"} {
+		_, err := engine.Analyze(context.Background(), target, model.AnalyzeOptions{})
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput || !errors.Is(err, errBlockedHost) {
+			t.Errorf("Analyze(%s) err = %v, want an InvalidInput AppError for a blocked host", target, err)
+		}
+	}
+}
+
+func TestEngine_Analyze_BlockedRedirect(t *testing.T) {
+	var blockedHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "public.example" {
+			http.Redirect(w, r, "http://admin.example.com/", http.StatusFound)
+			return
+		}
+		blockedHits.Add(1)
+		_, _ = w.Write([]byte("<html><title>Admin</title></html>"))
+	}))
+	defer server.Close()
+
+	// Every hostname reaches the server, so only the blocklist keeps the
+	// redirect from being followed.
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		},
+		CheckRedirect: safeRedirectPolicy,
+	}
+	engine := NewEngine(&HTTPClient{client: client}, &mockLinkChecker{})
+	engine.BlockHosts([]string{"admin.example.com"})
+
+	_, err := engine.Analyze(context.Background(), "http://public.example/", model.AnalyzeOptions{})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput || !errors.Is(err, errBlockedHost) {
+		t.Errorf("err = %v, want an InvalidInput AppError for a blocked host", err)
+	}
+	if got := blockedHits.Load(); got != 0 {
+		t.Errorf("blocked host was fetched %d times, want none", got)
+	}
+}
+
+func TestEngine_Analyze_BlockedMetaRefresh(t *testing.T) {
+	fetcher := &routeFetcher{bodies: map[string]string{
+		"https://example.com/old": `<html><head><title>Moved</title>
+			<meta http-equiv="refresh" content="0; url=https://admin.example.com/"></head></html>`,
+		"https://admin.example.com/": `<html><head><title>Admin</title></head></html>`,
+	}}
+	engine := NewEngine(fetcher, &mockLinkChecker{})
+	engine.BlockHosts([]string{"admin.example.com"})
+
+	_, err := engine.Analyze(context.Background(), "https://example.com/old", model.AnalyzeOptions{FollowMetaRefresh: true})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput || !errors.Is(err, errBlockedHost) {
+		t.Errorf("err = %v, want an InvalidInput AppError for a blocked host", err)
+	}
+	if got := fetcher.fetches["https://admin.example.com/"]; got != 0 {
+		t.Errorf("blocked refresh target was fetched %d times, want none", got)
+	}
+}
+
+func TestEngine_Analyze_BlockedRobotsRedirect(t *testing.T) {
+	var blockedHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Host != "public.example":
+			blockedHits.Add(1)
+			_, _ = w.Write([]byte("User-agent: *\nAllow: /\n"))
+		case r.URL.Path == "/robots.txt":
+			http.Redirect(w, r, "http://admin.example.com/robots.txt", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("<html><title>Public</title></html>"))
+		}
+	}))
+	defer server.Close()
+
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+			},
+		},
+		CheckRedirect: safeRedirectPolicy,
+	}
+	engine := NewEngine(&HTTPClient{client: client}, &mockLinkChecker{})
+	engine.BlockHosts([]string{"admin.example.com"})
+	engine.CheckRobotsTxt(RobotsEnforce, "")
+
+	result, err := engine.Analyze(context.Background(), "http://public.example/", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Title != "Public" {
+		t.Errorf("Title = %q, want Public", result.Title)
+	}
+	if got := blockedHits.Load(); got != 0 {
+		t.Errorf("blocked host was fetched %d times, want none", got)
+	}
+}
+
+// routeFetcher serves a body per URL, 404 for the rest, and counts the
+// fetches of each URL.
+type routeFetcher struct {
+	bodies  map[string]string
+	fetches map[string]int
+}
+
+func (f *routeFetcher) Fetch(_ context.Context, target string) (*Response, error) {
+	if f.fetches == nil {
+		f.fetches = make(map[string]int)
+	}
+	f.fetches[target]++
+	body, ok := f.bodies[target]
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusNotFound
+	}
+	return &Response{Body: io.NopCloser(strings.NewReader(body)), StatusCode: status}, nil
+}
+
+func TestEngine_Analyze_RobotsTxt(t *testing.T) {
+	robots := "User-agent: *\nDisallow: /private\nSitemap: https://example.com/sitemap.xml\n"
+	page := "<html><head><title>T</title></head></html>"
+
+	tests := []struct {
+		name           string
+		policy         string
+		target         string
+		robots         string
+		wantForbidden  bool
+		wantRobots     *model.RobotsTxt
+		wantRobotsWarn bool
+	}{
+		{
+			name: "enforce disallowed", policy: RobotsEnforce,
+			target: "https://example.com/private/doc", robots: robots, wantForbidden: true,
+		},
+		{
+			name: "enforce allowed", policy: RobotsEnforce,
+			target: "https://example.com/public", robots: robots,
+			wantRobots: &model.RobotsTxt{Found: true, Sitemaps: []string{"https://example.com/sitemap.xml"}},
+		},
+		{
+			name: "warn disallowed", policy: RobotsWarn,
+			target: "https://example.com/private/doc", robots: robots,
+			wantRobots:     &model.RobotsTxt{Found: true, Disallowed: true, Sitemaps: []string{"https://example.com/sitemap.xml"}},
+			wantRobotsWarn: true,
+		},
+		{
+			name: "no robots.txt", policy: RobotsEnforce,
+			target:     "https://example.com/private/doc",
+			wantRobots: &model.RobotsTxt{},
+		},
+		{name: "off", policy: "off", target: "https://example.com/private/doc", robots: robots},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies := map[string]string{tt.target: page}
+			if tt.robots != "" {
+				bodies["https://example.com/robots.txt"] = tt.robots
+			}
+			engine := NewEngine(&routeFetcher{bodies: bodies}, &mockLinkChecker{})
+			engine.CheckRobotsTxt(tt.policy, "PageInsightBot/1.0")
+
+			result, err := engine.Analyze(context.Background(), tt.target, model.AnalyzeOptions{})
+			if tt.wantForbidden {
+				var appErr *errs.AppError
+				if !errors.As(err, &appErr) || appErr.Kind != errs.Forbidden {
+					t.Fatalf("err = %v, want a Forbidden AppError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result.Robots, tt.wantRobots) {
+				t.Errorf("Robots = %+v, want %+v", result.Robots, tt.wantRobots)
+			}
+			warned := slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "robots.txt") })
+			if warned != tt.wantRobotsWarn {
+				t.Errorf("Warnings = %v, want a robots.txt warning: %v", result.Warnings, tt.wantRobotsWarn)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_RobotsTxtCachedPerHost(t *testing.T) {
+	fetcher := &routeFetcher{bodies: map[string]string{
+		"https://example.com/robots.txt": "User-agent: *\nDisallow:\n",
+		"https://example.com/a":          "<html></html>",
+		"https://EXAMPLE.com/b":          "<html></html>",
+	}}
+	engine := NewEngine(fetcher, &mockLinkChecker{})
+	engine.CheckRobotsTxt(RobotsWarn, "")
+
+	for _, target := range []string{"https://example.com/a", "https://EXAMPLE.com/b"} {
+		if _, err := engine.Analyze(context.Background(), target, model.AnalyzeOptions{}); err != nil {
+			t.Fatalf("Analyze(%s): %v", target, err)
+		}
+	}
+	if got := fetcher.fetches["https://example.com/robots.txt"]; got != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", got)
+	}
+}
+
+// linkCheckerFunc adapts a function to the linkChecker interface.
+type linkCheckerFunc func(ctx context.Context, links []string) LinkReport
+
+func (f linkCheckerFunc) CheckLinks(ctx context.Context, links []string) LinkReport {
+	return f(ctx, links)
+}
+
+func TestEngine_Analyze_NormalizesLinkTargets(t *testing.T) {
+	html := `<html><body>
+		<a href="https://example.com/page">A</a>
+		<a href="https://example.com/page/">B</a>
+		<a href="https://EXAMPLE.com/page">C</a>
+		<a href="https://example.com:443/page">D</a>
+		<a href="https://example.com//page#top">E</a>
+	</body></html>`
+	tests := []struct {
+		name     string
+		collapse bool
+		want     []string
+	}{
+		{
+			name: "repeated slashes kept",
+			want: []string{"https://example.com/page", "https://example.com//page"},
+		},
+		{
+			name:     "repeated slashes collapsed",
+			collapse: true,
+			want:     []string{"https://example.com/page"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &mockLinkChecker{}
+			engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, checker)
+			if tt.collapse {
+				engine.CollapseDuplicateSlashes()
+			}
+
+			result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(checker.receivedURLs, tt.want) {
+				t.Errorf("checker received %v, want %v", checker.receivedURLs, tt.want)
+			}
+			if got := result.Links.Internal + result.Links.External; got != 5 {
+				t.Errorf("Internal + External = %d, want 5 raw occurrences", got)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_ChecksLinkAsSpelled(t *testing.T) {
+	html := `<html><body>
+		<a href="https://web.archive.org/web/2020/https://x.example/">Archived</a>
+		<a href="https://example.com/page/">Page</a>
+		<a href="https://example.com/page">Page again</a>
+	</body></html>`
+	// Only the URLs as the page spells them exist; a rewritten spelling 404s.
+	exists := map[string]bool{
+		"https://web.archive.org/web/2020/https://x.example/": true,
+		"https://example.com/page/":                           true,
+	}
+	checker := linkCheckerFunc(func(_ context.Context, links []string) LinkReport {
+		report := LinkReport{Checked: len(links)}
+		for _, link := range links {
+			status := http.StatusOK
+			if !exists[link] {
+				status = http.StatusNotFound
+				report.Inaccessible++
+			}
+			report.Results = append(report.Results, StatusResult(link, status))
+		}
+		return report
+	})
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, checker)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{IncludeLinks: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Links.Inaccessible != 0 {
+		t.Errorf("Inaccessible = %d, want 0", result.Links.Inaccessible)
+	}
+	for _, detail := range result.LinksDetail {
+		if !detail.Checked || detail.Status != http.StatusOK {
+			t.Errorf("detail for %s = checked %v, status %d, want a 200", detail.URL, detail.Checked, detail.Status)
+		}
+	}
+}
+
+// failureChecker reports the URLs in the map as inaccessible with the mapped
+// failure category and the rest as 200s.
+type failureChecker map[string]string
+
+func (c failureChecker) CheckLinks(_ context.Context, links []string) LinkReport {
+	report := LinkReport{Checked: len(links)}
+	for _, link := range links {
+		result := StatusResult(link, http.StatusOK)
+		if failure, ok := c[link]; ok {
+			result = LinkResult{URL: link, Failure: failure}
+			report.Inaccessible++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func TestEngine_Analyze_FailureBreakdown(t *testing.T) {
+	html := `<html><body>
+		<a href="https://a.example/">A</a><a href="https://b.example/">B</a><a href="https://c.example/">C</a>
+		<a href="https://d.example/">D</a><a href="https://e.example/">E</a><a href="https://f.example/">F</a>
+		<a href="https://g.example/">G</a>
+	</body></html>`
+	checker := failureChecker{
+		"https://a.example/": failureTLS,
+		"https://b.example/": failureDNS,
+		"https://c.example/": failureTimeout,
+		"https://d.example/": failureConnectionRefused,
+		"https://e.example/": failureHTTP4xx,
+		"https://f.example/": failureHTTP5xx,
+	}
+	engine := NewEngine(&mockFetcher{body: html, statusCode: 200}, checker)
+
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	links := result.Links
+	got := []int{links.TLSErrors, links.DNSErrors, links.Timeouts, links.ConnectionErrors, links.HTTPErrors}
+	if want := []int{1, 1, 1, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("tls, dns, timeout, connection, http errors = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_Analyze_FetchErrorMessage(t *testing.T) {
+	certErr := &url.Error{Op: "Get", URL: "https://expired.example", Err: &tls.CertificateVerificationError{
+		Err: x509.CertificateInvalidError{Reason: x509.Expired},
+	}}
+	engine := NewEngine(&mockFetcher{err: certErr}, &mockLinkChecker{})
+
+	_, err := engine.Analyze(context.Background(), "https://expired.example", model.AnalyzeOptions{})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.Unreachable {
+		t.Fatalf("err = %v, want Unreachable AppError", err)
+	}
+	if !strings.Contains(appErr.Message, "certificate has expired") {
+		t.Errorf("Message = %q, want it to say the certificate expired", appErr.Message)
+	}
+}
+
+func TestEngine_Analyze_Progress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			_, _ = io.WriteString(w, `<html><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	engine := NewEngine(&HTTPClient{client: server.Client()}, testLinkChecker(2))
+	var events []progress.Event
+	ctx := progress.NewContext(context.Background(), func(ev progress.Event) { events = append(events, ev) })
+	if _, err := engine.Analyze(ctx, server.URL, model.AnalyzeOptions{}); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if len(events) != 6 {
+		t.Fatalf("got events %+v, want fetching, parsing and four link counts", events)
+	}
+	if events[0].Stage != progress.Fetching || events[1].Stage != progress.Parsing {
+		t.Errorf("first events = %+v, want fetching then parsing", events[:2])
+	}
+	for i, ev := range events[2:] {
+		want := progress.Event{Stage: progress.CheckingLinks, Checked: i, Total: 3}
+		if ev != want {
+			t.Errorf("link event %d = %+v, want %+v", i, ev, want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package pageinsight
+
+import (
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// errorPageBytes is how much of an error page is read for its title.
+	errorPageBytes = 16 << 10
+	// errorTitleRunes caps the error page title reported with the error.
+	errorTitleRunes = 120
+)
+
+// errorPageTitle reads the title from the start of an error page, which
+// tells a WAF or CDN block page apart from the site's own error. Control
+// and formatting characters are dropped, so the title can't reorder or hide
+// text where it is shown, and it is cut to errorTitleRunes. It is "" when
+// the page has no title near its start.
+func errorPageTitle(body io.Reader) string {
+	z := html.NewTokenizer(io.LimitReader(body, errorPageBytes))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) != "title" {
+				continue
+			}
+			if z.Next() != html.TextToken {
+				return ""
+			}
+			title := strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+					return ' '
+				}
+				return r
+			}, string(z.Text()))
+			return truncateText(title, errorTitleRunes)
+		}
+	}
+}
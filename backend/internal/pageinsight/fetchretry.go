@@ -0,0 +1,92 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultFetchAttempts is how many times a page fetch is tried unless
+// another number is configured: one retry rides out a connection reset or a
+// restarting upstream without stretching a failing analysis much.
+const defaultFetchAttempts = 2
+
+// fetchRetryBackoff is the wait before the first retry. It doubles for each
+// further one, plus up to half again of jitter.
+const fetchRetryBackoff = 200 * time.Millisecond
+
+// retryableStatus reports whether a page response is worth fetching again:
+// the gateway errors of an upstream that is down or restarting.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryableFetchError reports whether a page fetch that got no response may
+// succeed when tried again. Timeouts are not retried, as the attempt already
+// used the time allowed, nor are blocked addresses, redirect policy and TLS
+// errors, which another attempt would hit the same way.
+func retryableFetchError(err error) bool {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, errTooManyRedirects), errors.Is(err, errBlockedRedirect), errors.Is(err, errSelfTarget),
+		errors.Is(err, errBlockedHost):
+		return false
+	case errors.As(err, &dnsErr):
+		return dnsErr.IsTemporary
+	}
+	switch failureCategory(err) {
+	case failureConnectionRefused, failureUnreachable:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryBackoff is the wait before attempt number attempt, counting from 1
+// for the first try.
+func retryBackoff(attempt int) time.Duration {
+	backoff := fetchRetryBackoff << (attempt - 2)
+	return backoff + rand.N(backoff/2) //nolint:gosec // jitter needs no cryptographic randomness
+}
+
+// discard drains a little of a response that will not be used, so its
+// connection can be reused, and closes it.
+func discard(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+	_ = resp.Body.Close()
+}
+
+// doWithRetry sends a page request up to attempts times, as long as each try
+// fails in a way retryableFetchError or retryableStatus allows and ctx is
+// still live. The last response is returned whatever its status, with the
+// number of attempts made.
+func (c *HTTPClient) doWithRetry(ctx context.Context, attempts int, newRequest func() (*http.Request, error)) (*http.Response, int, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, attempt, err
+		}
+		resp, err := c.client.Do(req) //nolint:bodyclose // closed by discard or returned to the caller
+		last := attempt >= attempts || ctx.Err() != nil
+		switch {
+		case err != nil && (last || !retryableFetchError(err)):
+			return nil, attempt, err
+		case err == nil && (last || !retryableStatus(resp.StatusCode)):
+			return resp, attempt, nil
+		case err == nil:
+			discard(resp)
+		}
+		if !sleepContext(ctx, retryBackoff(attempt+1)) {
+			return nil, attempt, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errConnectionReset = errors.New("connection reset by peer")
+
+// flakyServer fails the first failures requests with fail, then serves a
+// page. It counts the requests it got.
+func flakyServer(t *testing.T, failures int32, fail func(http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) <= failures {
+			fail(w)
+			return
+		}
+		_, _ = io.WriteString(w, "<html><head><title>OK</title></head></html>")
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &requests
+}
+
+// resetConnection drops the connection without answering.
+func resetConnection(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err == nil {
+		_ = conn.Close()
+	}
+}
+
+func failWith(status int) func(http.ResponseWriter) {
+	return func(w http.ResponseWriter) { w.WriteHeader(status) }
+}
+
+func TestHTTPClient_Fetch_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		fail         func(http.ResponseWriter)
+		maxAttempts  int
+		wantStatus   int
+		wantRequests int32
+	}{
+		{name: "connection reset once", failures: 1, fail: resetConnection, wantStatus: http.StatusOK, wantRequests: 2},
+		{name: "503 once", failures: 1, fail: failWith(http.StatusServiceUnavailable), wantStatus: http.StatusOK, wantRequests: 2},
+		{name: "502 once", failures: 1, fail: failWith(http.StatusBadGateway), wantStatus: http.StatusOK, wantRequests: 2},
+		{name: "504 on every attempt", failures: 5, fail: failWith(http.StatusGatewayTimeout), wantStatus: http.StatusGatewayTimeout, wantRequests: 2},
+		{name: "three attempts configured", failures: 2, fail: failWith(http.StatusServiceUnavailable), maxAttempts: 3, wantStatus: http.StatusOK, wantRequests: 3},
+		{name: "retries turned off", failures: 1, fail: failWith(http.StatusServiceUnavailable), maxAttempts: 1, wantStatus: http.StatusServiceUnavailable, wantRequests: 1},
+		{name: "404 is not retried", failures: 1, fail: failWith(http.StatusNotFound), wantStatus: http.StatusNotFound, wantRequests: 1},
+		{name: "500 is not retried", failures: 1, fail: failWith(http.StatusInternalServerError), wantStatus: http.StatusInternalServerError, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, requests := flakyServer(t, tt.failures, tt.fail)
+			c := &HTTPClient{client: ts.Client()}
+			c.SetMaxAttempts(tt.maxAttempts)
+
+			resp, err := c.Fetch(context.Background(), ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("server got %d requests, want %d", got, tt.wantRequests)
+			}
+			if resp.Attempts != int(tt.wantRequests) {
+				t.Errorf("Attempts = %d, want %d", resp.Attempts, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHTTPClient_Fetch_RetryGivesUp(t *testing.T) {
+	ts, requests := flakyServer(t, 5, resetConnection)
+	c := &HTTPClient{client: ts.Client()}
+
+	_, err := c.Fetch(context.Background(), ts.URL)
+	if err == nil {
+		t.Fatal("expected an error after every attempt failed")
+	}
+	if got := requests.Load(); got != defaultFetchAttempts {
+		t.Errorf("server got %d requests, want %d", got, defaultFetchAttempts)
+	}
+}
+
+func TestHTTPClient_Fetch_NoRetryWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts, requests := flakyServer(t, 5, func(w http.ResponseWriter) {
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c := &HTTPClient{client: ts.Client()}
+
+	resp, err := c.Fetch(ctx, ts.URL)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server got %d requests, want 1 once the context was cancelled", got)
+	}
+}
+
+func TestRetryableFetchError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection reset", &net.OpError{Op: "read", Err: errConnectionReset}, true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"temporary DNS failure", &net.DNSError{Err: "server misbehaving", IsTemporary: true}, true},
+		{"unknown host", &net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{"blocked address", errBlockedAddress, false},
+		{"too many redirects", errTooManyRedirects, false},
+		{"timeout", context.DeadlineExceeded, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryableFetchError(tt.err); got != tt.want {
+				t.Errorf("retryableFetchError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt, base := range map[int]time.Duration{2: fetchRetryBackoff, 3: 2 * fetchRetryBackoff} {
+		if got := retryBackoff(attempt); got < base || got >= base+base/2 {
+			t.Errorf("retryBackoff(%d) = %v, want within [%v, %v)", attempt, got, base, base+base/2)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package pageinsight
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// defaultMaxResponseBody caps the decoded page body unless another limit is
+// configured, to prevent memory exhaustion from extremely large or infinite
+// responses and from decompression bombs.
+const defaultMaxResponseBody = 10 << 20 // 10 MB
+
+// FetchTiming breaks down how long a page fetch took. After redirects, DNS,
+// Connect and TLS describe the last hop, while TTFB and Total run from the
+// first request. A zero duration was not observed, like the DNS lookup and
+// connect of a reused connection.
+type FetchTiming struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+}
+
+// fetchTrace records the moments of one fetch through httptrace. Its
+// callbacks may run on the transport's goroutines, hence the lock.
+type fetchTrace struct {
+	mu                  sync.Mutex
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte, finished time.Time
+}
+
+func newFetchTrace() *fetchTrace {
+	return &fetchTrace{start: time.Now()}
+}
+
+func (t *fetchTrace) mark(moment *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*moment = time.Now()
+}
+
+// clientTrace hooks t into a request.
+func (t *fetchTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.mark(&t.connStart) },
+		ConnectDone:          func(string, string, error) { t.mark(&t.connDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+}
+
+// finish records the end of the body, the first time it is called.
+func (t *fetchTrace) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finished.IsZero() {
+		t.finished = time.Now()
+	}
+}
+
+// timing reports the durations observed so far. Total runs to now if the
+// body has not been read to its end.
+func (t *fetchTrace) timing() FetchTiming {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := t.finished
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return FetchTiming{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connStart, t.connDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		TTFB:    between(t.start, t.firstByte),
+		Total:   end.Sub(t.start),
+	}
+}
+
+// between is the time from start to end, or zero unless both were recorded
+// in that order.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// fetchStats reports the timing and size of a fetched page whose body has
+// been read, decoded bytes of it.
+func fetchStats(resp *Response, decoded int64) model.FetchStats {
+	stats := model.FetchStats{
+		BodyBytes: decoded,
+		Protocol:  resp.Proto,
+		Server:    resp.Header.Get("Server"),
+		PoweredBy: resp.Header.Get("X-Powered-By"),
+		Attempts:  resp.Attempts,
+	}
+	if resp.Timing != nil {
+		timing := resp.Timing()
+		stats.DNSMs = timing.DNS.Milliseconds()
+		stats.ConnectMs = timing.Connect.Milliseconds()
+		stats.TLSMs = timing.TLS.Milliseconds()
+		stats.TTFBMs = timing.TTFB.Milliseconds()
+		stats.TotalMs = timing.Total.Milliseconds()
+	}
+	if resp.Truncated != nil {
+		stats.Truncated = resp.Truncated()
+	}
+	return stats
+}
+
+// byteSize formats a size limit in whole megabytes, or kilobytes when it
+// isn't one.
+func byteSize(n int64) string {
+	if n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	return fmt.Sprintf("%d KB", n>>10)
+}
+
+// pageBody is a fetched page body capped at a number of decoded bytes.
+// It notes whether the page went on past the cap and when the body ended.
+type pageBody struct {
+	r         io.Reader
+	closer    io.Closer
+	trace     *fetchTrace
+	remaining int64
+	truncated bool
+}
+
+func (b *pageBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// One more byte tells a page of exactly the cap from a longer one.
+		var probe [1]byte
+		if n, _ := io.ReadFull(b.r, probe[:]); n > 0 {
+			b.truncated = true
+		}
+		b.trace.finish()
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if err != nil {
+		b.trace.finish()
+	}
+	return n, err
+}
+
+func (b *pageBody) Close() error {
+	b.trace.finish()
+	return b.closer.Close()
+}
+
+// Truncated reports whether the page was longer than the cap. It is
+// only final once the body has been read to its end.
+func (b *pageBody) Truncated() bool {
+	return b.truncated
+}
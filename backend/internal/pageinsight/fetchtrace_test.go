@@ -0,0 +1,119 @@
+package pageinsight
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+func TestHTTPClient_Fetch_Timing(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(delay)
+		_, _ = io.WriteString(w, "<html>")
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		_, _ = io.WriteString(w, "</html>")
+	}))
+	defer ts.Close()
+
+	c := &HTTPClient{client: ts.Client()}
+	resp, err := c.Fetch(context.Background(), ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	timing := resp.Timing()
+	if timing.TTFB < delay {
+		t.Errorf("TTFB = %v, want at least %v", timing.TTFB, delay)
+	}
+	if timing.Total < timing.TTFB+delay {
+		t.Errorf("Total = %v, want the TTFB of %v plus the %v the body took", timing.Total, timing.TTFB, delay)
+	}
+	if timing.Connect <= 0 {
+		t.Errorf("Connect = %v, want the new connection measured", timing.Connect)
+	}
+}
+
+func TestHTTPClient_Fetch_Truncated(t *testing.T) {
+	const configured = 1 << 20
+	tests := []struct {
+		name          string
+		limit         int64 // 0 keeps the default
+		size          int
+		wantTruncated bool
+	}{
+		{name: "under the limit", size: 1024},
+		{name: "exactly the default limit", size: defaultMaxResponseBody},
+		{name: "over the default limit", size: defaultMaxResponseBody + 1, wantTruncated: true},
+		{name: "exactly a configured limit", limit: configured, size: configured},
+		{name: "over a configured limit", limit: configured, size: configured + 1, wantTruncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(w, strings.Repeat("a", tt.size))
+			}))
+			defer ts.Close()
+
+			c := &HTTPClient{client: ts.Client()}
+			c.SetMaxBodyBytes(tt.limit)
+			resp, err := c.Fetch(context.Background(), ts.URL)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			n, _ := io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			limit := tt.limit
+			if limit == 0 {
+				limit = defaultMaxResponseBody
+			}
+			if want := min(int64(tt.size), limit); n != want {
+				t.Errorf("read %d bytes, want %d", n, want)
+			}
+			if got := resp.Truncated(); got != tt.wantTruncated {
+				t.Errorf("Truncated() = %v, want %v", got, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_FetchStats(t *testing.T) {
+	const limit = 1 << 20
+	page := "<html><head><title>Big</title></head><body>" + strings.Repeat("<p>filler</p>", limit/13) + "</body></html>"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, page)
+	}))
+	defer ts.Close()
+
+	fetcher := &HTTPClient{client: ts.Client()}
+	fetcher.SetMaxBodyBytes(limit)
+	engine := NewEngine(fetcher, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), ts.URL, model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Fetch.Truncated || result.Fetch.BodyBytes != limit {
+		t.Errorf("fetch = %+v, want a truncated body of %d bytes", result.Fetch, limit)
+	}
+	if result.Title != "Big" {
+		t.Errorf("Title = %q, want the start of the page analyzed", result.Title)
+	}
+	found := false
+	for _, w := range result.Warnings {
+		found = found || strings.Contains(w, "larger than the 1 MB response size limit")
+	}
+	if !found {
+		t.Errorf("warnings = %v, want one about the truncated page", result.Warnings)
+	}
+}
@@ -0,0 +1,188 @@
+package pageinsight
+
+import (
+	"slices"
+	"strings"
+)
+
+// Login form classifications reported in ParseResult.LoginForm.
+const (
+	loginFormNone   = "none"
+	loginFormLogin  = "login"
+	loginFormSignup = "signup"
+)
+
+var (
+	loginActionKeywords  = []string{"login", "log-in", "log_in", "signin", "sign-in", "sign_in"}
+	signupActionKeywords = []string{"signup", "sign-up", "sign_up", "register"}
+	searchInputNames     = []string{"q", "s", "query"}
+)
+
+// inputField holds the attributes of an <input> that hint at a form's purpose.
+type inputField struct {
+	typ          string
+	autocomplete string
+	name         string
+}
+
+// formSignals accumulates the hints one form gives about its purpose.
+type formSignals struct {
+	action          string
+	passwords       int
+	newPassword     bool
+	currentPassword bool
+	identifier      bool // a username or email field
+	search          bool
+}
+
+func (f *formSignals) input(in inputField, inLandmark bool) {
+	typ := strings.ToLower(strings.TrimSpace(in.typ))
+	autocomplete := strings.ToLower(in.autocomplete)
+	textual := typ == "" || typ == "text" || typ == "search"
+	name := strings.ToLower(strings.TrimSpace(in.name))
+	if typ == "search" || (textual && (inLandmark || slices.Contains(searchInputNames, name))) {
+		f.search = true
+	}
+	switch {
+	case hasToken(autocomplete, "new-password"):
+		f.newPassword = true
+	case hasToken(autocomplete, "current-password"):
+		f.currentPassword = true
+	}
+	switch {
+	case typ == "password":
+		f.passwords++
+	case hasToken(autocomplete, "username") || hasToken(autocomplete, "email") || typ == "email":
+		f.identifier = true
+	}
+}
+
+// classify decides whether the form logs users in or signs them up. Password
+// fields and autocomplete hints are the primary signals; the action URL only
+// breaks ties, e.g. for the first step of a username-then-password login.
+func (f *formSignals) classify() string {
+	action := strings.ToLower(f.action)
+	switch {
+	case f.newPassword || f.passwords >= 2:
+		return loginFormSignup
+	case f.currentPassword:
+		return loginFormLogin
+	case f.passwords == 1 && containsAny(action, signupActionKeywords):
+		return loginFormSignup
+	case f.passwords == 1:
+		return loginFormLogin
+	case f.identifier && containsAny(action, loginActionKeywords):
+		return loginFormLogin
+	}
+	return loginFormNone
+}
+
+// formTracker classifies every form on the page. Inputs outside any <form>
+// (common in script-driven login flows) are grouped as one implicit form.
+type formTracker struct {
+	inForm  bool
+	current formSignals
+	orphans formSignals
+	login   bool
+	signup  bool
+
+	// search is set once a search form is seen. searchAction is the raw
+	// action of the first one, and searchInForm is false when it was only a
+	// search input or landmark outside any <form>.
+	search       bool
+	searchAction string
+	searchInForm bool
+
+	// landmark is the tag name of the open role="search" element, if any.
+	// landmarkDepth counts same-name elements nested in it, so the matching
+	// end tag closes it.
+	landmark      string
+	landmarkDepth int
+}
+
+func (t *formTracker) open(action string, searchRole bool) {
+	// Browsers ignore a <form> start tag nested in an open form.
+	if t.inForm {
+		return
+	}
+	t.inForm = true
+	t.current = formSignals{action: action, search: searchRole || t.landmark != ""}
+}
+
+func (t *formTracker) input(in inputField) {
+	inLandmark := t.landmark != ""
+	if t.inForm {
+		t.current.input(in, inLandmark)
+		return
+	}
+	t.orphans.input(in, inLandmark)
+}
+
+func (t *formTracker) close() {
+	if !t.inForm {
+		return
+	}
+	t.inForm = false
+	t.record(&t.current, true)
+}
+
+// enterLandmark opens a non-form role="search" region.
+func (t *formTracker) enterLandmark(tag string) {
+	if t.landmark == "" {
+		t.landmark = tag
+		t.landmarkDepth = 1
+	}
+}
+
+func (t *formTracker) startTag(tag []byte) {
+	if t.landmark != "" && string(tag) == t.landmark {
+		t.landmarkDepth++
+	}
+}
+
+func (t *formTracker) endTag(tag []byte) {
+	if t.landmark == "" || string(tag) != t.landmark {
+		return
+	}
+	t.landmarkDepth--
+	if t.landmarkDepth == 0 {
+		t.landmark = ""
+	}
+}
+
+func (t *formTracker) record(f *formSignals, inForm bool) {
+	if f.search && !t.search {
+		t.search = true
+		t.searchAction = f.action
+		t.searchInForm = inForm
+	}
+	switch f.classify() {
+	case loginFormLogin:
+		t.login = true
+	case loginFormSignup:
+		t.signup = true
+	}
+}
+
+// result closes any open form and returns the page classification. A page
+// with both kinds of form, like a combined sign-in/register page, is "login".
+func (t *formTracker) result() string {
+	t.close()
+	t.record(&t.orphans, false)
+	switch {
+	case t.login:
+		return loginFormLogin
+	case t.signup:
+		return loginFormSignup
+	}
+	return loginFormNone
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
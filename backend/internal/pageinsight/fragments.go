@@ -0,0 +1,52 @@
+package pageinsight
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// maxBrokenFragmentSamples caps how many missing fragment targets are listed.
+const maxBrokenFragmentSamples = 5
+
+// fragmentTargets collects the ids and legacy <a name> anchors a same-page
+// link can scroll to.
+type fragmentTargets struct {
+	names map[string]bool
+}
+
+func (t *fragmentTargets) add(name string) {
+	if name == "" {
+		return
+	}
+	if t.names == nil {
+		t.names = make(map[string]bool)
+	}
+	t.names[name] = true
+}
+
+// broken counts the fragments with no matching target, listing up to
+// maxBrokenFragmentSamples distinct ones. An empty fragment and "top" scroll
+// to the top of the page, and text fragments (#:~:text=) are matched against
+// the text, so none of those can be broken.
+func (t *fragmentTargets) broken(fragments []string) (int, []string) {
+	var count int
+	var samples []string
+	for _, fragment := range fragments {
+		name := fragment
+		if decoded, err := url.PathUnescape(fragment); err == nil {
+			name = decoded
+		}
+		if name == "" || strings.EqualFold(name, "top") || strings.HasPrefix(name, ":~:") {
+			continue
+		}
+		if t.names[name] || t.names[fragment] {
+			continue
+		}
+		count++
+		if len(samples) < maxBrokenFragmentSamples && !slices.Contains(samples, fragment) {
+			samples = append(samples, fragment)
+		}
+	}
+	return count, samples
+}
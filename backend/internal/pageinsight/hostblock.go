@@ -0,0 +1,60 @@
+package pageinsight
+
+import (
+	"errors"
+	"strings"
+)
+
+var errBlockedHost = errors.New("host is blocked by the server's configuration")
+
+// hostBlocklist forbids hostnames an operator doesn't want analyzed or
+// checked, whatever they resolve to. An entry is either an exact hostname or
+// a wildcard like *.corp.example.com, which matches every subdomain of
+// corp.example.com but not corp.example.com itself. A nil list blocks
+// nothing.
+type hostBlocklist struct {
+	exact    map[string]bool
+	suffixes []string // ".corp.example.com" for *.corp.example.com
+}
+
+func newHostBlocklist(entries []string) *hostBlocklist {
+	var b hostBlocklist
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if suffix, ok := strings.CutPrefix(entry, "*."); ok {
+			b.suffixes = append(b.suffixes, "."+asciiHostname(suffix))
+		} else if entry != "" {
+			if b.exact == nil {
+				b.exact = make(map[string]bool)
+			}
+			b.exact[asciiHostname(entry)] = true
+		}
+	}
+	if b.exact == nil && b.suffixes == nil {
+		return nil
+	}
+	return &b
+}
+
+// blocks reports whether hostname, without a port, is on the list. Trailing
+// dots are ignored, so example.com. can't slip past an example.com entry.
+func (b *hostBlocklist) blocks(hostname string) bool {
+	if b == nil {
+		return false
+	}
+	host := strings.TrimSuffix(asciiHostname(hostname), ".")
+	if b.exact[host] {
+		return true
+	}
+	for _, suffix := range b.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocksLink reports whether the host of link is on the list.
+func (b *hostBlocklist) blocksLink(link string) bool {
+	return b != nil && b.blocks(linkHostname(link))
+}
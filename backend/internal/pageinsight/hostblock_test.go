@@ -0,0 +1,40 @@
+package pageinsight
+
+import "testing"
+
+func TestHostBlocklist_Blocks(t *testing.T) {
+	list := newHostBlocklist([]string{"admin.example.com", "*.corp.example.com", " München.example "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "admin.example.com", want: true},
+		{host: "ADMIN.example.com", want: true},
+		{host: "admin.example.com.", want: true},
+		{host: "www.example.com", want: false},
+		{host: "wiki.corp.example.com", want: true},
+		{host: "a.b.corp.example.com", want: true},
+		{host: "corp.example.com", want: false},
+		{host: "notcorp.example.com", want: false},
+		{host: "xn--mnchen-3ya.example", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := list.blocks(tt.host); got != tt.want {
+				t.Errorf("blocks(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostBlocklist_Empty(t *testing.T) {
+	list := newHostBlocklist([]string{"", " "})
+	if list != nil {
+		t.Errorf("newHostBlocklist of blank entries = %+v, want nil", list)
+	}
+	if list.blocksLink("https://example.com/") {
+		t.Error("nil list blocked a link")
+	}
+}
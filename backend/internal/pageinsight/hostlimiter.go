@@ -0,0 +1,139 @@
+package pageinsight
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// hostLimiter caps the requests in flight to any one host, so a page whose
+// links all point at the same site does not get rate limited by it.
+type hostLimiter struct {
+	limit int
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]chan struct{})}
+}
+
+// acquire waits for a free slot for host. It returns false if the context is
+// done first.
+func (l *hostLimiter) acquire(ctx context.Context, host string) bool {
+	select {
+	case l.slot(host) <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *hostLimiter) release(host string) {
+	<-l.slot(host)
+}
+
+func (l *hostLimiter) slot(host string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ch, ok := l.slots[host]
+	if !ok {
+		ch = make(chan struct{}, l.limit)
+		l.slots[host] = ch
+	}
+	return ch
+}
+
+// hostSet is a set of hosts safe for concurrent use. A nil *hostSet is empty
+// and ignores additions.
+type hostSet struct {
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+func (s *hostSet) add(host string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]bool)
+	}
+	s.hosts[host] = true
+}
+
+func (s *hostSet) has(host string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hosts[host]
+}
+
+// hostBreaker gives up on hosts that look down: once threshold consecutive
+// checks on a host fail to connect or time out, further checks on it are
+// skipped. A nil *hostBreaker never trips.
+type hostBreaker struct {
+	threshold int
+	mu        sync.Mutex
+	failures  map[string]int    // consecutive connection failures per host
+	last      map[string]string // the latest failure category per host
+}
+
+func newHostBreaker(threshold int) *hostBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &hostBreaker{threshold: threshold, failures: make(map[string]int), last: make(map[string]string)}
+}
+
+// record notes the outcome of a check on host. Only failures to get any
+// answer count; an HTTP error status shows the host is up.
+func (b *hostBreaker) record(host, failure string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch failure {
+	case failureDNS, failureTimeout, failureConnectionRefused, failureUnreachable:
+		b.failures[host]++
+		b.last[host] = failure
+	default:
+		b.failures[host] = 0
+	}
+}
+
+// tripped reports whether host is given up on, and with which failure its
+// remaining links are reported.
+func (b *hostBreaker) tripped(host string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures[host] < b.threshold {
+		return "", false
+	}
+	return b.last[host], true
+}
+
+// hostKey groups a link by its lowercase host, without the port when it is
+// the scheme's default. It is empty for links that don't parse.
+func hostKey(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	host := asciiHostname(u.Hostname())
+	port := u.Port()
+	scheme := strings.ToLower(u.Scheme)
+	if port == "" || isDefaultPort(scheme, port) {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
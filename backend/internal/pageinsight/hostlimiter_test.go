@@ -0,0 +1,126 @@
+package pageinsight
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostKey(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "https://Example.COM/page", want: "example.com"},
+		{link: "https://example.com:443/page", want: "example.com"},
+		{link: "http://example.com:80/page", want: "example.com"},
+		{link: "http://example.com:8080/page", want: "example.com:8080"},
+		{link: "https://example.com:80/page", want: "example.com:80"},
+		{link: "http://[::1]:80/", want: "::1"},
+		{link: "://bad-url", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			if got := hostKey(tt.link); got != tt.want {
+				t.Errorf("hostKey(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLinks_PerHostLimit(t *testing.T) {
+	const perHost = 4
+	var inFlight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 50)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/page/%d", ts.URL, i)
+	}
+
+	opts := DefaultLinkCheckerOptions()
+	opts.PerHost = perHost
+	lc := newLinkChecker(opts, &http.Transport{})
+	report := lc.CheckLinks(context.Background(), links)
+
+	if report.Checked != len(links) {
+		t.Errorf("Checked = %d, want %d", report.Checked, len(links))
+	}
+	if got := peak.Load(); got > perHost {
+		t.Errorf("peak in-flight requests = %d, want at most %d", got, perHost)
+	}
+}
+
+func TestCheckLinks_HostBreaker(t *testing.T) {
+	var blackholeRequests atomic.Int32
+	blackhole := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		blackholeRequests.Add(1)
+		<-r.Context().Done()
+	}))
+	defer blackhole.Close()
+
+	var healthyRequests atomic.Int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		healthyRequests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+
+	var links []string
+	for i := range 8 {
+		links = append(links, fmt.Sprintf("%s/dead/%d", blackhole.URL, i), fmt.Sprintf("%s/live/%d", healthy.URL, i))
+	}
+
+	opts := DefaultLinkCheckerOptions()
+	opts.Concurrency, opts.PerHost = 2, 1
+	opts.Timeout = 50 * time.Millisecond
+	opts.HostFailureThreshold = 3
+	opts.CacheTTL = 0
+	report := newLinkChecker(opts, &http.Transport{}).CheckLinks(context.Background(), links)
+
+	if got := blackholeRequests.Load(); got != 3 {
+		t.Errorf("blackhole requests = %d, want 3", got)
+	}
+	if got := healthyRequests.Load(); got != 8 {
+		t.Errorf("healthy requests = %d, want 8", got)
+	}
+	if report.Checked != len(links) || report.Inaccessible != 8 {
+		t.Errorf("Checked, Inaccessible = %d, %d, want %d, 8", report.Checked, report.Inaccessible, len(links))
+	}
+	for _, r := range report.Results {
+		if r.Failure != "" && r.Failure != failureTimeout {
+			t.Errorf("%s Failure = %q, want %q", r.URL, r.Failure, failureTimeout)
+		}
+	}
+}
+
+func TestHostBreaker_ResetsOnAnswer(t *testing.T) {
+	b := newHostBreaker(2)
+	b.record("example.com", failureTimeout)
+	b.record("example.com", failureHTTP5xx)
+	b.record("example.com", failureTimeout)
+	if _, down := b.tripped("example.com"); down {
+		t.Error("tripped after failures that were not consecutive")
+	}
+	b.record("example.com", failureTimeout)
+	if failure, down := b.tripped("example.com"); !down || failure != failureTimeout {
+		t.Errorf("tripped() = %q, %v, want %q, true", failure, down, failureTimeout)
+	}
+}
@@ -0,0 +1,60 @@
+package pageinsight
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// maxSlowestLinks caps how many of the slowest links are reported.
+const maxSlowestLinks = 5
+
+// linkLatency summarizes how long the requested links took to check. Results
+// that sent no request, with a zero Elapsed, are left out so cached and
+// skipped links don't drag the percentiles down.
+type linkLatency struct {
+	p50, p95 time.Duration
+	slowest  []LinkResult
+}
+
+func measureLatency(results []LinkResult) linkLatency {
+	var measured []LinkResult
+	for _, r := range results {
+		if r.Elapsed > 0 {
+			measured = append(measured, r)
+		}
+	}
+	if len(measured) == 0 {
+		return linkLatency{}
+	}
+	// Slowest first; ties are broken by URL so the order is stable.
+	slices.SortFunc(measured, func(a, b LinkResult) int {
+		return cmp.Or(cmp.Compare(b.Elapsed, a.Elapsed), cmp.Compare(a.URL, b.URL))
+	})
+	return linkLatency{
+		p50:     percentile(measured, 50),
+		p95:     percentile(measured, 95),
+		slowest: measured[:min(len(measured), maxSlowestLinks)],
+	}
+}
+
+// percentile returns the nearest-rank pth percentile of results sorted
+// slowest first: the smallest duration that at least p percent of the
+// results are at or under.
+func percentile(sorted []LinkResult, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100 // ceil(p/100 * n), at least 1
+	return sorted[len(sorted)-max(rank, 1)].Elapsed
+}
+
+func slowLinks(results []LinkResult) []model.SlowLink {
+	if len(results) == 0 {
+		return nil
+	}
+	links := make([]model.SlowLink, len(results))
+	for i, r := range results {
+		links[i] = model.SlowLink{URL: r.URL, ElapsedMs: r.Elapsed.Milliseconds()}
+	}
+	return links
+}
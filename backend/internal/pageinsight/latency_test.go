@@ -0,0 +1,76 @@
+package pageinsight
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMeasureLatency(t *testing.T) {
+	// Ten requested links taking 10ms to 100ms, plus a cached and a skipped
+	// one that must not count.
+	var results []LinkResult
+	for i := 10; i >= 1; i-- {
+		results = append(results, LinkResult{
+			URL:     fmt.Sprintf("https://example.com/%d", i),
+			Elapsed: time.Duration(i*10) * time.Millisecond,
+		})
+	}
+	results = append(results,
+		LinkResult{URL: "https://example.com/cached", Cached: true},
+		LinkResult{URL: "https://down.example/", Failure: failureTimeout},
+	)
+
+	got := measureLatency(results)
+	if got.p50 != 50*time.Millisecond {
+		t.Errorf("p50 = %v, want 50ms", got.p50)
+	}
+	if got.p95 != 100*time.Millisecond {
+		t.Errorf("p95 = %v, want 100ms", got.p95)
+	}
+	var slowest []string
+	for _, r := range got.slowest {
+		slowest = append(slowest, r.URL)
+	}
+	want := []string{
+		"https://example.com/10", "https://example.com/9", "https://example.com/8",
+		"https://example.com/7", "https://example.com/6",
+	}
+	if !slices.Equal(slowest, want) {
+		t.Errorf("slowest = %v, want %v", slowest, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		elapsed []time.Duration // slowest first
+		p       int
+		want    time.Duration
+	}{
+		{elapsed: []time.Duration{7}, p: 50, want: 7},
+		{elapsed: []time.Duration{7}, p: 95, want: 7},
+		{elapsed: []time.Duration{30, 20, 10}, p: 50, want: 20},
+		{elapsed: []time.Duration{40, 30, 20, 10}, p: 50, want: 20},
+		{elapsed: []time.Duration{40, 30, 20, 10}, p: 95, want: 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("p%d of %v", tt.p, tt.elapsed), func(t *testing.T) {
+			sorted := make([]LinkResult, len(tt.elapsed))
+			for i, d := range tt.elapsed {
+				sorted[i] = LinkResult{Elapsed: d}
+			}
+			if got := percentile(sorted, tt.p); got != tt.want {
+				t.Errorf("percentile = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureLatency_NothingRequested(t *testing.T) {
+	got := measureLatency([]LinkResult{{URL: "https://example.com/", Cached: true}})
+	if got.p50 != 0 || got.p95 != 0 || got.slowest != nil {
+		t.Errorf("measureLatency = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,95 @@
+package pageinsight
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// linkCache remembers link check results across calls for a TTL, so links
+// shared by pages of one site, such as navigation and footer links, are not
+// re-checked on every analysis. It holds at most size entries and evicts the
+// least recently used one when full. A nil *linkCache caches nothing.
+type linkCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key     string
+	result  LinkResult
+	expires time.Time
+}
+
+// newLinkCache returns nil, disabling the cache, when ttl or size is not
+// positive.
+func newLinkCache(ttl time.Duration, size int) *linkCache {
+	if ttl <= 0 || size <= 0 {
+		return nil
+	}
+	return &linkCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached result for link, marked as Cached and with the URL
+// as given.
+func (c *linkCache) get(link string) (LinkResult, bool) {
+	if c == nil {
+		return LinkResult{}, false
+	}
+	key := normalizeURL(link)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return LinkResult{}, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return LinkResult{}, false
+	}
+	c.order.MoveToFront(elem)
+
+	result := entry.result
+	result.URL = link
+	result.Elapsed = 0
+	result.Cached = true
+	return result, true
+}
+
+// put stores a completed result. Rate-limited results are not stored, since
+// the server only asked us to come back later.
+func (c *linkCache) put(result LinkResult) {
+	if c == nil || result.Failure == failureRateLimited {
+		return
+	}
+	key := normalizeURL(result.URL)
+	expires := c.now().Add(c.ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &cacheEntry{key: key, result: result, expires: expires}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,111 @@
+package pageinsight
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLinkCache_TTL(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := newLinkCache(time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	c.put(LinkResult{URL: "https://example.com/a", Status: http.StatusNotFound, Failure: failureHTTP4xx})
+
+	got, ok := c.get("https://EXAMPLE.com/a#top")
+	if !ok {
+		t.Fatal("get() missed a fresh entry")
+	}
+	want := LinkResult{URL: "https://EXAMPLE.com/a#top", Status: http.StatusNotFound, Failure: failureHTTP4xx, Cached: true}
+	if got != want {
+		t.Errorf("get() = %+v, want %+v", got, want)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("https://example.com/a"); ok {
+		t.Error("get() hit an expired entry")
+	}
+}
+
+func TestLinkCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLinkCache(time.Minute, 2)
+	c.put(LinkResult{URL: "https://example.com/a"})
+	c.put(LinkResult{URL: "https://example.com/b"})
+	c.get("https://example.com/a")
+	c.put(LinkResult{URL: "https://example.com/c"})
+
+	for link, want := range map[string]bool{
+		"https://example.com/a": true,
+		"https://example.com/b": false,
+		"https://example.com/c": true,
+	} {
+		if _, ok := c.get(link); ok != want {
+			t.Errorf("get(%q) hit = %v, want %v", link, ok, want)
+		}
+	}
+}
+
+func TestLinkCache_SkipsRateLimited(t *testing.T) {
+	c := newLinkCache(time.Minute, 10)
+	c.put(LinkResult{URL: "https://example.com/a", Status: http.StatusTooManyRequests, Failure: failureRateLimited})
+	if _, ok := c.get("https://example.com/a"); ok {
+		t.Error("rate-limited result was cached")
+	}
+}
+
+func TestLinkCache_Disabled(t *testing.T) {
+	c := newLinkCache(0, 10)
+	c.put(LinkResult{URL: "https://example.com/a"})
+	if _, ok := c.get("https://example.com/a"); ok {
+		t.Error("disabled cache returned a hit")
+	}
+}
+
+func TestCheckLinks_CachesAcrossCalls(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.CacheTTL = time.Minute })
+	first := lc.CheckLinks(context.Background(), []string{ts.URL})
+	second := lc.CheckLinks(context.Background(), []string{ts.URL})
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	if first.Cached != 0 || second.Cached != 1 {
+		t.Errorf("Cached = %d then %d, want 0 then 1", first.Cached, second.Cached)
+	}
+	if second.Inaccessible != 1 {
+		t.Errorf("cached Inaccessible = %d, want 1", second.Inaccessible)
+	}
+}
+
+func TestCheckLinks_DoesNotCacheCancelledChecks(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.CacheTTL = time.Minute })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	lc.CheckLinks(ctx, []string{ts.URL})
+
+	report := lc.CheckLinks(context.Background(), []string{ts.URL})
+	if report.Cached != 0 || report.Checked != 1 || report.Inaccessible != 0 {
+		t.Errorf("report after cancelled check = %+v, want a fresh accessible check", report)
+	}
+}
@@ -3,131 +3,445 @@ package pageinsight
 import (
 	"context"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-const maxLinks = 1000
+// Link check strategies. HEAD-first falls back to GET when a server rejects
+// HEAD; get-only suits servers that answer HEAD wrongly, and head-only never
+// downloads a body.
+const (
+	StrategyHeadFirst = "head-first"
+	StrategyGetOnly   = "get-only"
+	StrategyHeadOnly  = "head-only"
+)
+
+// defaultMaxLinks is the default cap on links checked per call.
+const defaultMaxLinks = 1000
+
+// linkAccept is the Accept header of link checks. It matches what browsers
+// send for a navigation, since some servers refuse requests that accept
+// nothing in particular.
+const linkAccept = "text/html,application/xhtml+xml,*/*;q=0.8"
+
+// LinkCheckerOptions configures a LinkChecker.
+type LinkCheckerOptions struct {
+	// Concurrency is the worker pool size.
+	Concurrency int
+	// PerHost caps the requests in flight to any one host.
+	PerHost int
+	// Timeout bounds each request.
+	Timeout time.Duration
+	// MaxLinks caps the links checked per call.
+	MaxLinks int
+	// Strategy is one of the Strategy constants.
+	Strategy string
+	// MaxRedirects is how many redirects are followed, so the final status
+	// is the one reported.
+	MaxRedirects int
+	// HostFailureThreshold is how many consecutive connection failures or
+	// timeouts on one host mark its remaining links inaccessible without a
+	// request; zero disables this.
+	HostFailureThreshold int
+	// CacheTTL is how long results are cached across calls, up to CacheSize
+	// links; zero disables the cache.
+	CacheTTL  time.Duration
+	CacheSize int
+	// Overflow is one of the Overflow constants and says which links are
+	// checked when there are more than MaxLinks; empty means
+	// OverflowTruncate.
+	Overflow string
+	// UserAgent identifies the requests; empty means the default
+	// PageInsightBot/1.0.
+	UserAgent string
+	// BlockedHosts, exact hostnames or *.suffix wildcards, are skipped
+	// without a request, and so are redirects to them.
+	BlockedHosts []string
+	// Proxy, when set, is the HTTP(S) proxy every check goes through.
+	Proxy *url.URL
+}
+
+// DefaultLinkCheckerOptions returns 25 workers, 4 per host, a 2s timeout,
+// 1000 links, the HEAD-first strategy, 3 redirects, a host given up on after 5
+// failures, a 5-minute cache of 10000 links, and truncation past the link cap.
+func DefaultLinkCheckerOptions() LinkCheckerOptions {
+	return LinkCheckerOptions{
+		Concurrency:          25,
+		PerHost:              4,
+		Timeout:              2 * time.Second,
+		MaxLinks:             defaultMaxLinks,
+		Strategy:             StrategyHeadFirst,
+		MaxRedirects:         3,
+		HostFailureThreshold: 5,
+		CacheTTL:             5 * time.Minute,
+		CacheSize:            10000,
+	}
+}
+
+// LinkReport summarizes a CheckLinks run.
+type LinkReport struct {
+	Inaccessible int
+	// RateLimited counts the rate-limited links, which are not counted as
+	// inaccessible, since the server only refused to answer.
+	RateLimited int
+	// Skipped counts the links on blocked hosts.
+	Skipped int
+	// Checked counts the links whose check completed before the context was
+	// done.
+	Checked int
+	// Cached counts the results served from the cache.
+	Cached int
+	// Partial is set when the context was done before every link was
+	// checked.
+	Partial bool
+	// Sampled is set when there were more links than the cap and a random
+	// sample of them was checked.
+	Sampled bool
+	// Results holds the outcomes of the checked links in the order the links
+	// were given.
+	Results []LinkResult
+}
+
+// LinkResult is the outcome of checking one link. Status is the final HTTP
+// status, 0 when no response arrived. Failure is the category of why the link
+// is inaccessible, empty when it is accessible. Elapsed covers the HEAD
+// request and any GET fallback; it is zero when no request was sent, for
+// cached results and hosts skipped after repeated failures. Cached is set
+// when the result came from an earlier check. Redirects counts the redirects
+// followed and FinalURL is where they led, empty when there were none.
+type LinkResult struct {
+	URL       string
+	Status    int
+	Failure   string
+	Elapsed   time.Duration
+	Cached    bool
+	Redirects int
+	FinalURL  string
+
+	retryAfter time.Duration // how long a rate-limiting server asked us to wait
+}
 
 // LinkChecker validates link accessibility using a reusable HTTP client.
+// When resolver is set, the hostnames of a batch are resolved up front.
 type LinkChecker struct {
-	client      *http.Client
-	concurrency int
-}
-
-// NewLinkChecker returns a LinkChecker with a 4s timeout that does not follow
-// redirects and blocks connections to private/reserved IP ranges.
-// The concurrency parameter controls the worker pool size.
-func NewLinkChecker(concurrency int) *LinkChecker {
-	return newLinkChecker(concurrency, &http.Transport{
-		DialContext:         safeDialer().DialContext,
-		MaxConnsPerHost:     concurrency,
-		MaxIdleConnsPerHost: concurrency,
+	client   *http.Client
+	opts     LinkCheckerOptions
+	cache    *linkCache
+	resolver hostResolver
+	blocked  *hostBlocklist
+}
+
+// NewLinkChecker returns a LinkChecker configured by opts that blocks
+// connections to private/reserved IP ranges, redirect targets included.
+func NewLinkChecker(opts LinkCheckerOptions) *LinkChecker {
+	dialer := safeDialer()
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialResolved(ctx, dialer, network, address)
+		},
+		// As for page fetches, HTTP/2 must be asked for with a custom dialer.
+		ForceAttemptHTTP2:   true,
+		MaxConnsPerHost:     opts.PerHost,
+		MaxIdleConnsPerHost: opts.PerHost,
 		IdleConnTimeout:     90 * time.Second,
-	})
+	}
+	var roundTripper http.RoundTripper = transport
+	if opts.Proxy != nil {
+		// Plain-http checks all share the connections to the proxy, so a
+		// per-host cap would cap the whole check; the host limiter still
+		// spaces out requests to each target.
+		transport.MaxConnsPerHost = opts.Concurrency
+		transport.MaxIdleConnsPerHost = opts.Concurrency
+		roundTripper = proxyTransport(transport, opts.Proxy, net.DefaultResolver)
+	}
+	lc := newLinkChecker(opts, roundTripper)
+	lc.resolver = net.DefaultResolver
+	return lc
 }
 
-func newLinkChecker(concurrency int, transport http.RoundTripper) *LinkChecker {
+func newLinkChecker(opts LinkCheckerOptions, transport http.RoundTripper) *LinkChecker {
+	blocked := newHostBlocklist(opts.BlockedHosts)
 	return &LinkChecker{
-		concurrency: concurrency,
+		opts:    opts,
+		cache:   newLinkCache(opts.CacheTTL, opts.CacheSize),
+		blocked: blocked,
 		client: &http.Client{
-			Timeout:   2 * time.Second,
+			Timeout:   opts.Timeout,
 			Transport: transport,
-			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
-				return http.ErrUseLastResponse
+			// Past the limit the last redirect response is reported as is,
+			// which also ends redirect loops.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if blocked.blocks(req.URL.Hostname()) {
+					return errBlockedHost
+				}
+				if len(via) > opts.MaxRedirects {
+					return http.ErrUseLastResponse
+				}
+				scopePageHeaders(req)
+				return nil
 			},
 		},
 	}
 }
 
-// checkLink performs a HEAD request and returns true if the link is inaccessible.
-// Some servers reject HEAD but accept GET, so a 403 or 405 on HEAD triggers a
-// lightweight GET fallback to reduce false positives.
-func (lc *LinkChecker) checkLink(ctx context.Context, link string) bool {
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+// checkLink probes the link as the strategy says and reports whether it is
+// accessible, with the time the check took. A rate-limited link is retried
+// once after the delay the server asked for. Hosts seen rejecting HEAD are
+// recorded in headRejected, which may be nil.
+func (lc *LinkChecker) checkLink(ctx context.Context, link string, headRejected *hostSet) LinkResult {
+	start := time.Now()
+	result := lc.probe(ctx, link, headRejected)
+	if result.Failure == failureRateLimited && sleepContext(ctx, result.retryAfter) {
+		result = lc.probe(ctx, link, headRejected)
+	}
+	result.retryAfter = 0
+	result.Elapsed = time.Since(start)
+	return result
+}
+
+// probe skips straight to GET for hosts that already rejected HEAD, saving
+// them a round trip per link.
+func (lc *LinkChecker) probe(ctx context.Context, link string, headRejected *hostSet) LinkResult {
+	if lc.opts.Strategy == StrategyGetOnly {
+		return lc.getProbe(ctx, link)
+	}
+	host := hostKey(link)
+	if lc.opts.Strategy == StrategyHeadFirst && headRejected.has(host) {
+		return lc.getProbe(ctx, link)
+	}
+	return lc.headProbe(ctx, link, host, headRejected)
+}
+
+// headProbe sends a HEAD request. Some servers reject HEAD but accept GET, so
+// under the HEAD-first strategy a 403 or 405 triggers a lightweight GET
+// fallback to reduce false positives, and the host is remembered.
+func (lc *LinkChecker) headProbe(ctx context.Context, link, host string, headRejected *hostSet) LinkResult {
+	req, err := newProbeRequest(ctx, http.MethodHead, link)
 	if err != nil {
-		return true // malformed URL is inaccessible
+		return LinkResult{URL: link, Failure: failureInvalidURL}
 	}
-	req.Header.Set("User-Agent", userAgent)
+	setRequestHeaders(req, lc.opts.UserAgent, linkAccept)
 
 	resp, err := lc.client.Do(req)
 	if err != nil {
-		return ctx.Err() == nil // inaccessible only if context wasn't cancelled
+		return requestFailed(ctx, link, err)
 	}
 	_ = resp.Body.Close()
 
-	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusMethodNotAllowed {
+	rejected := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusMethodNotAllowed
+	if rejected && lc.opts.Strategy != StrategyHeadOnly {
+		headRejected.add(host)
 		return lc.getProbe(ctx, link)
 	}
 
-	return resp.StatusCode >= 400
+	return responseResult(link, resp)
 }
 
-// getProbe sends a minimal-body GET as a fallback when HEAD is rejected.
-func (lc *LinkChecker) getProbe(ctx context.Context, link string) bool {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+// getProbe sends a minimal-body GET.
+func (lc *LinkChecker) getProbe(ctx context.Context, link string) LinkResult {
+	req, err := newProbeRequest(ctx, http.MethodGet, link)
 	if err != nil {
-		return true
+		return LinkResult{URL: link, Failure: failureInvalidURL}
 	}
-	req.Header.Set("User-Agent", userAgent)
+	setRequestHeaders(req, lc.opts.UserAgent, linkAccept)
 	req.Header.Set("Range", "bytes=0-0")
 
 	resp, err := lc.client.Do(req)
 	if err != nil {
-		return ctx.Err() == nil
+		return requestFailed(ctx, link, err)
+	}
+	drainProbe(resp)
+
+	return responseResult(link, resp)
+}
+
+// newProbeRequest builds a link check request. Credentials in the link are
+// dropped rather than sent as basic auth to whatever host the page links to.
+func newProbeRequest(ctx context.Context, method, link string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.User = nil
+	return req, nil
+}
+
+// maxProbeDrain caps how much of a GET probe's body is read. Many servers
+// ignore Range and send the whole resource.
+const maxProbeDrain = 4 << 10 // 4 KB
+
+// drainProbe reads and discards at most maxProbeDrain bytes before closing
+// the body, so a short body is consumed and its connection reused instead of
+// being cut off mid-response. A body declared larger than that is closed
+// without reading any of it.
+func drainProbe(resp *http.Response) {
+	if resp.ContentLength <= maxProbeDrain {
+		_, _ = io.CopyN(io.Discard, resp.Body, maxProbeDrain)
 	}
-	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
 	_ = resp.Body.Close()
+}
 
-	return resp.StatusCode >= 400
+// linkRun is the state shared by the workers of one CheckLinksDetailed call.
+type linkRun struct {
+	hosts        *hostLimiter
+	headRejected *hostSet
+	breaker      *hostBreaker
+	resolved     resolvedHosts
 }
 
-// CheckLinks validates a list of URLs concurrently using a pool
-// of worker goroutines sized by the configured concurrency and returns the
-// count of inaccessible links. Processes at most 1000 links.
-func (lc *LinkChecker) CheckLinks(ctx context.Context, links []string) int {
-	limit := min(len(links), maxLinks)
-	links = links[:limit]
+// runCheck checks one link within a run, answering from the cache when it
+// can and without a request when the link's host is blocked, its hostname
+// does not exist or the host is known to be down. checked is false when the
+// context ended before the link was evaluated.
+func (lc *LinkChecker) runCheck(ctx context.Context, run *linkRun, link string) (LinkResult, bool) {
+	if lc.blocked.blocksLink(link) {
+		return LinkResult{URL: link, Failure: failureBlockedHost}, true
+	}
+	cacheable := !sendsPageHeaders(ctx, link)
+	if result, ok := lc.cache.get(link); ok && cacheable {
+		return result, true
+	}
+	if err := run.resolved.notFound(link); err != nil {
+		result := LinkResult{URL: link, Failure: failureCategory(err)}
+		lc.cache.put(result)
+		return result, true
+	}
+	host := hostKey(link)
+	if !run.hosts.acquire(ctx, host) {
+		return LinkResult{}, false
+	}
+	defer run.hosts.release(host)
 
-	if limit == 0 {
-		return 0
+	if failure, down := run.breaker.tripped(host); down {
+		return LinkResult{URL: link, Failure: failure}, true
+	}
+	result := lc.checkLink(ctx, link, run.headRejected)
+	if ctx.Err() != nil {
+		return result, false
+	}
+	run.breaker.record(host, result.Failure)
+	if cacheable {
+		lc.cache.put(result)
 	}
+	return result, true
+}
 
-	jobs := make(chan string, limit)
-	results := make(chan bool, limit)
+// samples reports whether links are too many to check them all and a sample
+// of them is checked instead.
+func (lc *LinkChecker) samples(links []string) bool {
+	return lc.opts.Overflow == OverflowSample && len(links) > lc.opts.MaxLinks
+}
 
-	numWorkers := min(limit, lc.concurrency)
+// toResolve lists the links that will be requested, neither blocked nor
+// cached, whose hosts need resolving.
+func (lc *LinkChecker) toResolve(links []string) []string {
+	if lc.cache == nil && lc.blocked == nil {
+		return links
+	}
+	var pending []string
+	for _, link := range links {
+		if lc.blocked.blocksLink(link) {
+			continue
+		}
+		if _, ok := lc.cache.get(link); !ok {
+			pending = append(pending, link)
+		}
+	}
+	return pending
+}
 
+// CheckLinks validates a list of URLs like CheckLinksDetailed and summarizes
+// the outcome.
+func (lc *LinkChecker) CheckLinks(ctx context.Context, links []string) LinkReport {
+	results := lc.CheckLinksDetailed(ctx, links)
+	report := LinkReport{
+		Checked: len(results),
+		Partial: len(results) < min(len(links), lc.opts.MaxLinks),
+		Sampled: lc.samples(links),
+		Results: results,
+	}
+	for _, r := range results {
+		if r.Cached {
+			report.Cached++
+		}
+		switch {
+		case r.Failure == failureRateLimited:
+			report.RateLimited++
+		case r.Failure == failureBlockedHost:
+			report.Skipped++
+		case r.inaccessible():
+			report.Inaccessible++
+		}
+	}
+	return report
+}
+
+// CheckLinksDetailed validates a list of URLs concurrently using a pool of
+// Concurrency worker goroutines, with at most PerHost of them requesting the
+// same host at once, and returns the result of each link whose check
+// completed before the context was done, in the order given. Processes at
+// most MaxLinks links, the first ones or a random sample of them as Overflow
+// says. Once the context is done the workers stop taking links. Results of
+// completed checks are cached; those cut short by the context never are.
+func (lc *LinkChecker) CheckLinksDetailed(ctx context.Context, links []string) []LinkResult {
+	limit := min(len(links), lc.opts.MaxLinks)
+	tracker := linkProgressFrom(ctx)
+	tracker.skip(len(links) - limit)
+	if lc.samples(links) {
+		links = sampleLinks(ctx, links, limit)
+	} else {
+		links = links[:limit]
+	}
+
+	if limit == 0 {
+		return nil
+	}
+
+	run := &linkRun{
+		hosts:        newHostLimiter(lc.opts.PerHost),
+		headRejected: &hostSet{},
+		breaker:      newHostBreaker(lc.opts.HostFailureThreshold),
+	}
+	if lc.resolver != nil {
+		run.resolved = resolveHosts(ctx, lc.resolver, lc.toResolve(links), lc.opts.Concurrency, lc.opts.Timeout)
+		ctx = withResolvedHosts(ctx, run.resolved)
+	}
+
+	// Workers claim the next link from a shared counter and write its result
+	// into its own slot, so nothing is buffered beyond the results themselves.
+	results := make([]LinkResult, limit)
+	checked := make([]bool, limit)
+	var next atomic.Int64
 	var wg sync.WaitGroup
-	for range numWorkers {
+	for range min(limit, lc.opts.Concurrency) {
 		wg.Go(func() {
-			for link := range jobs {
-				if ctx.Err() != nil {
-					results <- false
-					continue
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= limit {
+					return
+				}
+				results[i], checked[i] = lc.runCheck(ctx, run, links[i])
+				if checked[i] {
+					tracker.done()
 				}
-				results <- lc.checkLink(ctx, link)
 			}
 		})
 	}
+	wg.Wait()
 
-	for _, link := range links {
-		jobs <- link
-	}
-	close(jobs)
-
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	var inaccessible int
-	for bad := range results {
-		if bad {
-			inaccessible++
+	// Compact the completed checks in place, keeping their order.
+	completed := results[:0]
+	for i, result := range results {
+		if checked[i] {
+			completed = append(completed, result)
 		}
 	}
-
-	return inaccessible
+	if len(completed) == 0 {
+		return nil
+	}
+	return completed
 }
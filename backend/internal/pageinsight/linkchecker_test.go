@@ -3,19 +3,39 @@ package pageinsight
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
 )
 
 // testLinkChecker returns a LinkChecker with a default transport (no SSRF
 // blocking) so tests can reach httptest servers on localhost.
 func testLinkChecker(concurrency int) *LinkChecker {
-	return newLinkChecker(concurrency, &http.Transport{
-		MaxConnsPerHost:     concurrency,
-		MaxIdleConnsPerHost: concurrency,
+	return testLinkCheckerWith(func(o *LinkCheckerOptions) {
+		o.Concurrency = concurrency
+		o.PerHost = concurrency
+	})
+}
+
+// testLinkCheckerWith is testLinkChecker with the default options adjusted by
+// configure. The cache is off unless configure turns it on, so every check
+// reaches the test server.
+func testLinkCheckerWith(configure func(*LinkCheckerOptions)) *LinkChecker {
+	opts := DefaultLinkCheckerOptions()
+	opts.CacheTTL = 0
+	configure(&opts)
+	return newLinkChecker(opts, &http.Transport{
+		MaxConnsPerHost:     opts.PerHost,
+		MaxIdleConnsPerHost: opts.PerHost,
 		IdleConnTimeout:     90 * time.Second,
 	})
 }
@@ -120,7 +140,7 @@ func TestCheckLinks(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			count := testLinkChecker(10).CheckLinks(context.Background(), tt.links)
+			count := testLinkChecker(10).CheckLinks(context.Background(), tt.links).Inaccessible
 			if count != tt.expected {
 				t.Errorf("inaccessible = %d, want %d", count, tt.expected)
 			}
@@ -128,6 +148,102 @@ func TestCheckLinks(t *testing.T) {
 	}
 }
 
+func TestCheckLinks_Results(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	links := []string{ts.URL + "/ok", ts.URL + "/gone", "://bad-url", ts.URL + "/broken"}
+	report := testLinkChecker(4).CheckLinks(context.Background(), links)
+
+	want := []LinkResult{
+		{URL: ts.URL + "/ok", Status: http.StatusOK},
+		{URL: ts.URL + "/gone", Status: http.StatusGone, Failure: failureHTTP4xx},
+		{URL: "://bad-url", Failure: failureInvalidURL},
+		{URL: ts.URL + "/broken", Status: http.StatusBadGateway, Failure: failureHTTP5xx},
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("Results = %+v, want %+v", report.Results, want)
+	}
+	for i, w := range want {
+		got := report.Results[i]
+		got.Elapsed = 0
+		if got != w {
+			t.Errorf("Results[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+	if report.Inaccessible != 3 {
+		t.Errorf("Inaccessible = %d, want 3", report.Inaccessible)
+	}
+}
+
+func TestCheckLinksDetailed_FailureCategories(t *testing.T) {
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer tlsServer.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	tests := []struct {
+		name string
+		lc   *LinkChecker
+		link string
+		want string
+	}{
+		{name: "404", lc: testLinkChecker(1), link: notFound.URL, want: failureHTTP4xx},
+		{name: "dns failure", lc: testLinkChecker(1), link: "http://no-such-host.invalid/", want: failureDNS},
+		{name: "connection refused", lc: testLinkChecker(1), link: closedURL, want: failureConnectionRefused},
+		{name: "untrusted certificate", lc: testLinkChecker(1), link: tlsServer.URL, want: failureTLS},
+		{name: "timeout", lc: timeoutLinkChecker(50 * time.Millisecond), link: slow.URL, want: failureTimeout},
+		{name: "private address", lc: NewLinkChecker(DefaultLinkCheckerOptions()), link: notFound.URL, want: failureBlockedPrivateIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := tt.lc.CheckLinksDetailed(context.Background(), []string{tt.link})
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			if results[0].Failure != tt.want {
+				t.Errorf("Failure = %q, want %q", results[0].Failure, tt.want)
+			}
+			if results[0].Elapsed <= 0 {
+				t.Errorf("Elapsed = %v, want > 0", results[0].Elapsed)
+			}
+		})
+	}
+}
+
+func timeoutLinkChecker(timeout time.Duration) *LinkChecker {
+	return testLinkCheckerWith(func(o *LinkCheckerOptions) { o.Timeout = timeout })
+}
+
 func TestCheckLinks_MaxLinksLimit(t *testing.T) {
 	var called int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -148,6 +264,275 @@ func TestCheckLinks_MaxLinksLimit(t *testing.T) {
 	}
 }
 
+func TestCheckLinks_ConfiguredMaxLinks(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 20)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/page/%d", ts.URL, i)
+	}
+
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.MaxLinks = 5 })
+	if got := lc.CheckLinks(context.Background(), links).Checked; got != 5 {
+		t.Errorf("Checked = %d, want 5", got)
+	}
+}
+
+func TestCheckLinks_Overflow(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 50)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/page/%d", ts.URL, i)
+	}
+
+	tests := []struct {
+		overflow    string
+		wantSampled bool
+	}{
+		{overflow: OverflowTruncate, wantSampled: false},
+		{overflow: OverflowSample, wantSampled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overflow, func(t *testing.T) {
+			lc := testLinkCheckerWith(func(o *LinkCheckerOptions) {
+				o.MaxLinks = 10
+				o.Overflow = tt.overflow
+			})
+			report := lc.CheckLinks(context.Background(), links)
+
+			if report.Checked != 10 || report.Sampled != tt.wantSampled || report.Partial {
+				t.Errorf("Checked, Sampled, Partial = %d, %v, %v, want 10, %v, false",
+					report.Checked, report.Sampled, report.Partial, tt.wantSampled)
+			}
+			var got []string
+			for _, r := range report.Results {
+				got = append(got, r.URL)
+			}
+			if truncated := slices.Equal(got, links[:10]); truncated == tt.wantSampled {
+				t.Errorf("checked %v, want the first 10 links only when truncating", got)
+			}
+		})
+	}
+}
+
+// countingBody is an endless response body that counts the bytes read from
+// it.
+type countingBody struct{ read int64 }
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+func (b *countingBody) Close() error { return nil }
+
+// bodyTransport answers every request with 200 and body, declaring
+// contentLength.
+type bodyTransport struct {
+	body          io.ReadCloser
+	contentLength int64
+}
+
+func (t bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		ContentLength: t.contentLength,
+		Body:          t.body,
+		Request:       req,
+	}, nil
+}
+
+func TestGetProbe_LimitsBodyRead(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength int64
+		wantMax       int64
+	}{
+		{name: "declared 50MB is not read", contentLength: 50 << 20, wantMax: 0},
+		{name: "unknown length is capped", contentLength: -1, wantMax: maxProbeDrain},
+		{name: "short body is read", contentLength: 100, wantMax: maxProbeDrain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &countingBody{}
+			opts := DefaultLinkCheckerOptions()
+			opts.Strategy = StrategyGetOnly
+			lc := newLinkChecker(opts, bodyTransport{body: body, contentLength: tt.contentLength})
+
+			result := lc.getProbe(context.Background(), "https://example.com/large.bin")
+
+			if result.Status != http.StatusOK {
+				t.Errorf("Status = %d, want 200", result.Status)
+			}
+			if body.read > tt.wantMax {
+				t.Errorf("read %d bytes, want at most %d", body.read, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestCheckLinks_RequestHeaders(t *testing.T) {
+	type seen struct{ method, ua, accept, requestID string }
+	var mu sync.Mutex
+	var requests []seen
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, seen{r.Method, r.Header.Get("User-Agent"), r.Header.Get("Accept"), r.Header.Get("X-Request-ID")})
+		mu.Unlock()
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	const ua = "PageInsightBot/1.0 (+https://example.org/bot)"
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.UserAgent = ua })
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	lc.CheckLinks(ctx, []string{ts.URL + "/page"})
+
+	want := []seen{
+		{http.MethodHead, ua, linkAccept, "req-42"},
+		{http.MethodGet, ua, linkAccept, "req-42"},
+	}
+	if !slices.Equal(requests, want) {
+		t.Errorf("requests = %+v, want %+v", requests, want)
+	}
+}
+
+func TestCheckLinks_DropsCredentials(t *testing.T) {
+	var authorization []string
+	var mu sync.Mutex
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	link := strings.Replace(ts.URL, "http://", "http://user:secret@", 1) + "/admin"
+	report := testLinkChecker(1).CheckLinks(context.Background(), []string{link})
+
+	if report.Inaccessible != 0 || len(authorization) == 0 {
+		t.Fatalf("report = %+v, want the link checked and accessible", report)
+	}
+	for _, auth := range authorization {
+		if auth != "" {
+			t.Errorf("Authorization = %q, want no credentials sent", auth)
+		}
+	}
+}
+
+func TestCheckLinks_IPv6Literal(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	_ = ts.Listener.Close()
+	ts.Listener = ln
+	ts.Start()
+	defer ts.Close()
+
+	// The test checker reaches loopback; the safe dialer must reject the same
+	// bracketed address.
+	report := testLinkChecker(1).CheckLinks(context.Background(), []string{ts.URL + "/path"})
+	if report.Inaccessible != 0 || report.Checked != 1 {
+		t.Errorf("report = %+v, want the IPv6 link reached", report)
+	}
+	blocked := NewLinkChecker(DefaultLinkCheckerOptions()).CheckLinksDetailed(context.Background(), []string{ts.URL + "/path"})
+	if len(blocked) != 1 || blocked[0].Failure != failureBlockedPrivateIP {
+		t.Errorf("safe checker results = %+v, want blocked-private-ip", blocked)
+	}
+}
+
+func TestCheckLinks_BlockedHosts(t *testing.T) {
+	var requests atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/to-blocked" {
+			http.Redirect(w, r, "http://admin.blocked.example/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.BlockedHosts = []string{"*.blocked.example"} })
+	report := lc.CheckLinks(context.Background(), []string{
+		"http://admin.blocked.example/login",
+		ts.URL + "/ok",
+		ts.URL + "/to-blocked",
+	})
+
+	if report.Skipped != 2 || report.Inaccessible != 0 || report.Checked != 3 {
+		t.Errorf("Skipped, Inaccessible, Checked = %d, %d, %d, want 2, 0, 3",
+			report.Skipped, report.Inaccessible, report.Checked)
+	}
+	for _, i := range []int{0, 2} {
+		if got := report.Results[i].Failure; got != failureBlockedHost {
+			t.Errorf("%s: Failure = %q, want %q", report.Results[i].URL, got, failureBlockedHost)
+		}
+	}
+	// The redirect is refused before it is followed, so only the test server
+	// is ever requested.
+	if got := requests.Load(); got != 2 {
+		t.Errorf("server saw %d requests, want 2", got)
+	}
+}
+
+func TestCheckLinks_Strategy(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		wantMethods []string
+		wantFailure string
+	}{
+		{strategy: StrategyHeadFirst, wantMethods: []string{http.MethodHead, http.MethodGet}},
+		{strategy: StrategyGetOnly, wantMethods: []string{http.MethodGet}},
+		{strategy: StrategyHeadOnly, wantMethods: []string{http.MethodHead}, wantFailure: failureHTTP4xx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			var mu sync.Mutex
+			var methods []string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				methods = append(methods, r.Method)
+				mu.Unlock()
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer ts.Close()
+
+			lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.Strategy = tt.strategy })
+			results := lc.CheckLinksDetailed(context.Background(), []string{ts.URL})
+
+			if !slices.Equal(methods, tt.wantMethods) {
+				t.Errorf("methods = %v, want %v", methods, tt.wantMethods)
+			}
+			if results[0].Failure != tt.wantFailure {
+				t.Errorf("Failure = %q, want %q", results[0].Failure, tt.wantFailure)
+			}
+		})
+	}
+}
+
 func TestCheckLinks_RespectsContextCancellation(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -159,7 +544,57 @@ func TestCheckLinks_RespectsContextCancellation(t *testing.T) {
 
 	links := []string{ts.URL + "/ok", ts.URL + "/ok"}
 
-	_ = testLinkChecker(10).CheckLinks(ctx, links)
+	report := testLinkChecker(10).CheckLinks(ctx, links)
+	if report.Checked != 0 || report.Inaccessible != 0 || !report.Partial {
+		t.Errorf("report = %+v, want a partial report with nothing checked", report)
+	}
+}
+
+func TestCheckLinks_StopsDispatchingOnCancellation(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(20 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 100)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/page/%d", ts.URL, i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := testLinkChecker(2).CheckLinks(ctx, links)
+
+	if !report.Partial || report.Checked >= len(links) {
+		t.Errorf("report = {Checked: %d, Partial: %v}, want a partial report", report.Checked, report.Partial)
+	}
+	// Two workers at 20ms per link get through a handful of links in 50ms;
+	// the remaining links must not be requested at all.
+	if got := requests.Load(); got > 10 {
+		t.Errorf("requests = %d, want the remaining links skipped", got)
+	}
+}
+
+func TestCheckLinks_NotPartialWhenComplete(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 20)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/page/%d", ts.URL, i)
+	}
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) { o.MaxLinks = 5 })
+	if report := lc.CheckLinks(context.Background(), links); report.Partial {
+		t.Errorf("Partial = true for a run capped at MaxLinks, want false")
+	}
 }
 
 func TestCheckLinks_BlocksPrivateIPs(t *testing.T) {
@@ -170,8 +605,8 @@ func TestCheckLinks_BlocksPrivateIPs(t *testing.T) {
 	defer ts.Close()
 
 	// Use the real constructor which includes the safe dialer.
-	lc := NewLinkChecker(10)
-	count := lc.CheckLinks(context.Background(), []string{ts.URL + "/ok"})
+	lc := NewLinkChecker(DefaultLinkCheckerOptions())
+	count := lc.CheckLinks(context.Background(), []string{ts.URL + "/ok"}).Inaccessible
 
 	// The request to localhost should fail (blocked by safe dialer),
 	// which makes the link appear inaccessible.
@@ -181,8 +616,8 @@ func TestCheckLinks_BlocksPrivateIPs(t *testing.T) {
 }
 
 func TestCheckLink_ContextCancelledDuringRequest(t *testing.T) {
-	// When context is cancelled and client.Do fails, checkLink should return
-	// false (not count as inaccessible) because the failure was due to cancellation.
+	// When context is cancelled and client.Do fails, checkLink should not
+	// report a failure because it was due to cancellation.
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -192,9 +627,46 @@ func TestCheckLink_ContextCancelledDuringRequest(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
-	result := lc.checkLink(ctx, ts.URL+"/ok")
-	if result {
-		t.Error("expected false (not inaccessible) when context is cancelled")
+	result := lc.checkLink(ctx, ts.URL+"/ok", nil)
+	if result.Failure != "" {
+		t.Errorf("Failure = %q, want none when context is cancelled", result.Failure)
+	}
+}
+
+func TestCheckLinks_RemembersHostsRejectingHEAD(t *testing.T) {
+	var heads, gets atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		gets.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	links := make([]string, 20)
+	for i := range links {
+		links[i] = fmt.Sprintf("%s/asset/%d", ts.URL, i)
+	}
+
+	lc := testLinkChecker(1)
+	report := lc.CheckLinks(context.Background(), links)
+	if report.Inaccessible != 0 {
+		t.Errorf("Inaccessible = %d, want 0", report.Inaccessible)
+	}
+	if got := heads.Load(); got != 1 {
+		t.Errorf("HEAD requests = %d, want 1", got)
+	}
+	if got := gets.Load(); got != int32(len(links)) {
+		t.Errorf("GET requests = %d, want %d", got, len(links))
+	}
+
+	// The rejecting host is only remembered for one call.
+	lc.CheckLinks(context.Background(), links[:1])
+	if got := heads.Load(); got != 2 {
+		t.Errorf("HEAD requests after a second call = %d, want 2", got)
 	}
 }
 
@@ -210,12 +682,35 @@ func TestGetProbe_GETFallbackFails(t *testing.T) {
 	defer ts.Close()
 
 	lc := testLinkChecker(1)
-	count := lc.CheckLinks(context.Background(), []string{ts.URL + "/page"})
+	count := lc.CheckLinks(context.Background(), []string{ts.URL + "/page"}).Inaccessible
 	if count != 1 {
 		t.Errorf("inaccessible = %d, want 1", count)
 	}
 }
 
+func TestGetProbe_RequestsFirstByteOnly(t *testing.T) {
+	// Media files can be huge; the GET fallback must not download them.
+	var gotRange atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		gotRange.Store(r.Header.Get("Range"))
+		w.WriteHeader(http.StatusPartialContent)
+	}))
+	defer ts.Close()
+
+	lc := testLinkChecker(1)
+	count := lc.CheckLinks(context.Background(), []string{ts.URL + "/video.mp4"}).Inaccessible
+	if count != 0 {
+		t.Errorf("inaccessible = %d, want 0", count)
+	}
+	if got, _ := gotRange.Load().(string); got != "bytes=0-0" {
+		t.Errorf("Range = %q, want %q", got, "bytes=0-0")
+	}
+}
+
 func TestGetProbe_ContextCancelled(t *testing.T) {
 	// Server returns 405 on HEAD, triggering getProbe. But context is
 	// cancelled before the GET, so it should not count as inaccessible.
@@ -235,8 +730,8 @@ func TestGetProbe_ContextCancelled(t *testing.T) {
 	// We need to test getProbe with cancelled context.
 	cancel()
 	result := lc.getProbe(ctx, ts.URL+"/page")
-	if result {
-		t.Error("expected false (not inaccessible) when context is cancelled during getProbe")
+	if result.Failure != "" {
+		t.Errorf("Failure = %q, want none when context is cancelled during getProbe", result.Failure)
 	}
 }
 
@@ -264,3 +759,155 @@ func BenchmarkCheckLinksLatency(b *testing.B) {
 		})
 	}
 }
+
+func TestCheckLinks_RateLimited(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int // served in order, the last one repeating
+		wantRequests     int32
+		wantFailure      string
+		wantInaccessible int
+		wantRateLimited  int
+	}{
+		{name: "429 then ok", statuses: []int{429, 200}, wantRequests: 2},
+		{name: "999 then ok", statuses: []int{999, 200}, wantRequests: 2},
+		{name: "still 429", statuses: []int{429}, wantRequests: 2, wantFailure: failureRateLimited, wantRateLimited: 1},
+		{name: "429 then 404", statuses: []int{429, 404}, wantRequests: 2, wantFailure: failureHTTP4xx, wantInaccessible: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := int(requests.Add(1))
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer ts.Close()
+
+			report := testLinkChecker(1).CheckLinks(context.Background(), []string{ts.URL})
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if got := report.Results[0].Failure; got != tt.wantFailure {
+				t.Errorf("Failure = %q, want %q", got, tt.wantFailure)
+			}
+			if report.Inaccessible != tt.wantInaccessible || report.RateLimited != tt.wantRateLimited {
+				t.Errorf("Inaccessible, RateLimited = %d, %d, want %d, %d",
+					report.Inaccessible, report.RateLimited, tt.wantInaccessible, tt.wantRateLimited)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "1", want: time.Second},
+		{value: "0", want: 0},
+		{value: "30", want: maxRetryAfter},
+		{value: "-5", want: 0},
+		{value: "", want: defaultRetryAfter},
+		{value: "soon", want: defaultRetryAfter},
+		{value: now.Add(time.Second).Format(http.TimeFormat), want: time.Second},
+		{value: now.Add(time.Hour).Format(http.TimeFormat), want: maxRetryAfter},
+		{value: now.Add(-time.Hour).Format(http.TimeFormat), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLinks_FollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	redirect := func(path, to string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, http.StatusMovedPermanently)
+		})
+	}
+	redirect("/one", "/ok")
+	redirect("/two", "/one")
+	redirect("/to-gone", "/gone")
+	redirect("/loop-a", "/loop-b")
+	redirect("/loop-b", "/loop-a")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tests := []struct {
+		path          string
+		wantStatus    int
+		wantFailure   string
+		wantRedirects int
+		wantFinal     string
+	}{
+		{path: "/ok", wantStatus: http.StatusOK},
+		{path: "/two", wantStatus: http.StatusOK, wantRedirects: 2, wantFinal: "/ok"},
+		{path: "/to-gone", wantStatus: http.StatusNotFound, wantFailure: failureHTTP4xx, wantRedirects: 1, wantFinal: "/gone"},
+		{path: "/loop-a", wantStatus: http.StatusMovedPermanently, wantRedirects: 3, wantFinal: "/loop-b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			results := testLinkChecker(1).CheckLinksDetailed(context.Background(), []string{ts.URL + tt.path})
+			got := results[0]
+			want := LinkResult{URL: ts.URL + tt.path, Status: tt.wantStatus, Failure: tt.wantFailure, Redirects: tt.wantRedirects}
+			if tt.wantFinal != "" {
+				want.FinalURL = ts.URL + tt.wantFinal
+			}
+			got.Elapsed = 0
+			if got != want {
+				t.Errorf("result = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// publicRedirectTransport answers requests for public.example with a
+// redirect to target and sends everything else to next.
+type publicRedirectTransport struct {
+	target string
+	next   http.RoundTripper
+}
+
+func (t publicRedirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != "public.example" {
+		return t.next.RoundTrip(req)
+	}
+	return &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": {t.target}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func TestCheckLinks_BlocksRedirectsToPrivateIPs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	lc := newLinkChecker(DefaultLinkCheckerOptions(), publicRedirectTransport{
+		target: ts.URL,
+		next:   &http.Transport{DialContext: safeDialer().DialContext},
+	})
+	results := lc.CheckLinksDetailed(context.Background(), []string{"http://public.example/"})
+	if results[0].Failure != failureBlockedPrivateIP {
+		t.Errorf("Failure = %q, want %q", results[0].Failure, failureBlockedPrivateIP)
+	}
+}
@@ -0,0 +1,184 @@
+package pageinsight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Failure categories reported in LinkResult.Failure.
+const (
+	failureInvalidURL        = "invalid-url"
+	failureDNS               = "dns"
+	failureTimeout           = "timeout"
+	failureTLS               = "tls"
+	failureConnectionRefused = "connection-refused"
+	failureBlockedPrivateIP  = "blocked-private-ip"
+	failureBlockedHost       = "blocked-host"
+	failureUnreachable       = "unreachable"
+	failureRateLimited       = "rate-limited"
+	failureHTTP4xx           = "http-4xx"
+	failureHTTP5xx           = "http-5xx"
+)
+
+// statusLinkedInDenied is the non-standard status LinkedIn answers automated
+// requests with; like 429 it means the request was refused, not that the page
+// is missing.
+const statusLinkedInDenied = 999
+
+// Delays before retrying a rate-limited link. The wait is capped well below
+// the client timeout so one slow host can't stall the check.
+const (
+	defaultRetryAfter = time.Second
+	maxRetryAfter     = 2 * time.Second
+)
+
+// StatusResult is the result of a link that answered with status.
+func StatusResult(link string, status int) LinkResult {
+	result := LinkResult{URL: link, Status: status}
+	switch {
+	case status == http.StatusTooManyRequests || status == statusLinkedInDenied:
+		result.Failure = failureRateLimited
+	case status >= 500:
+		result.Failure = failureHTTP5xx
+	case status >= 400:
+		result.Failure = failureHTTP4xx
+	}
+	return result
+}
+
+// inaccessible reports whether the result counts as a broken link. A
+// rate-limited link is not one: the server only refused to answer. Neither is
+// a link on a blocked host, which was skipped without a request.
+func (r LinkResult) inaccessible() bool {
+	return r.Failure != "" && r.Failure != failureRateLimited && r.Failure != failureBlockedHost
+}
+
+// responseResult classifies a response, keeping the Retry-After delay of a
+// rate-limited one and where any redirects led.
+func responseResult(link string, resp *http.Response) LinkResult {
+	result := StatusResult(link, resp.StatusCode)
+	if result.Failure == failureRateLimited {
+		result.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	// Each request made by following a redirect links back to the response
+	// that caused it.
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		result.Redirects++
+	}
+	if result.Redirects > 0 {
+		result.FinalURL = resp.Request.URL.String()
+	}
+	return result
+}
+
+// parseRetryAfter reads a Retry-After header given as seconds or an HTTP date,
+// capped at maxRetryAfter. A missing or malformed header means
+// defaultRetryAfter.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	var delay time.Duration
+	if secs, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(max(secs, 0)) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		delay = max(at.Sub(now), 0)
+	} else {
+		return defaultRetryAfter
+	}
+	return min(delay, maxRetryAfter)
+}
+
+// sleepContext waits for d, returning false if the context is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// requestFailed is the result of a request that got no response. It is only
+// a failure if the context wasn't cancelled.
+func requestFailed(ctx context.Context, link string, err error) LinkResult {
+	if ctx.Err() != nil {
+		return LinkResult{URL: link}
+	}
+	return LinkResult{URL: link, Failure: failureCategory(err)}
+}
+
+// failureCategory classifies why a request got no response.
+func failureCategory(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		return failureBlockedPrivateIP
+	case errors.Is(err, errBlockedHost):
+		return failureBlockedHost
+	case errors.As(err, &dnsErr):
+		return failureDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return failureConnectionRefused
+	case tlsProblem(err) != "":
+		return failureTLS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return failureTimeout
+	default:
+		return failureUnreachable
+	}
+}
+
+// tlsProblem describes a TLS or certificate error for users, or returns ""
+// when err is not one.
+func tlsProblem(err error) string {
+	var invalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	switch {
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return "its TLS certificate has expired or is not yet valid"
+	case errors.As(err, &hostnameErr):
+		return "its TLS certificate does not match the host name"
+	case errors.As(err, &authorityErr):
+		return "its TLS certificate is not signed by a trusted authority"
+	case errors.As(err, &invalidErr), errors.As(err, &certErr):
+		return "its TLS certificate is invalid"
+	case errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return "the TLS handshake failed"
+	default:
+		return ""
+	}
+}
+
+// unreachableMessage tells the user why the page itself could not be
+// fetched, as precisely as the error allows.
+func unreachableMessage(err error) string {
+	if errors.Is(err, errBadEncoding) || errors.Is(err, errUnknownEncoding) {
+		return "The provided URL sent a compressed response that could not be decoded."
+	}
+	switch failureCategory(err) {
+	case failureTLS:
+		return "The provided URL could not be reached securely: " + tlsProblem(err) + "."
+	case failureDNS:
+		return "The provided URL's host name could not be resolved. Check the address."
+	case failureConnectionRefused:
+		return "The provided URL refused the connection."
+	case failureTimeout:
+		return "The provided URL did not respond in time."
+	default:
+		return "The provided URL could not be reached. Check the address."
+	}
+}
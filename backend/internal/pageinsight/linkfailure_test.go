@@ -0,0 +1,83 @@
+package pageinsight
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+var errSomethingElse = errors.New("something else")
+
+func TestFailureCategoryAndMessage(t *testing.T) {
+	// wrap mimics how net/http reports a failed request.
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://example.com", Err: err}
+	}
+
+	tests := []struct {
+		name         string
+		err          error
+		wantCategory string
+		wantMessage  string
+	}{
+		{
+			name:         "expired certificate",
+			err:          wrap(&tls.CertificateVerificationError{Err: x509.CertificateInvalidError{Reason: x509.Expired}}),
+			wantCategory: failureTLS,
+			wantMessage:  "certificate has expired",
+		},
+		{
+			name:         "hostname mismatch",
+			err:          wrap(&tls.CertificateVerificationError{Err: x509.HostnameError{Host: "example.com"}}),
+			wantCategory: failureTLS,
+			wantMessage:  "does not match the host name",
+		},
+		{
+			name:         "unknown authority",
+			err:          wrap(&tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}),
+			wantCategory: failureTLS,
+			wantMessage:  "not signed by a trusted authority",
+		},
+		{
+			name:         "handshake alert",
+			err:          wrap(tls.AlertError(40)),
+			wantCategory: failureTLS,
+			wantMessage:  "TLS handshake failed",
+		},
+		{
+			name:         "dns",
+			err:          wrap(&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}),
+			wantCategory: failureDNS,
+			wantMessage:  "could not be resolved",
+		},
+		{
+			name:         "connection refused",
+			err:          wrap(&net.OpError{Op: "dial", Err: fmt.Errorf("connect: %w", syscall.ECONNREFUSED)}),
+			wantCategory: failureConnectionRefused,
+			wantMessage:  "refused the connection",
+		},
+		{
+			name:         "other",
+			err:          wrap(errSomethingElse),
+			wantCategory: failureUnreachable,
+			wantMessage:  "could not be reached. Check the address.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureCategory(tt.err); got != tt.wantCategory {
+				t.Errorf("failureCategory() = %q, want %q", got, tt.wantCategory)
+			}
+			if got := unreachableMessage(tt.err); !strings.Contains(got, tt.wantMessage) {
+				t.Errorf("unreachableMessage() = %q, want it to contain %q", got, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package pageinsight
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// Limits on the exclude_links patterns one request may send.
+const (
+	maxExcludePatterns      = 20
+	maxExcludePatternLength = 256
+)
+
+// linkFilter holds the compiled exclude_links patterns. A nil filter excludes
+// nothing.
+type linkFilter struct {
+	patterns []*regexp.Regexp
+}
+
+// compileLinkFilter compiles exclude_links patterns. A pattern wrapped in
+// slashes, like /logout/, is a regular expression that may match anywhere in
+// the URL; any other pattern is a glob that must match the whole URL, where *
+// matches any run of characters and ? a single one.
+func compileLinkFilter(patterns []string) (*linkFilter, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	if len(patterns) > maxExcludePatterns {
+		return nil, &errs.AppError{
+			Kind:    errs.InvalidInput,
+			Message: fmt.Sprintf("Too many \"exclude_links\" patterns; at most %d are allowed.", maxExcludePatterns),
+		}
+	}
+	f := &linkFilter{patterns: make([]*regexp.Regexp, 0, len(patterns))}
+	for _, pattern := range patterns {
+		if pattern == "" || len(pattern) > maxExcludePatternLength {
+			return nil, &errs.AppError{
+				Kind:    errs.InvalidInput,
+				Message: fmt.Sprintf("Each \"exclude_links\" pattern must be 1 to %d characters long.", maxExcludePatternLength),
+			}
+		}
+		re, err := compileLinkPattern(pattern)
+		if err != nil {
+			return nil, &errs.AppError{
+				Kind:    errs.InvalidInput,
+				Message: fmt.Sprintf("Invalid \"exclude_links\" pattern %q.", pattern),
+				Cause:   err,
+			}
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+func compileLinkPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func (f *linkFilter) excludes(link string) bool {
+	if f == nil {
+		return false
+	}
+	for _, re := range f.patterns {
+		if re.MatchString(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// split separates the targets to check from the excluded ones, keeping their
+// order.
+func (f *linkFilter) split(targets []string) (kept []string, excluded int) {
+	if f == nil {
+		return targets, 0
+	}
+	kept = make([]string, 0, len(targets))
+	for _, target := range targets {
+		if f.excludes(target) {
+			excluded++
+			continue
+		}
+		kept = append(kept, target)
+	}
+	return kept, excluded
+}
@@ -0,0 +1,90 @@
+package pageinsight
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+func TestLinkFilter_Excludes(t *testing.T) {
+	tests := []struct {
+		pattern string
+		link    string
+		want    bool
+	}{
+		{pattern: "*/logout*", link: "https://example.com/account/logout?next=/", want: true},
+		{pattern: "*/logout*", link: "https://example.com/login", want: false},
+		{pattern: "https://flaky.example/*", link: "https://flaky.example/widget.js", want: true},
+		{pattern: "https://flaky.example/*", link: "https://other.example/https://flaky.example/", want: false},
+		{pattern: "https://example.com/page?", link: "https://example.com/page2", want: true},
+		{pattern: "https://example.com/a.b", link: "https://example.com/aXb", want: false},
+		{pattern: `/\.ics$/`, link: "https://example.com/events/export.ics", want: true},
+		{pattern: `/\.ics$/`, link: "https://example.com/events/export.ics.html", want: false},
+		{pattern: "/calendar/", link: "https://example.com/team/calendar/2024", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.link, func(t *testing.T) {
+			f, err := compileLinkFilter([]string{tt.pattern})
+			if err != nil {
+				t.Fatalf("compileLinkFilter: %v", err)
+			}
+			if got := f.excludes(tt.link); got != tt.want {
+				t.Errorf("excludes(%q) = %v, want %v", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkFilter_Split(t *testing.T) {
+	f, err := compileLinkFilter([]string{"*/logout", "/tracker/"})
+	if err != nil {
+		t.Fatalf("compileLinkFilter: %v", err)
+	}
+	kept, excluded := f.split([]string{
+		"https://example.com/",
+		"https://example.com/logout",
+		"https://tracker.example/pixel",
+		"https://example.com/about",
+	})
+	if want := []string{"https://example.com/", "https://example.com/about"}; !slices.Equal(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if excluded != 2 {
+		t.Errorf("excluded = %d, want 2", excluded)
+	}
+
+	var none *linkFilter
+	if kept, excluded := none.split([]string{"https://example.com/"}); len(kept) != 1 || excluded != 0 {
+		t.Errorf("nil filter split = %v, %d, want the targets unchanged", kept, excluded)
+	}
+}
+
+func TestCompileLinkFilter_Invalid(t *testing.T) {
+	tooMany := make([]string, maxExcludePatterns+1)
+	for i := range tooMany {
+		tooMany[i] = "*/logout"
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{name: "bad regexp", patterns: []string{"/(unclosed/"}},
+		{name: "empty pattern", patterns: []string{""}},
+		{name: "too long", patterns: []string{strings.Repeat("a", maxExcludePatternLength+1)}},
+		{name: "too many", patterns: tooMany},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileLinkFilter(tt.patterns)
+			var appErr *errs.AppError
+			if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput {
+				t.Errorf("err = %v, want an InvalidInput AppError", err)
+			}
+		})
+	}
+}
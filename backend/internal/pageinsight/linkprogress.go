@@ -0,0 +1,64 @@
+package pageinsight
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/progress"
+)
+
+// linkProgress counts the links checked for an analysis that reports its
+// progress, and reports the count after each one.
+type linkProgress struct {
+	ctx context.Context
+
+	mu             sync.Mutex
+	checked, total int
+}
+
+type linkProgressKey struct{}
+
+// withLinkProgress returns a context that counts link checks towards total,
+// or ctx itself when the analysis doesn't report its progress.
+func withLinkProgress(ctx context.Context, total int) context.Context {
+	if progress.FromContext(ctx) == nil {
+		return ctx
+	}
+	p := &linkProgress{ctx: ctx, total: total}
+	p.report()
+	return context.WithValue(ctx, linkProgressKey{}, p)
+}
+
+func linkProgressFrom(ctx context.Context) *linkProgress {
+	p, _ := ctx.Value(linkProgressKey{}).(*linkProgress)
+	return p
+}
+
+// skip takes n links the link checker won't check, past its cap, off the
+// total.
+func (p *linkProgress) skip(n int) {
+	if p == nil || n <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total -= n
+	p.report()
+}
+
+// done counts one checked link.
+func (p *linkProgress) done() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checked++
+	p.report()
+}
+
+// report sends the count; callers other than the constructor hold p.mu,
+// which keeps the callback from running concurrently.
+func (p *linkProgress) report() {
+	progress.Report(p.ctx, progress.Event{Stage: progress.CheckingLinks, Checked: p.checked, Total: p.total})
+}
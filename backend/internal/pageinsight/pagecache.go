@@ -0,0 +1,214 @@
+package pageinsight
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// pageCache remembers recent analyses with the validators of the page they
+// came from, so a repeat analysis within the TTL asks the site whether the
+// page changed and reuses the earlier result when it answers 304 Not
+// Modified. It holds at most size entries and evicts the least recently used
+// one when full. A nil *pageCache caches nothing.
+type pageCache struct {
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // of *pageCacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// pageCacheEntry is a cached analysis, the links the crawler follows from
+// it, and the ETag and Last-Modified headers of its page.
+type pageCacheEntry struct {
+	key          string
+	analysis     *model.PageAnalysis
+	links        []Link
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// newPageCache returns nil, disabling the cache, when ttl or size is not
+// positive.
+func newPageCache(ttl time.Duration, size int) *pageCache {
+	if ttl <= 0 || size <= 0 {
+		return nil
+	}
+	return &pageCache{
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// pageCacheKey identifies an analysis by the normalized URL and the options
+// that shape its result. Analyses with caller-supplied headers are never
+// cached, as what they see may be private to the caller; nor are those
+// following a meta refresh, whose validators would be the stub's.
+func pageCacheKey(targetURL string, opts model.AnalyzeOptions) (string, bool) {
+	if len(opts.Headers) > 0 || opts.FollowMetaRefresh {
+		return "", false
+	}
+	return fmt.Sprintf("%s|%t|%t|%t|%q|%q|%t", normalizeURL(targetURL),
+		opts.CheckPreloads, opts.CheckImages, opts.IncludeLinks, opts.CheckLinks, opts.ExcludeLinks, opts.Force), true
+}
+
+// get returns the unexpired entry for key.
+func (c *pageCache) get(key string) (*pageCacheEntry, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*pageCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+// put stores an analysis whose page sent a validator; without one there is
+// no way to ask whether it changed.
+func (c *pageCache) put(key string, analysis *model.PageAnalysis, links []Link, header http.Header) {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	if c == nil || (etag == "" && lastModified == "") {
+		return
+	}
+	entry := &pageCacheEntry{
+		key:          key,
+		analysis:     analysis,
+		links:        links,
+		etag:         etag,
+		lastModified: lastModified,
+		expires:      c.now().Add(c.ttl),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*pageCacheEntry).key)
+	}
+}
+
+// cachedAnalysis is a copy of the entry's analysis, marked as cached and
+// reported for targetURL.
+func (e *pageCacheEntry) cachedAnalysis(targetURL string) *model.PageAnalysis {
+	result := cloneAnalysis(e.analysis)
+	result.URL = targetURL
+	result.Cached = true
+	return result
+}
+
+// cloneAnalysis returns a deep copy of a, so a caller changing a result, its
+// warnings or link details say, can't change the cached analysis it came
+// from or went into.
+func cloneAnalysis(a *model.PageAnalysis) *model.PageAnalysis {
+	return deepCopy(reflect.ValueOf(a)).Interface().(*model.PageAnalysis)
+}
+
+// deepCopy copies v along with everything its pointers, slices and maps
+// reach. Unexported struct fields, such as the location of a time.Time, are
+// copied as they are.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := range v.NumField() {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+type conditionalKey struct{}
+
+// withConditional returns a context whose page fetch asks the site to answer
+// 304 Not Modified if the page still matches entry.
+func withConditional(ctx context.Context, entry *pageCacheEntry) context.Context {
+	return context.WithValue(ctx, conditionalKey{}, entry)
+}
+
+// setConditionalHeaders adds If-None-Match and If-Modified-Since to a page
+// fetch made for a revalidation.
+func setConditionalHeaders(req *http.Request) {
+	entry, _ := req.Context().Value(conditionalKey{}).(*pageCacheEntry)
+	if entry == nil {
+		return
+	}
+	if entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+	if entry.lastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.lastModified)
+	}
+}
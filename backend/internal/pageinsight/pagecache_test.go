@@ -0,0 +1,218 @@
+package pageinsight
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// etagServer serves a page tagged with its current version, answering 304
+// to a request that already has it. It counts the full responses.
+type etagServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	version string
+	full    int
+}
+
+func newETagServer(t *testing.T) *etagServer {
+	t.Helper()
+	s := &etagServer{version: "v1"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		etag := `"` + s.version + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		s.full++
+		_, _ = io.WriteString(w, "<html><head><title>"+s.version+"</title></head></html>")
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *etagServer) set(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+func (s *etagServer) fullResponses() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.full
+}
+
+func TestEngine_Analyze_PageCache(t *testing.T) {
+	site := newETagServer(t)
+	engine := NewEngine(&HTTPClient{client: site.Client()}, &mockLinkChecker{})
+	engine.EnablePageCache(time.Minute, 10)
+	ctx := context.Background()
+
+	analyze := func(opts model.AnalyzeOptions) *model.PageAnalysis {
+		t.Helper()
+		result, err := engine.Analyze(ctx, site.URL, opts)
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		return result
+	}
+
+	if first := analyze(model.AnalyzeOptions{}); first.Cached || first.Title != "v1" {
+		t.Errorf("first analysis: cached = %v, title = %q, want a fresh v1", first.Cached, first.Title)
+	}
+	if again := analyze(model.AnalyzeOptions{}); !again.Cached || again.Title != "v1" {
+		t.Errorf("repeat analysis: cached = %v, title = %q, want the cached v1", again.Cached, again.Title)
+	}
+	if got := site.fullResponses(); got != 1 {
+		t.Errorf("site sent %d full responses, want 1 with the repeat revalidated", got)
+	}
+
+	// Other options make another analysis, and headers are never cached.
+	if other := analyze(model.AnalyzeOptions{CheckImages: true}); other.Cached {
+		t.Error("analysis with other options was served from the cache")
+	}
+	for range 2 {
+		if private := analyze(model.AnalyzeOptions{Headers: model.RequestHeaders{"Cookie": "a=b"}}); private.Cached {
+			t.Error("analysis with caller-supplied headers was served from the cache")
+		}
+	}
+
+	site.set("v2")
+	if changed := analyze(model.AnalyzeOptions{}); changed.Cached || changed.Title != "v2" {
+		t.Errorf("changed page: cached = %v, title = %q, want a fresh v2", changed.Cached, changed.Title)
+	}
+}
+
+func TestEngine_Analyze_PageCacheCopiesResults(t *testing.T) {
+	site := newETagServer(t)
+	engine := NewEngine(&HTTPClient{client: site.Client()}, &mockLinkChecker{})
+	engine.EnablePageCache(time.Minute, 10)
+	ctx := context.Background()
+
+	mutate := func(result *model.PageAnalysis) {
+		result.Title = "changed"
+		result.Headings["h1"] = 99
+		for i := range result.Warnings {
+			result.Warnings[i] = "changed"
+		}
+	}
+	var wantWarnings []string
+	for i := range 3 {
+		result, err := engine.Analyze(ctx, site.URL, model.AnalyzeOptions{})
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		if i == 0 {
+			wantWarnings = slices.Clone(result.Warnings)
+		}
+		if result.Cached != (i > 0) || result.Title != "v1" || result.Headings["h1"] != 0 || !slices.Equal(result.Warnings, wantWarnings) {
+			t.Errorf("analysis %d: cached = %v, title = %q, h1 = %d, warnings = %v, want the untouched v1",
+				i, result.Cached, result.Title, result.Headings["h1"], result.Warnings)
+		}
+		// The fresh result, then the cached ones, are changed by the caller.
+		mutate(result)
+	}
+}
+
+func TestCloneAnalysis(t *testing.T) {
+	original := &model.PageAnalysis{
+		Title:       "Home",
+		Headings:    map[string]int{"h1": 1},
+		LinksDetail: []model.LinkDetail{{URL: "https://example.com/", Rel: []string{"nofollow"}}},
+		TLS:         &model.TLSInfo{Version: "TLS 1.3"},
+		Warnings:    []string{"a warning"},
+	}
+	want := &model.PageAnalysis{
+		Title:       "Home",
+		Headings:    map[string]int{"h1": 1},
+		LinksDetail: []model.LinkDetail{{URL: "https://example.com/", Rel: []string{"nofollow"}}},
+		TLS:         &model.TLSInfo{Version: "TLS 1.3"},
+		Warnings:    []string{"a warning"},
+	}
+
+	clone := cloneAnalysis(original)
+	if !reflect.DeepEqual(clone, want) {
+		t.Fatalf("clone = %+v, want %+v", clone, want)
+	}
+	clone.Headings["h1"] = 2
+	clone.LinksDetail[0].Rel[0] = "ugc"
+	clone.TLS.Version = "TLS 1.2"
+	clone.Warnings[0] = "changed"
+	if !reflect.DeepEqual(original, want) {
+		t.Errorf("changing the clone changed the original to %+v", original)
+	}
+}
+
+func TestEngine_Analyze_PageCacheOff(t *testing.T) {
+	site := newETagServer(t)
+	engine := NewEngine(&HTTPClient{client: site.Client()}, &mockLinkChecker{})
+	engine.EnablePageCache(0, 10)
+
+	for range 2 {
+		result, err := engine.Analyze(context.Background(), site.URL, model.AnalyzeOptions{})
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		if result.Cached {
+			t.Error("analysis was cached with the cache off")
+		}
+	}
+	if got := site.fullResponses(); got != 2 {
+		t.Errorf("site sent %d full responses, want 2", got)
+	}
+}
+
+func TestPageCache_ExpiryAndEviction(t *testing.T) {
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	c := newPageCache(time.Minute, 2)
+	c.now = func() time.Time { return now }
+	header := http.Header{"Etag": {`"x"`}}
+	analysis := &model.PageAnalysis{Title: "cached"}
+
+	c.put("a", analysis, nil, header)
+	c.put("b", analysis, nil, header)
+	c.put("no validators", analysis, nil, http.Header{})
+	if _, ok := c.get("no validators"); ok {
+		t.Error("a page without ETag or Last-Modified was cached")
+	}
+	c.get("a") // a is now more recently used than b
+	c.put("c", analysis, nil, header)
+	if _, ok := c.get("b"); ok {
+		t.Error("least recently used entry b was not evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("entry a was evicted")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("a"); ok {
+		t.Error("entry a outlived its TTL")
+	}
+}
+
+func TestPageCacheKey(t *testing.T) {
+	base, _ := pageCacheKey("https://Example.com/page#top", model.AnalyzeOptions{})
+	same, _ := pageCacheKey("https://example.com/page", model.AnalyzeOptions{Timeout: time.Second})
+	if base != same {
+		t.Errorf("keys %q and %q differ, want the URL normalized and the timeout ignored", base, same)
+	}
+	if other, _ := pageCacheKey("https://example.com/page", model.AnalyzeOptions{CheckLinks: model.CheckLinksNone}); other == base {
+		t.Error("options that change the result share a key")
+	}
+	if _, ok := pageCacheKey("https://example.com/", model.AnalyzeOptions{FollowMetaRefresh: true}); ok {
+		t.Error("an analysis following a meta refresh is cacheable")
+	}
+}
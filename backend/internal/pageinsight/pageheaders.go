@@ -0,0 +1,76 @@
+package pageinsight
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+type pageHeadersKey struct{}
+
+// pageHeaders are the caller-supplied headers of one analysis and the origin
+// of the analyzed page, the only place they are sent.
+type pageHeaders struct {
+	scheme string
+	host   string
+	header http.Header
+}
+
+// withPageHeaders returns a context whose page fetch, and link checks on the
+// same host as page, send headers.
+func withPageHeaders(ctx context.Context, page *url.URL, headers model.RequestHeaders) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	h := make(http.Header, len(headers))
+	for name, value := range headers {
+		h.Set(name, value)
+	}
+	return context.WithValue(ctx, pageHeadersKey{}, &pageHeaders{
+		scheme: page.Scheme,
+		host:   strings.ToLower(page.Host),
+		header: h,
+	})
+}
+
+// sendsTo reports whether the headers may go with a request to u: one on the
+// page's host, and not over plain http when the page itself is https.
+func (p *pageHeaders) sendsTo(u *url.URL) bool {
+	if p.scheme == "https" && u.Scheme != "https" {
+		return false
+	}
+	return strings.ToLower(u.Host) == p.host
+}
+
+// scopePageHeaders sets the caller-supplied headers on req when its URL is on
+// the analyzed page's host, and removes them otherwise. Redirects start as a
+// copy of the first request's headers, so they go through here again.
+func scopePageHeaders(req *http.Request) {
+	p, _ := req.Context().Value(pageHeadersKey{}).(*pageHeaders)
+	if p == nil {
+		return
+	}
+	send := p.sendsTo(req.URL)
+	for name, values := range p.header {
+		if send {
+			req.Header[name] = values
+		} else {
+			req.Header.Del(name)
+		}
+	}
+}
+
+// sendsPageHeaders reports whether a link check of link carries the
+// caller-supplied headers. Its result depends on them, so it is neither
+// served from nor stored in the cache shared with other analyses.
+func sendsPageHeaders(ctx context.Context, link string) bool {
+	p, _ := ctx.Value(pageHeadersKey{}).(*pageHeaders)
+	if p == nil {
+		return false
+	}
+	u, err := url.Parse(link)
+	return err == nil && p.sendsTo(u)
+}
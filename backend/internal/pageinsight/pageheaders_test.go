@@ -0,0 +1,126 @@
+package pageinsight
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// headerRecorder is a test server that records the Cookie and X-Api-Key
+// headers of each request by path. /away redirects to target, if set.
+type headerRecorder struct {
+	*httptest.Server
+
+	target string
+	mu     sync.Mutex
+	seen   map[string][]string
+}
+
+func newHeaderRecorder(t *testing.T) *headerRecorder {
+	t.Helper()
+	r := &headerRecorder{seen: make(map[string][]string)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.seen[req.URL.Path] = []string{req.Header.Get("Cookie"), req.Header.Get("X-Api-Key")}
+		r.mu.Unlock()
+		if req.URL.Path == "/away" && r.target != "" {
+			http.Redirect(w, req, r.target, http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *headerRecorder) headers(path string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seen[path]
+}
+
+var testPageHeaders = model.RequestHeaders{"Cookie": "session=abc", "x-api-key": "k1"}
+
+func pageHeadersContext(t *testing.T, pageURL string) context.Context {
+	t.Helper()
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return withPageHeaders(context.Background(), page, testPageHeaders)
+}
+
+func TestHTTPClient_Fetch_PageHeaders(t *testing.T) {
+	page := newHeaderRecorder(t)
+	other := newHeaderRecorder(t)
+	page.target = other.URL + "/landing"
+
+	c := &HTTPClient{client: &http.Client{CheckRedirect: safeRedirectPolicy}}
+	ctx := pageHeadersContext(t, page.URL)
+	for _, path := range []string{"/", "/away"} {
+		resp, err := c.Fetch(ctx, page.URL+path)
+		if err != nil {
+			t.Fatalf("Fetch(%s): %v", path, err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	want := []string{"session=abc", "k1"}
+	if got := page.headers("/"); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("page got headers %q, want %q", got, want)
+	}
+	if got := other.headers("/landing"); len(got) != 2 || got[0] != "" || got[1] != "" {
+		t.Errorf("redirect to another host got headers %q, want none", got)
+	}
+}
+
+func TestLinkChecker_PageHeaders(t *testing.T) {
+	page := newHeaderRecorder(t)
+	other := newHeaderRecorder(t)
+	lc := testLinkCheckerWith(func(o *LinkCheckerOptions) {
+		o.Strategy = StrategyGetOnly
+		o.CacheTTL = time.Minute
+	})
+
+	ctx := pageHeadersContext(t, page.URL)
+	lc.CheckLinks(ctx, []string{page.URL + "/internal", other.URL + "/external"})
+
+	if got := page.headers("/internal"); len(got) != 2 || got[0] != "session=abc" {
+		t.Errorf("same-host link got headers %q, want the page's", got)
+	}
+	if got := other.headers("/external"); len(got) != 2 || got[0] != "" || got[1] != "" {
+		t.Errorf("third-party link got headers %q, want none", got)
+	}
+	if _, ok := lc.cache.get(page.URL + "/internal"); ok {
+		t.Error("a link checked with the page's headers was cached")
+	}
+	if _, ok := lc.cache.get(other.URL + "/external"); !ok {
+		t.Error("a link checked without the page's headers was not cached")
+	}
+}
+
+func TestPageHeaders_SendsTo(t *testing.T) {
+	page, _ := url.Parse("https://example.com/account")
+	p, _ := withPageHeaders(context.Background(), page, testPageHeaders).Value(pageHeadersKey{}).(*pageHeaders)
+
+	tests := map[string]bool{
+		"https://example.com/other":     true,
+		"https://EXAMPLE.com/":          true,
+		"http://example.com/":           false,
+		"https://www.example.com/":      false,
+		"https://example.com:8443/":     false,
+		"https://example.com.evil.net/": false,
+	}
+	for raw, want := range tests {
+		u, _ := url.Parse(raw)
+		if got := p.sendsTo(u); got != want {
+			t.Errorf("sendsTo(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -3,110 +3,1166 @@ package pageinsight
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"net/url"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
 	"golang.org/x/net/html"
 )
 
 var (
-	tagTitle = []byte("title")
-	tagA     = []byte("a")
-	tagInput = []byte("input")
-	attrHref = []byte("href")
-	attrType = []byte("type")
+	tagTitle    = []byte("title")
+	tagH1       = []byte("h1")
+	tagA        = []byte("a")
+	tagImg      = []byte("img")
+	tagInput    = []byte("input")
+	tagSVG      = []byte("svg")
+	tagScript   = []byte("script")
+	tagStyle    = []byte("style")
+	tagNoscript = []byte("noscript")
+	tagAppRoot  = []byte("app-root")
+	tagIframe   = []byte("iframe")
+	tagLink     = []byte("link")
+	tagForm     = []byte("form")
+	tagVideo    = []byte("video")
+	tagAudio    = []byte("audio")
+	tagPicture  = []byte("picture")
+	tagSource   = []byte("source")
+	tagSearch   = []byte("search")
+	tagTable    = []byte("table")
+	tagTh       = []byte("th")
+	tagCaption  = []byte("caption")
+	tagMeta     = []byte("meta")
+	tagTime     = []byte("time")
+	tagSelect   = []byte("select")
+	tagTextarea = []byte("textarea")
+	tagLabel    = []byte("label")
+
+	attrHref    = []byte("href")
+	attrType    = []byte("type")
+	attrAlt     = []byte("alt")
+	attrSrc     = []byte("src")
+	attrRel     = []byte("rel")
+	attrAction  = []byte("action")
+	attrName    = []byte("name")
+	attrRole    = []byte("role")
+	attrStyle   = []byte("style")
+	attrTarget  = []byte("target")
+	attrSrcset  = []byte("srcset")
+	attrLoading = []byte("loading")
+	attrWidth   = []byte("width")
+	attrHeight  = []byte("height")
+	attrID      = []byte("id")
+
+	attrAutocomplete = []byte("autocomplete")
+	attrHTTPEquiv    = []byte("http-equiv")
+	attrContent      = []byte("content")
+	attrProperty     = []byte("property")
+	attrDatetime     = []byte("datetime")
+	attrItemprop     = []byte("itemprop")
+	attrFor          = []byte("for")
+	attrClass        = []byte("class")
+	attrAriaLabel    = []byte("aria-label")
+	attrAriaLabelBy  = []byte("aria-labelledby")
+)
+
+const (
+	// maxAnchorText caps the number of characters kept per link's anchor text.
+	maxAnchorText = 200
+	// maxH1Text caps the number of characters kept from the first <h1>.
+	maxH1Text = 300
+	// maxInlineSVGBytes is the raw size above which a single inline <svg> is
+	// reported as oversized.
+	maxInlineSVGBytes = 50 << 10 // 50 KB
+	// maxMixedContentExamples caps how many offending URLs are kept as examples.
+	maxMixedContentExamples = 5
 )
 
 // ParseResult holds everything extracted from a single-pass HTML parse.
 type ParseResult struct {
-	HTMLVersion  string
-	Title        string
-	Headings     map[string]int
-	Links        []Link
+	HTMLVersion string
+	// Doctype is the raw doctype, empty when there is none, so clients can
+	// inspect ones that HTMLVersion reports as "Unknown".
+	Doctype    string
+	Title      string
+	TitleCount int
+	Headings   map[string]int
+	FirstH1    string
+	Links      []Link
+	// Fragments lists the targets of same-page links (href="#id") without
+	// the leading '#'; they are kept apart from Links because they never
+	// need checking.
+	Fragments []string
+	// BrokenFragments counts the fragments whose target is neither an id nor
+	// an <a name> on the page, with a few listed in BrokenFragmentSamples.
+	BrokenFragments       int
+	BrokenFragmentSamples []string
+	Media                 MediaCounts
+	MediaLinks            []Link
+	// HasLoginForm is the legacy boolean form of LoginForm, only true for
+	// "login".
 	HasLoginForm bool
+	// LoginForm is "login", "signup" or "none".
+	LoginForm     string
+	HasSearchForm bool
+	// SearchAction is the resolved action of the first search form, empty
+	// when the search input is not inside a <form>.
+	SearchAction string
+	Tables       int
+	LayoutTables int
+	// ManifestURL is the resolved href of the first <link rel="manifest">.
+	ManifestURL string
+	// Pagination holds the resolved <link rel="next"> and <link rel="prev">
+	// targets.
+	Pagination model.Pagination
+	// MetaRefresh is the first valid <meta http-equiv="refresh">, with its
+	// target resolved.
+	MetaRefresh  *model.MetaRefresh
+	SVGCount     int
+	SVGBytes     int
+	OversizedSVG int
+
+	InlineEventHandlers int
+	JavascriptLinks     int
+	// UnsafeTargetBlank counts target="_blank" links whose rel has neither
+	// noopener nor noreferrer.
+	UnsafeTargetBlank int
+	Images            model.ImageStats
+	// ImageLinks holds the src and srcset candidates of every <img> and the
+	// srcset candidates of <source> elements.
+	ImageLinks   []Link
+	InlineStyles model.InlineStyles
+	MixedContent model.MixedContent
+
+	// MentionsServiceWorker reports an inline script calling
+	// serviceWorker.register.
+	MentionsServiceWorker bool
+	// ThirdPartyDomains counts external scripts per host other than the
+	// page's.
+	ThirdPartyDomains []model.DomainCount
+	// TextRatio is the share of the document's bytes that are visible text,
+	// outside <script>, <style>, <noscript> and <title>, with whitespace
+	// collapsed, and TextBytes is the size of that text.
+	TextRatio float64
+	TextBytes int
+	// DOMNodes counts elements and DOMDepth is their deepest nesting.
+	DOMNodes int
+	DOMDepth int
+	// MarkupWarnings counts structural problems the tokenizer recovered
+	// from, with up to maxMarkupSamples of them described in MarkupSamples.
+	MarkupWarnings int
+	MarkupSamples  []string
+
+	// LikelyRequiresJavascript flags a near-empty page that looks like a
+	// client-rendered app shell.
+	LikelyRequiresJavascript bool
+	// Trackers names the well-known trackers among ThirdPartyDomains.
+	Trackers []string
+	// Analytics names the analytics products installed, found from script
+	// URLs and inline snippets.
+	Analytics []string
+	// Dates holds the published and modified dates from JSON-LD, Open Graph
+	// meta or <time> elements, preferred in that order.
+	Dates model.PageDates
+	// UnlabeledInputs counts form controls with no <label>, aria-label or
+	// aria-labelledby.
+	UnlabeledInputs int
+	// EmptyHeadings counts headings with neither text nor image alt text.
+	EmptyHeadings int
+	// BotProtection names the CAPTCHA and bot-protection widgets embedded in
+	// the page.
+	BotProtection []string
+	// Landmarks counts ARIA landmarks by role, from role attributes and the
+	// HTML elements that imply them.
+	Landmarks map[string]int
+	// ResourceHints counts the <link> preload, prefetch, dns-prefetch and
+	// preconnect hints.
+	ResourceHints model.ResourceHints
+	// PreloadURLs lists the distinct resolved preload targets.
+	PreloadURLs []string
+	// Roles counts every role attribute value.
+	Roles map[string]int
+
+	Warnings []string
+}
+
+// MediaCounts counts media container elements.
+type MediaCounts struct {
+	Video   int
+	Audio   int
+	Picture int
+}
+
+// tableState records what an open <table> contains. A table without header
+// cells or a caption, or one that holds another table, is likely used for
+// layout rather than data.
+type tableState struct {
+	hasHeader  bool
+	hasCaption bool
+	hasNested  bool
+}
+
+// elementContext tracks the foreign-content elements the tokenizer is
+// currently inside, so their contents do not leak into page-level counters
+// (an SVG <title> is not the page title, an SVG <a> is not a page link).
+type elementContext struct {
+	svgDepth int
+	svgBytes int // raw bytes of the outermost <svg> currently open
+}
+
+func (c *elementContext) inSVG() bool {
+	return c.svgDepth > 0
 }
 
 // Link represents a URL found on the page with its classification.
+// Text is the visible anchor text (including image alt text), with
+// whitespace collapsed and capped at maxAnchorText characters. Rel is the raw
+// rel attribute of an anchor.
 type Link struct {
 	URL        string
 	IsInternal bool
+	Text       string
+	Rel        string
 }
 
-// Parse performs a single-pass traversal of the HTML body, extracting
-// title, headings, HTML version, links, and login form presence.
+// ParseOptions selects the features a parse extracts, so callers that only
+// need the basics skip the cost of the rest. Title, headings, HTML version and
+// form detection are always extracted.
+type ParseOptions struct {
+	// CollectAnchors extracts <a> links, fragments and anchor text.
+	CollectAnchors bool
+	// CollectMeta extracts the <meta> refresh and Open Graph dates, and the
+	// manifest, pagination and resource hint <link> elements.
+	CollectMeta bool
+	// CollectImages extracts <img> stats, image and srcset candidate URLs,
+	// and image hosts.
+	CollectImages bool
+	// MaxLinks caps how many links are kept, 0 for no limit. Links past the
+	// cap are counted in a warning.
+	MaxLinks int
+}
+
+// DefaultParseOptions extracts every feature with no link cap.
+func DefaultParseOptions() ParseOptions {
+	return ParseOptions{CollectAnchors: true, CollectMeta: true, CollectImages: true}
+}
+
+// Parse performs a single-pass traversal of the HTML body with the default
+// options, extracting title, headings, HTML version, links, and login form
+// classification.
 func Parse(body io.Reader, baseURL *url.URL) (*ParseResult, error) {
-	result := &ParseResult{
-		HTMLVersion: "Unknown",
-		Headings:    map[string]int{"h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0},
-	}
+	return ParseWithOptions(body, baseURL, DefaultParseOptions())
+}
 
-	z := html.NewTokenizer(body)
-	var inTitle bool
+// ParseWithOptions is Parse extracting only the features opts selects.
+func ParseWithOptions(body io.Reader, baseURL *url.URL, opts ParseOptions) (*ParseResult, error) {
+	p := &parser{
+		z:       html.NewTokenizer(body),
+		baseURL: baseURL,
+		opts:    opts,
+		anchor:  -1,
+		result: &ParseResult{
+			HTMLVersion: "Unknown",
+			Headings:    map[string]int{"h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0},
+		},
+	}
 
 	for {
-		tt := z.Next()
+		tt := p.z.Next()
+		raw := len(p.z.Raw())
+		p.docBytes += raw
+		if p.elem.inSVG() {
+			p.elem.svgBytes += raw
+		}
+
 		switch tt {
 		case html.ErrorToken:
-			if errors.Is(z.Err(), io.EOF) {
-				return result, nil
+			if errors.Is(p.z.Err(), io.EOF) {
+				p.finish()
+				return p.result, nil
 			}
-			return nil, z.Err()
+			return nil, p.z.Err()
 
 		case html.DoctypeToken:
-			token := z.Token()
-			result.HTMLVersion = detectHTMLVersion(token)
+			token := p.z.Token()
+			p.result.HTMLVersion = detectHTMLVersion(token)
+			p.result.Doctype = strings.TrimSpace(token.Data)
 
 		case html.StartTagToken, html.SelfClosingTagToken:
-			tn, hasAttr := z.TagName()
+			p.startTag(tt == html.SelfClosingTagToken)
+
+		case html.TextToken:
+			p.text()
 
+		case html.EndTagToken:
+			p.endTag()
+		}
+	}
+}
+
+// parser holds the traversal state of a single Parse call.
+type parser struct {
+	z       *html.Tokenizer
+	baseURL *url.URL
+	opts    ParseOptions
+	result  *ParseResult
+
+	inTitle    bool
+	inScript   bool
+	inStyle    bool
+	inNoscript bool
+	js         jsSignals
+	analytics  analyticsDetector
+	dates      dateTracker
+	labels     labelTracker
+	bots       botDetector
+	landmarks  landmarkTracker
+	targets    fragmentTargets
+	// inJSONLD is set inside a JSON-LD <script>, whose text collects in jsonLD.
+	inJSONLD  bool
+	jsonLD    strings.Builder
+	docBytes  int
+	textBytes int
+	titleText strings.Builder
+	inFirstH1 bool
+	h1Text    strings.Builder
+	// inHeading is set inside an <h1>-<h6>, and headingText once it has
+	// non-whitespace text or image alt text.
+	inHeading   bool
+	headingText bool
+	emptyHrefs  int
+	// droppedLinks counts links past opts.MaxLinks.
+	droppedLinks int
+	elem         elementContext
+	forms        formTracker
+	dom          domTracker
+	tables       []tableState // open tables, innermost last
+	// thirdParty counts external scripts and images by third-party host.
+	thirdParty map[string]*model.DomainCount
+
+	// attrsRead records whether the current tag's attributes were consumed,
+	// since the tokenizer only allows iterating them once. role holds their
+	// raw role attribute, and searchRole records whether it included "search".
+	attrsRead  bool
+	searchRole bool
+	role       string
+
+	// anchor is the index in result.Links of the <a> currently open, or -1.
+	anchor     int
+	anchorText strings.Builder
+}
+
+func (p *parser) startTag(selfClosing bool) {
+	tn, hasAttr := p.z.TagName()
+	p.attrsRead = false
+	p.searchRole = false
+	p.role = ""
+	p.dom.start(tn, selfClosing)
+	p.forms.startTag(tn)
+
+	switch {
+	case bytes.Equal(tn, tagSVG):
+		p.result.SVGCount++
+		if !p.elem.inSVG() {
+			p.elem.svgBytes = len(p.z.Raw())
+		}
+		if !selfClosing {
+			p.elem.svgDepth++
+		} else if !p.elem.inSVG() {
+			p.closeSVG()
+		}
+
+	case p.elem.inSVG():
+		// Foreign content does not contribute to page-level counters.
+
+	case bytes.Equal(tn, tagTitle):
+		p.inTitle = true
+		p.result.TitleCount++
+
+	case isHeading(tn):
+		p.result.Headings[string(tn)]++
+		if tn[1] == '1' && p.result.Headings["h1"] == 1 && !selfClosing {
+			p.inFirstH1 = true
+		}
+		// Headings cannot nest, so a new one implicitly closes the previous.
+		p.closeHeading()
+		p.inHeading = true
+		if selfClosing {
+			p.closeHeading()
+		}
+
+	case bytes.Equal(tn, tagA) && p.opts.CollectAnchors:
+		// Anchors cannot nest, so a new <a> implicitly closes the previous one.
+		p.closeAnchor()
+		if !hasAttr {
+			break
+		}
+		var href, target, rel string
+		var hasHref bool
+		p.scanAttrs(func(key, val []byte) {
 			switch {
-			case bytes.Equal(tn, tagTitle):
-				inTitle = true
+			case bytes.Equal(key, attrHref):
+				href, hasHref = string(val), true
+			case bytes.Equal(key, attrTarget):
+				target = string(val)
+			case bytes.Equal(key, attrRel):
+				rel = string(val)
+			case bytes.Equal(key, attrName):
+				p.targets.add(string(val))
+			}
+		})
+		if !hasHref {
+			break
+		}
+		if strings.EqualFold(strings.TrimSpace(target), "_blank") && !hasToken(rel, "noopener") && !hasToken(rel, "noreferrer") {
+			p.result.UnsafeTargetBlank++
+		}
+		p.anchorHref(strings.TrimSpace(href), rel, selfClosing)
 
-			case len(tn) == 2 && tn[0] == 'h' && tn[1] >= '1' && tn[1] <= '6':
-				result.Headings[string(tn)]++
+	case bytes.Equal(tn, tagImg) && !p.opts.CollectImages:
+		if (p.anchor >= 0 || p.inHeading) && hasAttr {
+			alt, _ := p.attr(attrAlt)
+			p.imageAlt(alt)
+		}
 
-			case bytes.Equal(tn, tagA) && hasAttr:
-				if href := extractAttr(z, attrHref); href != "" {
-					if link, ok := classifyLink(href, baseURL); ok {
-						result.Links = append(result.Links, link)
-					}
+	case bytes.Equal(tn, tagImg):
+		p.result.Images.Count++
+		var alt, src, srcset, loading string
+		var hasWidth, hasHeight bool
+		if hasAttr {
+			p.scanAttrs(func(key, val []byte) {
+				switch {
+				case bytes.Equal(key, attrAlt):
+					alt = string(val)
+				case bytes.Equal(key, attrSrc):
+					src = string(val)
+				case bytes.Equal(key, attrSrcset):
+					srcset = string(val)
+				case bytes.Equal(key, attrLoading):
+					loading = string(val)
+				case bytes.Equal(key, attrWidth):
+					hasWidth = true
+				case bytes.Equal(key, attrHeight):
+					hasHeight = true
 				}
+			})
+		}
+		p.imageAlt(alt)
+		p.image(src, srcset, loading, hasWidth && hasHeight)
 
-			case bytes.Equal(tn, tagInput) && hasAttr:
-				if strings.EqualFold(extractAttr(z, attrType), "password") {
-					result.HasLoginForm = true
+	case bytes.Equal(tn, tagScript):
+		if hasAttr {
+			var src, typ string
+			p.scanAttrs(func(key, val []byte) {
+				switch {
+				case bytes.Equal(key, attrSrc):
+					src = string(val)
+				case bytes.Equal(key, attrType):
+					typ = string(val)
 				}
+			})
+			p.inJSONLD = !selfClosing && strings.EqualFold(strings.TrimSpace(typ), "application/ld+json")
+			p.checkMixedContent(&p.result.MixedContent.Scripts, src)
+			p.thirdPartyRef(src, false)
+			p.analytics.scriptSrc(src)
+			p.bots.resourceSrc(src)
+		}
+		p.inScript = !selfClosing
+
+	case bytes.Equal(tn, tagStyle):
+		p.result.InlineStyles.Blocks++
+		p.inStyle = !selfClosing
+
+	case bytes.Equal(tn, tagNoscript):
+		p.inNoscript = !selfClosing
+
+	case bytes.Equal(tn, tagAppRoot):
+		// Angular mounts into a custom element rather than an id.
+		p.js.appRoot = true
+
+	case bytes.Equal(tn, tagIframe) && hasAttr:
+		src, _ := p.attr(attrSrc)
+		p.checkMixedContent(&p.result.MixedContent.Iframes, src)
+		p.bots.resourceSrc(src)
+
+	case bytes.Equal(tn, tagLink) && hasAttr:
+		var rel, href string
+		p.scanAttrs(func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, attrRel):
+				rel = string(val)
+			case bytes.Equal(key, attrHref):
+				href = string(val)
+			}
+		})
+		if hasToken(rel, "stylesheet") {
+			p.checkMixedContent(&p.result.MixedContent.Stylesheets, href)
+		}
+		if p.opts.CollectMeta {
+			p.linkRel(rel, href)
+		}
+
+	case bytes.Equal(tn, tagMeta) && hasAttr && p.opts.CollectMeta:
+		var equiv, property, content string
+		p.scanAttrs(func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, attrHTTPEquiv):
+				equiv = string(val)
+			case bytes.Equal(key, attrProperty):
+				property = string(val)
+			case bytes.Equal(key, attrContent):
+				content = string(val)
 			}
+		})
+		if strings.EqualFold(strings.TrimSpace(equiv), "refresh") && p.result.MetaRefresh == nil {
+			p.metaRefresh(content)
+		}
+		p.dates.meta(property, content)
 
-		case html.TextToken:
-			if inTitle {
-				result.Title = strings.TrimSpace(string(z.Text()))
-				inTitle = false
+	case bytes.Equal(tn, tagTime) && hasAttr:
+		var datetime, itemprop string
+		p.scanAttrs(func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, attrDatetime):
+				datetime = string(val)
+			case bytes.Equal(key, attrItemprop):
+				itemprop = string(val)
 			}
+		})
+		if datetime != "" {
+			p.dates.timeElement(datetime, itemprop)
+		}
 
-		case html.EndTagToken:
-			tn, _ := z.TagName()
-			if bytes.Equal(tn, tagTitle) {
-				inTitle = false
+	case bytes.Equal(tn, tagVideo) || bytes.Equal(tn, tagAudio):
+		if bytes.Equal(tn, tagVideo) {
+			p.result.Media.Video++
+		} else {
+			p.result.Media.Audio++
+		}
+		if hasAttr {
+			src, _ := p.attr(attrSrc)
+			p.addMediaLink(src)
+		}
+
+	case bytes.Equal(tn, tagPicture):
+		p.result.Media.Picture++
+
+	case bytes.Equal(tn, tagSource) && hasAttr:
+		// <source> inside <picture> uses srcset, and media sources use src.
+		var src, srcset string
+		p.scanAttrs(func(key, val []byte) {
+			switch {
+			case bytes.Equal(key, attrSrc):
+				src = string(val)
+			case bytes.Equal(key, attrSrcset):
+				srcset = string(val)
 			}
+		})
+		p.addMediaLink(src)
+		if p.opts.CollectImages {
+			p.srcset(srcset)
+		}
+
+	case bytes.Equal(tn, tagTable):
+		p.result.Tables++
+		if len(p.tables) > 0 {
+			p.tables[len(p.tables)-1].hasNested = true
 		}
+		if !selfClosing {
+			p.tables = append(p.tables, tableState{})
+		}
+
+	case bytes.Equal(tn, tagTh) && len(p.tables) > 0:
+		p.tables[len(p.tables)-1].hasHeader = true
+
+	case bytes.Equal(tn, tagCaption) && len(p.tables) > 0:
+		p.tables[len(p.tables)-1].hasCaption = true
+
+	case bytes.Equal(tn, tagForm):
+		var action string
+		if hasAttr {
+			action, _ = p.attr(attrAction)
+			p.checkMixedContent(&p.result.MixedContent.FormActions, action)
+		}
+		p.forms.open(action, p.searchRole)
+
+	case bytes.Equal(tn, tagInput) || bytes.Equal(tn, tagSelect) || bytes.Equal(tn, tagTextarea):
+		var in inputField
+		var id string
+		var ariaLabeled bool
+		if hasAttr {
+			p.scanAttrs(func(key, val []byte) {
+				switch {
+				case bytes.Equal(key, attrType):
+					in.typ = string(val)
+				case bytes.Equal(key, attrAutocomplete):
+					in.autocomplete = string(val)
+				case bytes.Equal(key, attrName):
+					in.name = string(val)
+				case bytes.Equal(key, attrID):
+					id = strings.TrimSpace(string(val))
+				case bytes.Equal(key, attrAriaLabel) || bytes.Equal(key, attrAriaLabelBy):
+					ariaLabeled = ariaLabeled || strings.TrimSpace(string(val)) != ""
+				}
+			})
+		}
+		if bytes.Equal(tn, tagInput) && hasAttr {
+			p.forms.input(in)
+		}
+		if !bytes.Equal(tn, tagInput) {
+			// The type attribute only exempts <input> buttons.
+			in.typ = ""
+		}
+		p.labels.control(in.typ, id, ariaLabeled)
+
+	case bytes.Equal(tn, tagLabel):
+		var forID string
+		if hasAttr {
+			forID, _ = p.attr(attrFor)
+		}
+		p.labels.openLabel(strings.TrimSpace(forID))
+		if selfClosing {
+			p.labels.closeLabel()
+		}
+	}
+
+	if hasAttr && !p.attrsRead {
+		p.scanAttrs(nil)
+	}
+	if !p.elem.inSVG() {
+		p.landmarks.element(tn, p.role, selfClosing)
+	}
+	if (p.searchRole || bytes.Equal(tn, tagSearch)) && !bytes.Equal(tn, tagForm) && !selfClosing && !p.elem.inSVG() {
+		p.forms.enterLandmark(string(tn))
+	}
+}
+
+func (p *parser) anchorHref(href, rel string, selfClosing bool) {
+	switch {
+	case href == "":
+		p.emptyHrefs++
+	case href[0] == '#':
+		p.result.Fragments = append(p.result.Fragments, href[1:])
+	case isJavascriptURL(href):
+		p.result.JavascriptLinks++
+	default:
+		link, ok := classifyLink(href, p.baseURL)
+		if !ok {
+			return
+		}
+		if p.opts.MaxLinks > 0 && len(p.result.Links) >= p.opts.MaxLinks {
+			p.droppedLinks++
+			return
+		}
+		link.Rel = rel
+		p.result.Links = append(p.result.Links, link)
+		if !selfClosing {
+			p.anchor = len(p.result.Links) - 1
+		}
+	}
+}
+
+func (p *parser) text() {
+	// Text may only be read once per token.
+	text := string(p.z.Text())
+	if p.inTitle {
+		p.titleText.WriteString(text)
+	}
+	if p.anchor >= 0 {
+		appendText(&p.anchorText, text, maxAnchorText)
+	}
+	if p.inFirstH1 {
+		appendText(&p.h1Text, text, maxH1Text)
+	}
+	if p.inHeading && !p.inScript && !p.inStyle && strings.TrimSpace(text) != "" {
+		p.headingText = true
+	}
+	if p.inScript && strings.Contains(text, "serviceWorker.register") {
+		p.result.MentionsServiceWorker = true
+	}
+	if p.inScript {
+		p.js.inlineScript += len(text)
+		p.analytics.inlineScript(text)
+	}
+	if p.inJSONLD && p.jsonLD.Len()+len(text) <= maxJSONLDBytes {
+		p.jsonLD.WriteString(text)
+	}
+	if p.inStyle {
+		p.result.InlineStyles.CSSBytes += len(text)
+	}
+	if p.inNoscript {
+		// The tokenizer hands over <noscript> content as raw markup, and it is
+		// hidden whenever scripts run, so it is not visible text.
+		p.js.noscriptText(text)
+		return
+	}
+	if !p.inScript && !p.inStyle && !p.inTitle {
+		p.textBytes += visibleTextBytes(text)
+	}
+}
+
+func (p *parser) endTag() {
+	tn, _ := p.z.TagName()
+	switch {
+	case bytes.Equal(tn, tagSVG) && p.elem.inSVG():
+		p.elem.svgDepth--
+		if !p.elem.inSVG() {
+			p.closeSVG()
+		}
+	case bytes.Equal(tn, tagTitle):
+		p.closeTitle()
+	case bytes.Equal(tn, tagScript):
+		p.inScript = false
+		p.closeJSONLD()
+	case bytes.Equal(tn, tagStyle):
+		p.inStyle = false
+	case bytes.Equal(tn, tagNoscript):
+		p.inNoscript = false
+	case isHeading(tn):
+		if tn[1] == '1' {
+			p.closeFirstH1()
+		}
+		p.closeHeading()
+	case bytes.Equal(tn, tagA):
+		p.closeAnchor()
+	case bytes.Equal(tn, tagForm):
+		p.forms.close()
+	case bytes.Equal(tn, tagLabel):
+		p.labels.closeLabel()
+	case bytes.Equal(tn, tagTable) && !p.elem.inSVG():
+		p.closeTable()
+	}
+	p.forms.endTag(tn)
+	p.landmarks.endTag(tn)
+	p.dom.end(tn)
+}
+
+// finish flushes any state left open at the end of the document.
+func (p *parser) finish() {
+	p.closeTitle()
+	p.closeFirstH1()
+	p.closeHeading()
+	p.closeAnchor()
+	if p.elem.inSVG() {
+		p.closeSVG()
+	}
+	for len(p.tables) > 0 {
+		p.closeTable()
+	}
+	p.dom.finish()
+	p.result.DOMNodes, p.result.DOMDepth = p.dom.nodes, p.dom.maxDepth
+	p.result.MarkupWarnings, p.result.MarkupSamples = p.dom.problems, p.dom.samples
+	if p.dom.nodes > largeDOMNodes {
+		p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("DOM has %d elements; Lighthouse flags more than %d", p.dom.nodes, largeDOMNodes))
+	}
+	if p.js.requiresJavascript(p.textBytes) {
+		p.result.LikelyRequiresJavascript = true
+		p.result.Warnings = append(p.result.Warnings,
+			"page has almost no text without JavaScript; it likely renders client-side, so title, headings and links may be missing")
+	}
+	p.result.TextBytes = p.textBytes
+	if p.docBytes > 0 {
+		p.result.TextRatio = float64(p.textBytes) / float64(p.docBytes)
+	}
+	p.result.ThirdPartyDomains, p.result.Trackers = thirdPartySummary(p.thirdParty)
+	p.result.Analytics = p.analytics.products()
+	p.closeJSONLD()
+	p.result.Dates = p.dates.result()
+	p.result.UnlabeledInputs = p.labels.result()
+	p.result.BotProtection = p.bots.products()
+	p.result.Landmarks, p.result.Roles = p.landmarks.landmarks, p.landmarks.roles
+	p.result.BrokenFragments, p.result.BrokenFragmentSamples = p.targets.broken(p.result.Fragments)
+	slices.Sort(p.result.ResourceHints.Hosts)
+	p.result.LoginForm = p.forms.result()
+	p.result.HasLoginForm = p.result.LoginForm == loginFormLogin
+	p.result.HasSearchForm = p.forms.search
+	if p.forms.searchInForm {
+		p.result.SearchAction = p.resolve(p.forms.searchAction)
+	}
+	if p.emptyHrefs > 0 {
+		p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("%d anchor(s) with an empty href", p.emptyHrefs))
+	}
+	if p.droppedLinks > 0 {
+		p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("%d link(s) past the first %d were not collected", p.droppedLinks, p.opts.MaxLinks))
 	}
 }
 
-func extractAttr(z *html.Tokenizer, target []byte) string {
+// closeTitle stores the accumulated title text. Like browsers, the first
+// <title> element is the page title; later ones are only counted.
+func (p *parser) closeTitle() {
+	if p.inTitle && p.result.TitleCount == 1 {
+		p.result.Title = collapseWhitespace(p.titleText.String())
+	}
+	p.inTitle = false
+	p.titleText.Reset()
+}
+
+func (p *parser) closeFirstH1() {
+	if p.inFirstH1 {
+		p.result.FirstH1 = truncateText(p.h1Text.String(), maxH1Text)
+	}
+	p.inFirstH1 = false
+	p.h1Text.Reset()
+}
+
+// closeHeading counts the open heading as empty if it had no text.
+func (p *parser) closeHeading() {
+	if p.inHeading && !p.headingText {
+		p.result.EmptyHeadings++
+	}
+	p.inHeading, p.headingText = false, false
+}
+
+// imageAlt adds the alt text of an <img> to the open link and heading.
+func (p *parser) imageAlt(alt string) {
+	if p.anchor >= 0 {
+		// Separate alt text from neighbouring text, as a screen reader would.
+		appendText(&p.anchorText, " "+alt+" ", maxAnchorText)
+	}
+	if p.inHeading && strings.TrimSpace(alt) != "" {
+		p.headingText = true
+	}
+}
+
+func (p *parser) closeAnchor() {
+	if p.anchor >= 0 {
+		p.result.Links[p.anchor].Text = truncateText(p.anchorText.String(), maxAnchorText)
+	}
+	p.anchor = -1
+	p.anchorText.Reset()
+}
+
+func (p *parser) closeSVG() {
+	p.result.SVGBytes += p.elem.svgBytes
+	if p.elem.svgBytes > maxInlineSVGBytes {
+		p.result.OversizedSVG++
+		p.result.Warnings = append(p.result.Warnings, fmt.Sprintf("inline SVG of %d KB exceeds %d KB", p.elem.svgBytes>>10, maxInlineSVGBytes>>10))
+	}
+	p.elem.svgDepth, p.elem.svgBytes = 0, 0
+}
+
+// scanAttrs visits every attribute of the current tag, feeding each one to
+// globalAttr along the way. Tag-specific handlers pass visit to pick out the
+// attributes they need; visit may be nil.
+func (p *parser) scanAttrs(visit func(key, val []byte)) {
+	p.attrsRead = true
 	for {
-		key, val, more := z.TagAttr()
-		if bytes.Equal(key, target) {
-			return string(val)
+		key, val, more := p.z.TagAttr()
+		p.globalAttr(key, val)
+		if visit != nil {
+			visit(key, val)
 		}
 		if !more {
-			return ""
+			return
+		}
+	}
+}
+
+// globalAttr inspects attributes that matter on any element. Every start
+// tag's attributes pass through it exactly once, via scanAttrs.
+func (p *parser) globalAttr(key, val []byte) {
+	switch {
+	case isEventHandlerAttr(key):
+		p.result.InlineEventHandlers++
+	case bytes.Equal(key, attrRole):
+		p.role = string(val)
+		if hasToken(strings.ToLower(p.role), "search") {
+			p.searchRole = true
+		}
+	case bytes.Equal(key, attrID):
+		p.targets.add(string(val))
+		if appRootIDs[string(val)] {
+			p.js.appRoot = true
+		}
+	case bytes.Equal(key, attrStyle):
+		p.result.InlineStyles.Attributes++
+		p.result.InlineStyles.CSSBytes += len(val)
+	case bytes.Equal(key, attrClass):
+		p.bots.class(string(val))
+	}
+}
+
+// closeJSONLD decodes the JSON-LD block that just ended, if any. A block
+// truncated at maxJSONLDBytes fails to decode and is skipped.
+func (p *parser) closeJSONLD() {
+	if !p.inJSONLD {
+		return
+	}
+	p.inJSONLD = false
+	p.dates.jsonLDBlock(p.jsonLD.String())
+	p.jsonLD.Reset()
+}
+
+// attr scans the current tag's attributes and returns the value of target if present.
+func (p *parser) attr(target []byte) (string, bool) {
+	var val string
+	var found bool
+	p.scanAttrs(func(key, v []byte) {
+		if !found && bytes.Equal(key, target) {
+			val, found = string(v), true
+		}
+	})
+	return val, found
+}
+
+func (p *parser) addMediaLink(src string) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return
+	}
+	if link, ok := classifyLink(src, p.baseURL); ok {
+		p.result.MediaLinks = append(p.result.MediaLinks, link)
+	}
+}
+
+// closeTable pops the innermost open table and counts it if it looks like a
+// layout table.
+func (p *parser) closeTable() {
+	if len(p.tables) == 0 {
+		return
+	}
+	t := p.tables[len(p.tables)-1]
+	p.tables = p.tables[:len(p.tables)-1]
+	if t.hasNested || (!t.hasHeader && !t.hasCaption) {
+		p.result.LayoutTables++
+	}
+}
+
+// linkRel records the <link> relations whose target the analysis reports.
+// Only the first link of each relation counts.
+func (p *parser) linkRel(rel, href string) {
+	link, ok := classifyLink(strings.TrimSpace(href), p.baseURL)
+	if !ok {
+		return
+	}
+	pagination := &p.result.Pagination
+	switch {
+	case hasToken(rel, "manifest") && p.result.ManifestURL == "":
+		p.result.ManifestURL = link.URL
+	case hasToken(rel, "next") && pagination.Next == "":
+		pagination.Next = link.URL
+	case (hasToken(rel, "prev") || hasToken(rel, "previous")) && pagination.Prev == "":
+		pagination.Prev = link.URL
+	}
+	p.resourceHint(rel, link.URL)
+}
+
+// resourceHint counts the preload, prefetch, dns-prefetch and preconnect
+// tokens of a <link rel> and records the host it targets. One link may carry
+// several, as in rel="preconnect dns-prefetch".
+func (p *parser) resourceHint(rel, target string) {
+	hints := &p.result.ResourceHints
+	counts := []struct {
+		token string
+		count *int
+	}{
+		{"preload", &hints.Preload},
+		{"prefetch", &hints.Prefetch},
+		{"dns-prefetch", &hints.DNSPrefetch},
+		{"preconnect", &hints.Preconnect},
+	}
+	var hinted bool
+	for _, c := range counts {
+		if hasToken(rel, c.token) {
+			*c.count++
+			hinted = true
+		}
+	}
+	if !hinted {
+		return
+	}
+	if hasToken(rel, "preload") && !slices.Contains(p.result.PreloadURLs, target) {
+		p.result.PreloadURLs = append(p.result.PreloadURLs, target)
+	}
+	if parsed, err := url.Parse(target); err == nil && parsed.Hostname() != "" {
+		host := strings.ToLower(parsed.Hostname())
+		if !slices.Contains(hints.Hosts, host) {
+			hints.Hosts = append(hints.Hosts, host)
 		}
 	}
 }
 
+func (p *parser) metaRefresh(content string) {
+	delay, target, ok := parseRefreshContent(content)
+	if !ok {
+		return
+	}
+	refresh := &model.MetaRefresh{DelaySeconds: delay}
+	if target != "" {
+		refresh.URL = p.resolve(target)
+	}
+	p.result.MetaRefresh = refresh
+}
+
+// image records the loading hints of an <img>, the URLs of its src and srcset
+// candidates, and their third-party hosts.
+func (p *parser) image(src, srcset, loading string, hasDimensions bool) {
+	stats := &p.result.Images
+	if strings.EqualFold(strings.TrimSpace(loading), "lazy") {
+		stats.Lazy++
+	}
+	if !hasDimensions {
+		stats.MissingDimensions++
+	}
+	p.checkMixedContent(&p.result.MixedContent.Images, src)
+	p.thirdPartyRef(src, true)
+	if src = strings.TrimSpace(src); src != "" {
+		if link, ok := classifyLink(src, p.baseURL); ok {
+			p.result.ImageLinks = append(p.result.ImageLinks, link)
+		}
+	}
+	if p.srcset(srcset) {
+		stats.Srcset++
+	}
+}
+
+// srcset records the candidate URLs of an <img> or <source> srcset and
+// reports whether it had any.
+func (p *parser) srcset(srcset string) bool {
+	candidates := parseSrcset(srcset)
+	for _, c := range candidates {
+		if link, ok := classifyLink(c, p.baseURL); ok {
+			p.result.ImageLinks = append(p.result.ImageLinks, link)
+		}
+		p.thirdPartyRef(c, true)
+	}
+	return len(candidates) > 0
+}
+
+// thirdPartyRef counts a script or image reference by host if the host is
+// not the page's own.
+func (p *parser) thirdPartyRef(ref string, image bool) {
+	link, ok := classifyLink(strings.TrimSpace(ref), p.baseURL)
+	if !ok || link.IsInternal {
+		return
+	}
+	parsed, err := url.Parse(link.URL)
+	if err != nil || parsed.Hostname() == "" {
+		return
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if p.thirdParty == nil {
+		p.thirdParty = make(map[string]*model.DomainCount)
+	}
+	count := p.thirdParty[host]
+	if count == nil {
+		count = &model.DomainCount{Domain: host}
+		p.thirdParty[host] = count
+	}
+	count.Count++
+	if image {
+		count.Images++
+	} else {
+		count.Scripts++
+	}
+}
+
+// resolve resolves ref against the page URL, returning "" if it is malformed.
+// An empty ref resolves to the page itself, as a form without an action does.
+func (p *parser) resolve(ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return p.baseURL.ResolveReference(parsed).String()
+}
+
+// checkMixedContent records ref as mixed content when an https page loads it
+// over plain http. count points at the per-resource-type counter.
+func (p *parser) checkMixedContent(count *int, ref string) {
+	if p.baseURL.Scheme != "https" || ref == "" {
+		return
+	}
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return
+	}
+	resolved := p.baseURL.ResolveReference(parsed)
+	if resolved.Scheme != "http" {
+		return
+	}
+	*count++
+	mc := &p.result.MixedContent
+	if len(mc.Examples) < maxMixedContentExamples {
+		mc.Examples = append(mc.Examples, resolved.String())
+	}
+}
+
+// hasToken reports whether the space-separated list contains token, ignoring case.
+func hasToken(list, token string) bool {
+	for field := range strings.FieldsSeq(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHeading reports whether a tag name is one of h1 to h6.
+func isHeading(tn []byte) bool {
+	return len(tn) == 2 && tn[0] == 'h' && tn[1] >= '1' && tn[1] <= '6'
+}
+
+// isEventHandlerAttr reports whether an attribute name is an inline event
+// handler such as onclick or onload.
+func isEventHandlerAttr(key []byte) bool {
+	return len(key) > 2 && key[0] == 'o' && key[1] == 'n'
+}
+
+// isJavascriptURL reports whether href uses the javascript: scheme. Browsers
+// ignore ASCII tabs and newlines inside URLs, so those are stripped first.
+func isJavascriptURL(href string) bool {
+	const scheme = "javascript:"
+	href = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, href)
+	return len(href) >= len(scheme) && strings.EqualFold(href[:len(scheme)], scheme)
+}
+
+// appendText adds a text fragment to b, stopping once enough has been
+// collected to fill limit characters after whitespace collapsing.
+func appendText(b *strings.Builder, text string, limit int) {
+	if text == "" || b.Len() >= limit*4 {
+		return
+	}
+	b.WriteString(text)
+}
+
+// visibleTextBytes returns the length of text with leading and trailing
+// whitespace removed and inner whitespace runs collapsed to a single space.
+func visibleTextBytes(text string) int {
+	n := 0
+	for word := range strings.FieldsSeq(text) {
+		if n > 0 {
+			n++
+		}
+		n += len(word)
+	}
+	return n
+}
+
+// collapseWhitespace trims s and replaces each internal run of whitespace,
+// including newlines, with a single space.
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncateText collapses runs of whitespace and truncates to limit runes.
+func truncateText(raw string, limit int) string {
+	text := collapseWhitespace(raw)
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return strings.TrimSpace(string([]rune(text)[:limit]))
+}
+
 func classifyLink(href string, baseURL *url.URL) (Link, bool) {
 	parsed, err := url.Parse(href)
 	if err != nil {
@@ -120,7 +1176,13 @@ func classifyLink(href string, baseURL *url.URL) (Link, bool) {
 		return Link{}, false
 	}
 
-	isInternal := strings.EqualFold(resolved.Host, baseURL.Host)
+	// Credentials, written in the link or inherited from the base URL, are
+	// dropped so they are neither sent to the link's host nor reported.
+	resolved.User = nil
+	// Hosts are compared, and requested, in punycode, whichever form the page
+	// and the base URL use.
+	resolved.Host = asciiHost(resolved.Host)
+	isInternal := resolved.Host == asciiHost(baseURL.Host)
 	return Link{URL: resolved.String(), IsInternal: isInternal}, true
 }
 
@@ -131,18 +1193,29 @@ func detectHTMLVersion(token html.Token) string {
 	// https://www.w3.org/QA/2002/04/valid-dtd-list.html
 	data := strings.ToLower(token.Data)
 
-	if !strings.Contains(data, "public") {
-		// HTML5 doctype has no PUBLIC identifier.
-		return "HTML5"
-	}
-
 	switch {
+	case strings.Contains(data, "about:legacy-compat"):
+		// The HTML5 doctype for tools that cannot emit the short form.
+		return "HTML5"
+	case !strings.Contains(data, "public") && !strings.Contains(data, "system"):
+		// HTML5 doctype has no PUBLIC or SYSTEM identifier.
+		return "HTML5"
 	case strings.Contains(data, "xhtml 1.1") || strings.Contains(data, "xhtml basic 1.1"):
 		return "XHTML 1.1"
 	case strings.Contains(data, "xhtml 1.0"):
+		// Strict, Transitional and Frameset alike.
 		return "XHTML 1.0"
 	case strings.Contains(data, "html 4.01"):
 		return "HTML 4.01"
+	case strings.Contains(data, "html 3.2"):
+		return "HTML 3.2"
+	case strings.Contains(data, "-//ietf//dtd html 3"):
+		// The HTML 3.0 draft, which the IETF published before W3C's 3.2.
+		return "HTML 3.0"
+	case strings.Contains(data, "-//ietf//dtd html"):
+		// The other IETF DTDs, including the level and strict variants, are
+		// HTML 2.0.
+		return "HTML 2.0"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,94 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+// Analysis phases, as reported in timeout details.
+const (
+	phaseFetch     = "fetch"
+	phaseParse     = "parse"
+	phaseLinkCheck = "link_check"
+)
+
+// phaseTimer records how long each completed analysis phase took and which
+// phase is currently running, and when the analysis started.
+type phaseTimer struct {
+	start   time.Time
+	current string
+	started time.Time
+	done    []model.PhaseTiming
+}
+
+// newPhaseTimer starts timing an analysis now.
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{start: time.Now()}
+}
+
+// begin ends the running phase, if any, and starts the named one.
+func (t *phaseTimer) begin(phase string) {
+	now := time.Now()
+	if t.current != "" {
+		t.done = append(t.done, model.PhaseTiming{
+			Phase:      t.current,
+			DurationMs: now.Sub(t.started).Milliseconds(),
+		})
+	}
+	t.current, t.started = phase, now
+}
+
+// timings ends the running phase and sums the time of each phase so far.
+func (t *phaseTimer) timings() model.Timings {
+	t.begin("")
+	timings := model.Timings{TotalMs: time.Since(t.start).Milliseconds()}
+	for _, phase := range t.done {
+		switch phase.Phase {
+		case phaseFetch:
+			timings.FetchMs += phase.DurationMs
+		case phaseParse:
+			timings.ParseMs += phase.DurationMs
+		case phaseLinkCheck:
+			timings.LinkCheckMs += phase.DurationMs
+		}
+	}
+	return timings
+}
+
+// timeout builds the Timeout error for a deadline hit during the running
+// phase. linksChecked and linksTotal are only meaningful for link checking.
+func (t *phaseTimer) timeout(cause error, linksChecked, linksTotal int) *errs.AppError {
+	detail := &model.TimeoutDetail{
+		Phase:           t.current,
+		PhaseElapsedMs:  time.Since(t.started).Milliseconds(),
+		CompletedPhases: t.done,
+	}
+
+	var message string
+	switch t.current {
+	case phaseFetch:
+		message = "Analysis timed out while fetching the page. The target URL may be slow to respond."
+	case phaseParse:
+		message = "Analysis timed out while reading the page. The target URL may be slow to respond."
+	default:
+		detail.LinksChecked, detail.LinksTotal = linksChecked, linksTotal
+		message = fmt.Sprintf("Analysis timed out while checking links (%d of %d checked).", linksChecked, linksTotal)
+	}
+
+	return &errs.AppError{
+		Kind:    errs.Timeout,
+		Message: message,
+		Cause:   cause,
+		Detail:  detail,
+	}
+}
+
+// timedOut reports whether ctx ended because its deadline passed.
+func timedOut(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
@@ -0,0 +1,101 @@
+package pageinsight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+// Behind an outbound proxy every connection goes to the proxy, usually on a
+// private network itself, so the dial-time address check of safeDialer can't
+// tell what is being fetched. A proxied transport instead dials nothing but
+// the proxy, and checkedTransport resolves the target host of each request,
+// redirects included, and rejects private and reserved addresses before the
+// request is sent. The proxy resolves the host again on its side, so unlike
+// the dial-time check this one does not stop DNS rebinding; the proxy's own
+// egress rules remain the last line of defense.
+
+// proxyTransport returns a transport that sends every request through
+// proxyURL, refusing connections anywhere else, and checks request targets
+// with resolver.
+func proxyTransport(t *http.Transport, proxyURL *url.URL, resolver hostResolver) http.RoundTripper {
+	proxyAddr := canonicalProxyAddr(proxyURL)
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	t.Proxy = http.ProxyURL(proxyURL)
+	t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address != proxyAddr {
+			return nil, fmt.Errorf("%w: %s is not the outbound proxy", errBlockedAddress, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &checkedTransport{next: t, resolver: resolver}
+}
+
+// canonicalProxyAddr is the host:port the transport dials for proxyURL, the
+// port defaulting by scheme.
+func canonicalProxyAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// checkedTransport rejects requests whose target host resolves to a private
+// or reserved address, then hands them to next.
+type checkedTransport struct {
+	next     http.RoundTripper
+	resolver hostResolver
+}
+
+func (t *checkedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := checkTargetHost(req.Context(), t.resolver, req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// checkTargetHost returns errBlockedAddress if host is, or resolves to, an
+// address that may not be fetched. Hosts resolved up front for a link check
+// are not looked up again.
+func checkTargetHost(ctx context.Context, resolver hostResolver, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkTargetAddr(addr)
+	}
+	addrs, err := lookupTarget(ctx, resolver, host)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errBlockedAddress, err)
+		}
+		if err := checkTargetAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lookupTarget(ctx context.Context, resolver hostResolver, host string) ([]string, error) {
+	hosts, _ := ctx.Value(resolvedHostsKey{}).(resolvedHosts)
+	if resolved, ok := hosts[host]; ok && resolved.err == nil && len(resolved.addrs) > 0 {
+		return resolved.addrs, nil
+	}
+	return resolver.LookupHost(ctx, host)
+}
+
+func checkTargetAddr(addr netip.Addr) error {
+	if isBlockedIP(addr) {
+		return fmt.Errorf("%w: %s", errBlockedAddress, addr)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// proxyResolver resolves the hosts of the proxy tests: a public one and two
+// that point into private networks.
+func proxyResolver() *fakeResolver {
+	return &fakeResolver{addrs: map[string][]string{
+		"example.com":   {"93.184.215.14"},
+		"intranet.test": {"10.0.0.7"},
+		"mixed.test":    {"93.184.215.14", "192.168.1.1"},
+	}}
+}
+
+// testProxy is a forward proxy for plain http that serves every page itself
+// and records the URLs it was asked for. /moved redirects to the intranet.
+type testProxy struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	urls []string
+}
+
+func newTestProxy(t *testing.T) *testProxy {
+	t.Helper()
+	p := &testProxy{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.urls = append(p.urls, r.URL.String())
+		p.mu.Unlock()
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "http://intranet.test/admin", http.StatusFound)
+			return
+		}
+		_, _ = io.WriteString(w, "<html><head><title>Proxied</title></head></html>")
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *testProxy) requested() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.urls...)
+}
+
+func (p *testProxy) transport(t *testing.T) http.RoundTripper {
+	t.Helper()
+	proxyURL, err := url.Parse(p.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proxyTransport(&http.Transport{}, proxyURL, proxyResolver())
+}
+
+func TestHTTPClient_Fetch_Proxy(t *testing.T) {
+	proxy := newTestProxy(t)
+	c := &HTTPClient{client: &http.Client{Transport: proxy.transport(t), CheckRedirect: safeRedirectPolicy}}
+
+	resp, err := c.Fetch(context.Background(), "http://example.com/page")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("status = %d, body = %q, want the proxied page", resp.StatusCode, body)
+	}
+	if got := proxy.requested(); len(got) != 1 || got[0] != "http://example.com/page" {
+		t.Errorf("proxy saw %v, want the target URL", got)
+	}
+}
+
+func TestHTTPClient_Fetch_ProxyBlocksPrivateTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		wantProxied int // requests that reach the proxy before the block
+	}{
+		{name: "IP literal", url: "http://127.0.0.1/"},
+		{name: "private host", url: "http://intranet.test/"},
+		{name: "one private address", url: "http://mixed.test/"},
+		{name: "redirect to a private host", url: "http://example.com/moved", wantProxied: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newTestProxy(t)
+			c := &HTTPClient{client: &http.Client{Transport: proxy.transport(t), CheckRedirect: safeRedirectPolicy}}
+			c.SetMaxAttempts(1)
+
+			_, err := c.Fetch(context.Background(), tt.url)
+			if !errors.Is(err, errBlockedAddress) {
+				t.Errorf("err = %v, want errBlockedAddress", err)
+			}
+			if got := proxy.requested(); len(got) != tt.wantProxied {
+				t.Errorf("proxy saw %v, want %d requests", got, tt.wantProxied)
+			}
+		})
+	}
+}
+
+func TestLinkChecker_Proxy(t *testing.T) {
+	proxy := newTestProxy(t)
+	opts := DefaultLinkCheckerOptions()
+	opts.CacheTTL = 0
+	lc := newLinkChecker(opts, proxy.transport(t))
+
+	if result := lc.checkLink(context.Background(), "http://example.com/ok", nil); result.Failure != "" || result.Status != http.StatusOK {
+		t.Errorf("public link = %+v, want it checked through the proxy", result)
+	}
+	if result := lc.checkLink(context.Background(), "http://intranet.test/", nil); result.Failure != failureBlockedPrivateIP {
+		t.Errorf("private link failure = %q, want %q", result.Failure, failureBlockedPrivateIP)
+	}
+	if got := proxy.requested(); len(got) != 1 {
+		t.Errorf("proxy saw %v, want only the public link", got)
+	}
+}
+
+func TestCanonicalProxyAddr(t *testing.T) {
+	tests := map[string]string{
+		"http://proxy.corp:3128": "proxy.corp:3128",
+		"http://proxy.corp":      "proxy.corp:80",
+		"https://proxy.corp":     "proxy.corp:443",
+		"http://[::1]:8080":      "[::1]:8080",
+	}
+	for raw, want := range tests {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canonicalProxyAddr(u); got != want {
+			t.Errorf("canonicalProxyAddr(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package pageinsight
+
+import (
+	"context"
+	"net/http"
+)
+
+type redirectGuardKey struct{}
+
+// hostRefuser returns the error a fetch of a page on hostname, without a
+// port, is refused with, or nil when the host may be fetched.
+type hostRefuser func(ctx context.Context, hostname string) error
+
+// withRedirectGuard returns a context whose page fetch stops at any redirect
+// to a host refuse returns an error for. The first target is the caller's to
+// check; this covers the hops the HTTP client follows on its own.
+func withRedirectGuard(ctx context.Context, refuse hostRefuser) context.Context {
+	return context.WithValue(ctx, redirectGuardKey{}, refuse)
+}
+
+// guardRedirect returns the error the redirect req is refused with by the
+// guard of its context, or nil.
+func guardRedirect(req *http.Request) error {
+	refuse, _ := req.Context().Value(redirectGuardKey{}).(hostRefuser)
+	if refuse == nil {
+		return nil
+	}
+	return refuse(req.Context(), req.URL.Hostname())
+}
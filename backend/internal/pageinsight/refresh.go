@@ -0,0 +1,53 @@
+package pageinsight
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxRefreshDelay caps parsed refresh delays so absurd values cannot overflow.
+const maxRefreshDelay = 1 << 20
+
+// parseRefreshContent parses the content attribute of a
+// <meta http-equiv="refresh"> tag, such as "5" or "0; url='/next'", following
+// the algorithm browsers use. target is the unresolved URL, empty when the
+// page refreshes itself.
+func parseRefreshContent(content string) (delay int, target string, ok bool) {
+	s := strings.TrimLeft(content, " \t\n\f\r")
+
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if digits == 0 && !strings.HasPrefix(s, ".") {
+		return 0, "", false
+	}
+	if digits > 0 {
+		delay, _ = strconv.Atoi(s[:min(digits, 7)])
+		delay = min(delay, maxRefreshDelay)
+	}
+	// Fractional seconds are ignored, as browsers do.
+	s = strings.TrimLeft(s[digits:], "0123456789.")
+
+	s = strings.TrimLeft(s, " \t\n\f\r")
+	if s != "" && s[0] != ';' && s[0] != ',' {
+		// Anything else after the delay makes the whole value invalid.
+		return 0, "", false
+	}
+	if s != "" {
+		s = strings.TrimLeft(s[1:], " \t\n\f\r")
+	}
+
+	if len(s) >= 3 && strings.EqualFold(s[:3], "url") {
+		rest := strings.TrimLeft(s[3:], " \t\n\f\r")
+		if strings.HasPrefix(rest, "=") {
+			s = strings.TrimLeft(rest[1:], " \t\n\f\r")
+		}
+	}
+
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		quote := s[0]
+		s = s[1:]
+		if end := strings.IndexByte(s, quote); end >= 0 {
+			s = s[:end]
+		}
+	}
+	return delay, strings.TrimSpace(s), true
+}
@@ -0,0 +1,34 @@
+package pageinsight
+
+import "testing"
+
+func TestParseRefreshContent(t *testing.T) {
+	tests := []struct {
+		content    string
+		wantDelay  int
+		wantTarget string
+		wantOK     bool
+	}{
+		{content: "0;url=/new-page", wantTarget: "/new-page", wantOK: true},
+		{content: "  5 ; URL = 'https://example.com/a b' trailing", wantDelay: 5, wantTarget: "https://example.com/a b", wantOK: true},
+		{content: `3,url="next.html"`, wantDelay: 3, wantTarget: "next.html", wantOK: true},
+		{content: "1.5; url=/later", wantDelay: 1, wantTarget: "/later", wantOK: true},
+		{content: ".5;/later", wantTarget: "/later", wantOK: true},
+		{content: "0; /no-url-prefix", wantTarget: "/no-url-prefix", wantOK: true},
+		{content: "10", wantDelay: 10, wantOK: true},
+		{content: "99999999999;url=/x", wantDelay: maxRefreshDelay, wantTarget: "/x", wantOK: true},
+		{content: "", wantOK: false},
+		{content: "url=/x", wantOK: false},
+		{content: "5 seconds", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			delay, target, ok := parseRefreshContent(tt.content)
+			if ok != tt.wantOK || delay != tt.wantDelay || target != tt.wantTarget {
+				t.Errorf("parseRefreshContent(%q) = %d, %q, %v, want %d, %q, %v",
+					tt.content, delay, target, ok, tt.wantDelay, tt.wantTarget, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package pageinsight
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// robots.txt policies for Engine.CheckRobotsTxt. Warn reports a disallowed
+// page but analyzes it anyway; enforce refuses to fetch it.
+const (
+	RobotsWarn    = "warn"
+	RobotsEnforce = "enforce"
+)
+
+const (
+	// maxRobotsBytes is how much of a robots.txt is parsed, the minimum RFC
+	// 9309 asks crawlers to support.
+	maxRobotsBytes = 500 << 10
+	// robotsTimeout bounds the robots.txt fetch, so a slow one leaves the
+	// page fetch its time budget.
+	robotsTimeout = 3 * time.Second
+	// robotsCacheTTL is how long a host's robots.txt is reused, and
+	// maxRobotsEntries how many hosts are remembered.
+	robotsCacheTTL   = time.Hour
+	maxRobotsEntries = 1000
+	// robotsRetryTTL is how long a robots.txt that couldn't be loaded, and so
+	// disallows everything, is reused before it is asked for again.
+	robotsRetryTTL = time.Minute
+)
+
+// robotsRule is one allow or disallow line of a group.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsTxt is the part of a parsed robots.txt that applies to one crawler:
+// the rules of the groups naming it, or of the * groups when none does.
+// unreachable is set instead for a robots.txt that couldn't be loaded, which
+// RFC 9309 says disallows everything.
+type robotsTxt struct {
+	found       bool
+	unreachable bool
+	rules       []robotsRule
+	sitemaps    []string
+}
+
+// parseRobots reads the rules that apply to the crawler with the given
+// product token, like PageInsightBot, following RFC 9309: agent names match
+// case-insensitively, consecutive user-agent lines share the group that
+// follows them, and every matching group is merged.
+func parseRobots(r io.Reader, token string) *robotsTxt {
+	robots := &robotsTxt{found: true}
+	var named, wildcard []robotsRule
+	var anyNamed bool
+	var agents []string // the agents of the group being read
+	inRules := false
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsBytes))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty disallow allows everything, like no rule at all.
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, agent := range agents {
+				switch {
+				case agent == strings.ToLower(token):
+					named = append(named, rule)
+					anyNamed = true
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				robots.sitemaps = append(robots.sitemaps, value)
+			}
+		}
+	}
+	robots.rules = wildcard
+	if anyNamed {
+		robots.rules = named
+	}
+	return robots
+}
+
+// allows reports whether path, with its query, may be fetched. The longest
+// matching rule decides, and an allow wins a tie. /robots.txt itself is
+// always allowed.
+func (r *robotsTxt) allows(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	if r.unreachable {
+		return false
+	}
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if len(rule.pattern) < longest || !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || rule.allow {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a robots.txt path pattern, where * matches any run of
+// characters and a trailing $ anchors the end; without it the pattern is a
+// prefix.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	if !anchored {
+		pattern += "*"
+	}
+	// Greedy wildcard matching, backtracking to the last * on a mismatch.
+	p, s := 0, 0
+	star, mark := -1, 0
+	for s < len(path) {
+		switch {
+		case p < len(pattern) && pattern[p] == '*':
+			star, mark = p, s
+			p++
+		case p < len(pattern) && pattern[p] == path[s]:
+			p++
+			s++
+		case star >= 0:
+			p = star + 1
+			mark++
+			s = mark
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// robotsToken is the product token robots.txt groups name a crawler by: the
+// user agent up to its version or comment, PageInsightBot for
+// PageInsightBot/1.0.
+func robotsToken(ua string) string {
+	if ua == "" {
+		ua = userAgent
+	}
+	token, _, _ := strings.Cut(ua, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
+
+// robotsEntry is a cached robots.txt and when it expires.
+type robotsEntry struct {
+	robots  *robotsTxt
+	expires time.Time
+}
+
+// robotsCache remembers the robots.txt of each origin for robotsCacheTTL.
+type robotsCache struct {
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+	now     func() time.Time
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{entries: make(map[string]robotsEntry), now: time.Now}
+}
+
+func (c *robotsCache) get(origin string) (*robotsTxt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[origin]
+	if !ok || c.now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.robots, true
+}
+
+// put remembers robots for origin until ttl has passed.
+func (c *robotsCache) put(origin string, robots *robotsTxt, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= maxRobotsEntries {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+	if len(c.entries) >= maxRobotsEntries {
+		// Still full of live entries: make room by dropping any one.
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[origin] = robotsEntry{robots: robots, expires: now.Add(ttl)}
+}
+
+// robotsChecker looks up whether pages may be fetched, fetching each
+// origin's robots.txt through the engine's fetcher, so it gets the same SSRF
+// protection as the page itself.
+type robotsChecker struct {
+	policy string
+	token  string
+	cache  *robotsCache
+}
+
+// robotsFor returns the robots.txt of target's origin. A missing one, any
+// other 4xx, or one this server refuses to fetch, like a redirect to a
+// blocked host, has no rules, so everything is allowed. A 5xx or a fetch
+// that fails, say on a timeout, leaves the site's rules unknown, so
+// everything is disallowed, and only for robotsRetryTTL.
+func (rc *robotsChecker) robotsFor(ctx context.Context, fetcher Fetcher, target *url.URL) *robotsTxt {
+	origin := strings.ToLower(target.Scheme) + "://" + asciiHost(target.Host)
+	if robots, ok := rc.cache.get(origin); ok {
+		return robots
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, robotsTimeout)
+	defer cancel()
+	robots := &robotsTxt{}
+	resp, err := fetcher.Fetch(fetchCtx, origin+"/robots.txt")
+	switch {
+	case err != nil:
+		robots.unreachable = !refusedFetch(err)
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		robots = parseRobots(resp.Body, rc.token)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		robots.unreachable = true
+	}
+	if resp != nil {
+		_ = resp.Body.Close()
+	}
+	// A fetch cut short by the analysis ending says nothing about the site.
+	if ctx.Err() == nil {
+		ttl := robotsCacheTTL
+		if robots.unreachable {
+			ttl = robotsRetryTTL
+		}
+		rc.cache.put(origin, robots, ttl)
+	}
+	return robots
+}
+
+// refusedFetch reports whether err is this server declining the fetch,
+// rather than the site failing to answer.
+func refusedFetch(err error) bool {
+	for _, refused := range []error{errBlockedHost, errSelfTarget, errBlockedAddress, errBlockedRedirect, errTooManyRedirects} {
+		if errors.Is(err, refused) {
+			return true
+		}
+	}
+	return false
+}
+
+// report describes robots for the analysis result.
+func (r *robotsTxt) report(allowed bool) *model.RobotsTxt {
+	return &model.RobotsTxt{Found: r.found, Disallowed: !allowed, Sitemaps: r.sitemaps}
+}
+
+// requestPath is the path and query of target as robots.txt rules see it.
+func requestPath(target *url.URL) string {
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if target.RawQuery != "" {
+		path += "?" + target.RawQuery
+	}
+	return path
+}
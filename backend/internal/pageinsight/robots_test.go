@@ -0,0 +1,194 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `# A comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: pageinsightbot
+User-agent: OtherBot
+Disallow: /private
+Allow: /private/press
+Disallow: /*.pdf$
+Disallow: /search?q=
+
+User-agent: *
+Disallow: /everything-for-others
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/news.xml
+`
+
+func TestParseRobots_Allows(t *testing.T) {
+	robots := parseRobots(strings.NewReader(testRobots), "PageInsightBot")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/", want: true},
+		{path: "/everything-for-others", want: true},
+		{path: "/private", want: false},
+		{path: "/private/team", want: false},
+		{path: "/private/press", want: true},
+		{path: "/private/press/2024", want: true},
+		{path: "/docs/guide.pdf", want: false},
+		{path: "/docs/guide.pdf?download=1", want: true},
+		{path: "/search?q=shoes", want: false},
+		{path: "/search", want: true},
+		{path: "/robots.txt", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := robots.allows(tt.path); got != tt.want {
+				t.Errorf("allows(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	want := []string{"https://example.com/sitemap.xml", "https://example.com/news.xml"}
+	if !robots.found || !slices.Equal(robots.sitemaps, want) {
+		t.Errorf("found, sitemaps = %v, %v, want true, %v", robots.found, robots.sitemaps, want)
+	}
+}
+
+func TestParseRobots_FallsBackToWildcardGroup(t *testing.T) {
+	robots := parseRobots(strings.NewReader(testRobots), "UnknownBot")
+	if robots.allows("/everything-for-others") || !robots.allows("/private") {
+		t.Error("UnknownBot should follow the * group only")
+	}
+}
+
+func TestParseRobots_EqualLengthAllowWins(t *testing.T) {
+	robots := parseRobots(strings.NewReader("User-agent: *\nDisallow: /page\nAllow: /page\n"), "PageInsightBot")
+	if !robots.allows("/page") {
+		t.Error("allows(/page) = false, want the allow rule to win a tie")
+	}
+}
+
+func TestParseRobots_EmptyDisallowAllowsAll(t *testing.T) {
+	robots := parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "PageInsightBot")
+	if !robots.allows("/anything") {
+		t.Error("allows(/anything) = false, want an empty Disallow to allow everything")
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{pattern: "/fish", path: "/fish.html", want: true},
+		{pattern: "/fish", path: "/Fish.html", want: false},
+		{pattern: "/fish$", path: "/fish", want: true},
+		{pattern: "/fish$", path: "/fish/", want: false},
+		{pattern: "/*.php", path: "/folder/index.php?x=1", want: true},
+		{pattern: "/*.php$", path: "/folder/index.php?x=1", want: false},
+		{pattern: "/fish*salmon", path: "/fish/and/salmon/", want: true},
+		{pattern: "/fish*salmon", path: "/fishtrout", want: false},
+		{pattern: "*", path: "/anything", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsToken(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "PageInsightBot",
+		"PageInsightBot/1.0":               "PageInsightBot",
+		"AcmeAudit/2.1 (+https://acme.io)": "AcmeAudit",
+		"SimpleBot":                        "SimpleBot",
+	}
+	for ua, want := range tests {
+		if got := robotsToken(ua); got != want {
+			t.Errorf("robotsToken(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestRobotsCache_Expires(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newRobotsCache()
+	cache.now = func() time.Time { return now }
+
+	cache.put("https://example.com", &robotsTxt{found: true}, robotsCacheTTL)
+	if _, ok := cache.get("https://example.com"); !ok {
+		t.Fatal("get right after put missed")
+	}
+	now = now.Add(robotsCacheTTL + time.Second)
+	if _, ok := cache.get("https://example.com"); ok {
+		t.Error("get after the TTL hit, want a miss")
+	}
+}
+
+func TestRobotsChecker_RobotsFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		fetcher     *mockFetcher
+		wantAllowed bool
+		wantTTL     time.Duration
+	}{
+		{
+			name:        "found",
+			fetcher:     &mockFetcher{body: "User-agent: *\nDisallow: /admin\n", statusCode: http.StatusOK},
+			wantAllowed: true, wantTTL: robotsCacheTTL,
+		},
+		{
+			name:        "missing",
+			fetcher:     &mockFetcher{statusCode: http.StatusNotFound},
+			wantAllowed: true, wantTTL: robotsCacheTTL,
+		},
+		{
+			name:        "server error",
+			fetcher:     &mockFetcher{statusCode: http.StatusServiceUnavailable},
+			wantAllowed: false, wantTTL: robotsRetryTTL,
+		},
+		{
+			name:        "unreachable",
+			fetcher:     &mockFetcher{err: errors.New("dial tcp: connection refused")},
+			wantAllowed: false, wantTTL: robotsRetryTTL,
+		},
+		{
+			name:        "refused by this server",
+			fetcher:     &mockFetcher{err: fmt.Errorf("redirect: %w", errBlockedHost)},
+			wantAllowed: true, wantTTL: robotsCacheTTL,
+		},
+	}
+	target, _ := url.Parse("https://example.com/page")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			cache := newRobotsCache()
+			cache.now = func() time.Time { return now }
+			rc := &robotsChecker{token: "PageInsightBot", cache: cache}
+
+			if got := rc.robotsFor(context.Background(), tt.fetcher, target).allows("/page"); got != tt.wantAllowed {
+				t.Errorf("allows(/page) = %v, want %v", got, tt.wantAllowed)
+			}
+			now = now.Add(tt.wantTTL - time.Second)
+			if _, ok := cache.get("https://example.com"); !ok {
+				t.Errorf("cache missed before %v", tt.wantTTL)
+			}
+			now = now.Add(2 * time.Second)
+			if _, ok := cache.get("https://example.com"); ok {
+				t.Errorf("cache hit after %v, want a miss", tt.wantTTL)
+			}
+		})
+	}
+}
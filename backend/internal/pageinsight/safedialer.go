@@ -24,6 +24,7 @@ var reservedPrefixes = []netip.Prefix{
 	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking (RFC 2544)
 	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2 (RFC 5737)
 	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3 (RFC 5737)
+	netip.MustParsePrefix("2001:db8::/32"),   // IPv6 documentation (RFC 3849)
 }
 
 // safeDialer returns a net.Dialer whose Control function rejects connections
@@ -43,6 +43,11 @@ func TestIsBlockedIP(t *testing.T) {
 		{name: "benchmark 198.18.x.x", ip: "198.18.0.1", blocked: true},
 		{name: "benchmark 198.19.x.x", ip: "198.19.255.254", blocked: true},
 
+		// IPv6 unique local (fc00::/7) and documentation ranges
+		{name: "ULA fc00::/8", ip: "fc00::1", blocked: true},
+		{name: "ULA fd00::/8", ip: "fd12:3456:789a::1", blocked: true},
+		{name: "IPv6 documentation", ip: "2001:db8::1", blocked: true},
+
 		// Unspecified
 		{name: "unspecified IPv4", ip: "0.0.0.0", blocked: true},
 		{name: "unspecified IPv6", ip: "::", blocked: true},
@@ -59,6 +64,7 @@ func TestIsBlockedIP(t *testing.T) {
 		{name: "public IPv4", ip: "93.184.216.34", blocked: false},
 		{name: "public range near CGN", ip: "100.63.255.255", blocked: false},
 		{name: "public range after CGN", ip: "100.128.0.1", blocked: false},
+		{name: "public IPv6", ip: "2606:4700:4700::1111", blocked: false},
 	}
 
 	for _, tt := range tests {
@@ -87,6 +93,9 @@ func TestBlockPrivateAddresses(t *testing.T) {
 		{name: "invalid address no port", address: "127.0.0.1", wantErr: true},
 		{name: "IPv6 bracket format", address: "[::1]:80", wantErr: true},
 		{name: "mapped IPv4 loopback", address: "[::ffff:127.0.0.1]:80", wantErr: true},
+		{name: "public IPv6 bracket format", address: "[2606:4700:4700::1111]:443", wantErr: false},
+		{name: "IPv6 ULA", address: "[fd00::1]:443", wantErr: true},
+		{name: "IPv6 with zone", address: "[fe80::1%eth0]:443", wantErr: true},
 	}
 
 	for _, tt := range tests {
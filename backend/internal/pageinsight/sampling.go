@@ -0,0 +1,55 @@
+package pageinsight
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+)
+
+// What a LinkChecker does with the links past MaxLinks. Truncating keeps the
+// first MaxLinks in document order, which biases the inaccessible count
+// toward the top of the page; sampling checks a uniform random MaxLinks of
+// them instead, so the count can be extrapolated to the whole page.
+const (
+	OverflowTruncate = "truncate"
+	OverflowSample   = "sample"
+)
+
+// sampleLinks picks n of links uniformly at random, kept in their original
+// order. The choice is seeded by the request id, or by the links themselves
+// when there is none, so the same request always checks the same sample.
+func sampleLinks(ctx context.Context, links []string, n int) []string {
+	rng := rand.New(rand.NewPCG(sampleSeed(ctx, links), 0)) //nolint:gosec // sampling, not security
+	picked := rng.Perm(len(links))[:n]
+	slices.Sort(picked)
+	sample := make([]string, n)
+	for i, index := range picked {
+		sample[i] = links[index]
+	}
+	return sample
+}
+
+func sampleSeed(ctx context.Context, links []string) uint64 {
+	h := fnv.New64a()
+	if id := requestid.FromContext(ctx); id != "" {
+		_, _ = h.Write([]byte(id))
+		return h.Sum64()
+	}
+	for _, link := range links {
+		_, _ = h.Write([]byte(link))
+		_, _ = h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// extrapolate scales the inaccessible count of a sample of checked links to
+// all total links, rounding to the nearest link.
+func extrapolate(inaccessible, checked, total int) int {
+	if checked == 0 || inaccessible <= 0 {
+		return inaccessible
+	}
+	return (inaccessible*total*2 + checked) / (checked * 2)
+}
@@ -0,0 +1,63 @@
+package pageinsight
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/requestid"
+)
+
+func TestSampleLinks(t *testing.T) {
+	links := make([]string, 100)
+	for i := range links {
+		links[i] = fmt.Sprintf("https://example.com/%03d", i)
+	}
+	ctx := requestid.NewContext(context.Background(), "req-1")
+
+	sample := sampleLinks(ctx, links, 10)
+
+	if len(sample) != 10 {
+		t.Fatalf("len(sample) = %d, want 10", len(sample))
+	}
+	if !slices.IsSorted(sample) || len(slices.Compact(slices.Clone(sample))) != 10 {
+		t.Errorf("sample = %v, want 10 distinct links in document order", sample)
+	}
+	if slices.Equal(sample, links[:10]) {
+		t.Errorf("sample = %v, want a random pick rather than the first links", sample)
+	}
+	if again := sampleLinks(ctx, links, 10); !slices.Equal(again, sample) {
+		t.Errorf("sample for the same request id = %v, want %v", again, sample)
+	}
+	other := sampleLinks(requestid.NewContext(context.Background(), "req-2"), links, 10)
+	if slices.Equal(other, sample) {
+		t.Errorf("another request id sampled the same links %v", other)
+	}
+	noID := sampleLinks(context.Background(), links, 10)
+	if again := sampleLinks(context.Background(), links, 10); !slices.Equal(again, noID) {
+		t.Errorf("sample without a request id = %v, want %v", again, noID)
+	}
+}
+
+func TestExtrapolate(t *testing.T) {
+	tests := []struct {
+		inaccessible, checked, total int
+		want                         int
+	}{
+		{inaccessible: 10, checked: 1000, total: 5000, want: 50},
+		{inaccessible: 1, checked: 3, total: 10, want: 3},
+		{inaccessible: 1, checked: 3, total: 11, want: 4},
+		{inaccessible: 0, checked: 1000, total: 5000, want: 0},
+		{inaccessible: notChecked, checked: 0, total: 5000, want: notChecked},
+		{inaccessible: 4, checked: 0, total: 5000, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d of %d scaled to %d", tt.inaccessible, tt.checked, tt.total), func(t *testing.T) {
+			if got := extrapolate(tt.inaccessible, tt.checked, tt.total); got != tt.want {
+				t.Errorf("extrapolate = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package pageinsight
+
+import (
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// guessScheme turns a URL pasted without its scheme, like
+// example.com/pricing, into an https URL. It returns false when raw has a
+// scheme or doesn't start with something that looks like a host: a dotted
+// hostname, localhost or an IP address, with an optional port.
+func guessScheme(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.Contains(raw, "://") || strings.ContainsAny(raw, " \t\r\n@\\") {
+		return "", false
+	}
+	guessed := "https://" + raw
+	u, err := url.Parse(guessed)
+	if err != nil || u.Host == "" || !plausibleHost(u.Hostname()) {
+		return "", false
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", false
+		}
+	}
+	return guessed, true
+}
+
+// plausibleHost reports whether host is an IP address, localhost, or a
+// hostname of at least two labels, each made of letters, digits and inner
+// hyphens, with a top-level label that isn't all digits.
+func plausibleHost(host string) bool {
+	if _, err := netip.ParseAddr(host); err == nil {
+		return true
+	}
+	host = asciiHostname(host)
+	if host == "localhost" {
+		return true
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
@@ -0,0 +1,113 @@
+package pageinsight
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+func TestGuessScheme(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // "" when no scheme is guessed
+	}{
+		{"example.com", "https://example.com"},
+		{"example.com/pricing", "https://example.com/pricing"},
+		{"  www.example.co.uk/a?b=c#d ", "https://www.example.co.uk/a?b=c#d"},
+		{"example.com:8443/admin", "https://example.com:8443/admin"},
+		{"localhost:3000", "https://localhost:3000"},
+		{"93.184.215.14/page", "https://93.184.215.14/page"},
+		{"münchen.example/karte", "https://münchen.example/karte"},
+		{"https://example.com", ""},
+		{"ftp://example.com", ""},
+		{"not a url at all", ""},
+		{"example.com/a page", ""},
+		{"example", ""},
+		{"example.com:99999", ""},
+		{"example.com:port", ""},
+		{"user@example.com", ""},
+		{"mailto:someone@example.com", ""},
+		{"javascript:alert(1)", ""},
+		{"-bad-.example", ""},
+		{"1.2.3", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := guessScheme(tt.raw)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Errorf("guessScheme(%q) = %q, %v, want %q", tt.raw, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+// httpsDownFetcher serves pages over http only; https requests fail the TLS
+// handshake.
+type httpsDownFetcher struct {
+	pages   pagesFetcher
+	fetched []string
+}
+
+func (f *httpsDownFetcher) Fetch(ctx context.Context, url string) (*Response, error) {
+	f.fetched = append(f.fetched, url)
+	if strings.HasPrefix(url, "https://") {
+		return nil, tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}
+	}
+	return f.pages.Fetch(ctx, url)
+}
+
+func TestEngine_Analyze_GuessesScheme(t *testing.T) {
+	page := `<html><head><title>Pricing</title></head></html>`
+	t.Run("https", func(t *testing.T) {
+		engine := NewEngine(pagesFetcher{"https://example.com/pricing": page}, &mockLinkChecker{})
+		result, err := engine.Analyze(context.Background(), "example.com/pricing", model.AnalyzeOptions{})
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		if result.URL != "https://example.com/pricing" || result.Title != "Pricing" {
+			t.Errorf("URL = %q, title = %q, want the https page", result.URL, result.Title)
+		}
+		if !slices.ContainsFunc(result.Warnings, func(w string) bool { return strings.Contains(w, "no scheme") }) {
+			t.Errorf("warnings = %v, want one about the missing scheme", result.Warnings)
+		}
+	})
+
+	t.Run("falls back to http", func(t *testing.T) {
+		fetcher := &httpsDownFetcher{pages: pagesFetcher{"http://example.com/pricing": page}}
+		engine := NewEngine(fetcher, &mockLinkChecker{})
+		result, err := engine.Analyze(context.Background(), "example.com/pricing", model.AnalyzeOptions{})
+		if err != nil {
+			t.Fatalf("Analyze: %v", err)
+		}
+		if result.URL != "http://example.com/pricing" {
+			t.Errorf("URL = %q, want the http page", result.URL)
+		}
+	})
+
+	t.Run("no fallback for an explicit https URL", func(t *testing.T) {
+		fetcher := &httpsDownFetcher{pages: pagesFetcher{"http://example.com/pricing": page}}
+		engine := NewEngine(fetcher, &mockLinkChecker{})
+		_, err := engine.Analyze(context.Background(), "https://example.com/pricing", model.AnalyzeOptions{})
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.Unreachable || len(fetcher.fetched) != 1 {
+			t.Errorf("err = %v after fetching %v, want Unreachable after the https attempt alone", err, fetcher.fetched)
+		}
+	})
+
+	t.Run("garbage", func(t *testing.T) {
+		engine := NewEngine(pagesFetcher{}, &mockLinkChecker{})
+		_, err := engine.Analyze(context.Background(), "not a url at all", model.AnalyzeOptions{})
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput {
+			t.Errorf("err = %v, want InvalidInput", err)
+		}
+	})
+}
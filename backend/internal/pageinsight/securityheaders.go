@@ -0,0 +1,45 @@
+package pageinsight
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// maxSecurityHeaderValue caps each reported header value; a
+// Content-Security-Policy can run to many kilobytes.
+const maxSecurityHeaderValue = 512
+
+// securityHeaders reports the security-related headers of the page response.
+// A header sent more than once is reported with its values joined by commas.
+func securityHeaders(h http.Header) *model.SecurityHeaders {
+	return &model.SecurityHeaders{
+		StrictTransportSecurity: headerValue(h, "Strict-Transport-Security"),
+		ContentSecurityPolicy:   headerValue(h, "Content-Security-Policy"),
+		XFrameOptions:           headerValue(h, "X-Frame-Options"),
+		XContentTypeOptions:     headerValue(h, "X-Content-Type-Options"),
+		ReferrerPolicy:          headerValue(h, "Referrer-Policy"),
+		PermissionsPolicy:       headerValue(h, "Permissions-Policy"),
+	}
+}
+
+// headerValue returns the value of the named header, cut to
+// maxSecurityHeaderValue bytes on a character boundary, or nil when the
+// response did not send it.
+func headerValue(h http.Header, name string) *string {
+	values := h.Values(name)
+	if len(values) == 0 {
+		return nil
+	}
+	value := strings.Join(values, ", ")
+	if len(value) > maxSecurityHeaderValue {
+		cut := maxSecurityHeaderValue
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+		value = value[:cut]
+	}
+	return &value
+}
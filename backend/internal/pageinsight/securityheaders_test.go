@@ -0,0 +1,77 @@
+package pageinsight
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	h.Set("X-Frame-Options", "")
+	h.Add("Content-Security-Policy", "default-src 'self'")
+	h.Add("Content-Security-Policy", "frame-ancestors 'none'")
+
+	got := securityHeaders(h)
+	tests := []struct {
+		name  string
+		value *string
+		want  string
+		sent  bool
+	}{
+		{name: "HSTS", value: got.StrictTransportSecurity, want: "max-age=63072000; includeSubDomains", sent: true},
+		{name: "empty X-Frame-Options", value: got.XFrameOptions, want: "", sent: true},
+		{name: "repeated CSP", value: got.ContentSecurityPolicy, want: "default-src 'self', frame-ancestors 'none'", sent: true},
+		{name: "absent Referrer-Policy", value: got.ReferrerPolicy},
+		{name: "absent Permissions-Policy", value: got.PermissionsPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.value != nil) != tt.sent {
+				t.Fatalf("value = %v, want sent = %v", tt.value, tt.sent)
+			}
+			if tt.sent && *tt.value != tt.want {
+				t.Errorf("value = %q, want %q", *tt.value, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityHeaders_TruncatesLongValues(t *testing.T) {
+	h := http.Header{}
+	// A multi-byte character straddling the cap must not be split.
+	h.Set("Content-Security-Policy", strings.Repeat("a", maxSecurityHeaderValue-1)+"é"+strings.Repeat("b", 100))
+
+	got := *securityHeaders(h).ContentSecurityPolicy
+	if len(got) != maxSecurityHeaderValue-1 || got != strings.Repeat("a", maxSecurityHeaderValue-1) {
+		t.Errorf("truncated CSP has %d bytes, want %d without a split character", len(got), maxSecurityHeaderValue-1)
+	}
+}
+
+// headerFetcher serves a page with the given response headers.
+type headerFetcher http.Header
+
+func (f headerFetcher) Fetch(_ context.Context, _ string) (*Response, error) {
+	return &Response{
+		Body:       io.NopCloser(strings.NewReader("<html></html>")),
+		StatusCode: http.StatusOK,
+		Header:     http.Header(f),
+	}, nil
+}
+
+func TestEngine_Analyze_SecurityHeaders(t *testing.T) {
+	engine := NewEngine(headerFetcher{"X-Content-Type-Options": {"nosniff"}}, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), "https://example.com", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sh := result.SecurityHeaders
+	if sh == nil || sh.XContentTypeOptions == nil || *sh.XContentTypeOptions != "nosniff" || sh.StrictTransportSecurity != nil {
+		t.Errorf("security headers = %+v, want only X-Content-Type-Options", sh)
+	}
+}
@@ -0,0 +1,71 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+)
+
+var errSelfTarget = errors.New("target is this server")
+
+// selfGuard recognizes URLs that lead back to this server, whose analysis
+// would have it call itself. A host is this server when it matches one of
+// the operator's hostnames for it, which is the only way to recognize a
+// public name behind a load balancer, or when it resolves to an address of
+// one of the machine's network interfaces. A nil guard recognizes nothing.
+type selfGuard struct {
+	hostnames *hostBlocklist
+	addrs     map[netip.Addr]bool
+	resolver  hostResolver
+}
+
+// newSelfGuard takes the server's own hostnames, exact or *.suffix wildcards
+// like BlockHosts, and reads the addresses of its network interfaces.
+func newSelfGuard(hostnames []string) *selfGuard {
+	g := &selfGuard{
+		hostnames: newHostBlocklist(hostnames),
+		addrs:     make(map[netip.Addr]bool),
+		resolver:  net.DefaultResolver,
+	}
+	ifaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return g
+	}
+	for _, addr := range ifaceAddrs {
+		if prefix, err := netip.ParsePrefix(addr.String()); err == nil {
+			g.addrs[prefix.Addr().Unmap()] = true
+		}
+	}
+	return g
+}
+
+// targets reports whether hostname, without a port, is this server. A name
+// that doesn't resolve is not; the fetch reports that failure on its own.
+func (g *selfGuard) targets(ctx context.Context, hostname string) bool {
+	if g == nil {
+		return false
+	}
+	if g.hostnames.blocks(hostname) {
+		return true
+	}
+	addrs := []string{hostname}
+	if _, err := netip.ParseAddr(hostname); err != nil {
+		resolved, err := g.resolver.LookupHost(ctx, hostname)
+		if err != nil {
+			return false
+		}
+		addrs = resolved
+	}
+	for _, raw := range addrs {
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			continue
+		}
+		// Connecting to the unspecified address reaches the local machine.
+		if addr = addr.Unmap().WithZone(""); addr.IsUnspecified() || g.addrs[addr] {
+			return true
+		}
+	}
+	return false
+}
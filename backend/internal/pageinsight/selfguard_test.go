@@ -0,0 +1,171 @@
+package pageinsight
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+	"github.com/Bahjat/page-insight-tool/backend/internal/platform/errs"
+)
+
+func TestEngine_Analyze_RejectsSelf(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("<html><title>API</title></html>"))
+	}))
+	defer server.Close()
+	listener := netip.MustParseAddrPort(server.Listener.Addr().String())
+
+	// Every hostname reaches the server, so only the guard keeps it from
+	// being fetched.
+	dialer := &net.Dialer{}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, listener.String())
+		},
+	}}
+	engine := NewEngine(&HTTPClient{client: client}, &mockLinkChecker{})
+	engine.self = &selfGuard{
+		hostnames: newHostBlocklist([]string{"*.insight.example"}),
+		addrs:     map[netip.Addr]bool{listener.Addr(): true},
+		resolver: &fakeResolver{addrs: map[string][]string{
+			"tool.example":   {listener.Addr().String()},
+			"public.example": {"192.0.2.10"},
+		}},
+	}
+
+	for _, target := range []string{"http://tool.example/analyze", "http://api.insight.example/", "http://0.0.0.0/"} {
+		_, err := engine.Analyze(context.Background(), target, model.AnalyzeOptions{})
+		var appErr *errs.AppError
+		if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput || !errors.Is(err, errSelfTarget) {
+			t.Errorf("Analyze(%s) err = %v, want an InvalidInput AppError for this server", target, err)
+		}
+	}
+	if got := hits.Load(); got != 0 {
+		t.Errorf("server was fetched %d times, want none", got)
+	}
+
+	if _, err := engine.Analyze(context.Background(), "http://public.example/", model.AnalyzeOptions{}); err != nil {
+		t.Errorf("Analyze of another host: %v", err)
+	}
+}
+
+func TestEngine_Analyze_RejectsSelfBeforeRobots(t *testing.T) {
+	var selfHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "public.example" {
+			// robots.txt and the page alike redirect back to this server.
+			http.Redirect(w, r, "http://tool.example"+r.URL.Path, http.StatusFound)
+			return
+		}
+		selfHits.Add(1)
+		_, _ = w.Write([]byte("User-agent: *\nAllow: /\n"))
+	}))
+	defer server.Close()
+	listener := netip.MustParseAddrPort(server.Listener.Addr().String())
+
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, listener.String())
+			},
+		},
+		CheckRedirect: safeRedirectPolicy,
+	}
+	for _, policy := range []string{RobotsWarn, RobotsEnforce} {
+		t.Run(policy, func(t *testing.T) {
+			engine := NewEngine(&HTTPClient{client: client}, &mockLinkChecker{})
+			engine.CheckRobotsTxt(policy, "")
+			engine.self = &selfGuard{
+				addrs: map[netip.Addr]bool{listener.Addr(): true},
+				resolver: &fakeResolver{addrs: map[string][]string{
+					"tool.example":   {listener.Addr().String()},
+					"public.example": {"192.0.2.10"},
+				}},
+			}
+
+			for _, target := range []string{"http://tool.example/analyze", "http://public.example/"} {
+				_, err := engine.Analyze(context.Background(), target, model.AnalyzeOptions{})
+				if !errors.Is(err, errSelfTarget) {
+					t.Errorf("Analyze(%s) err = %v, want the request for this server refused", target, err)
+				}
+			}
+			if got := selfHits.Swap(0); got != 0 {
+				t.Errorf("server was fetched as itself %d times, want none", got)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_RejectsRedirectToSelf(t *testing.T) {
+	var selfHits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host == "public.example" {
+			http.Redirect(w, r, "http://tool.example/v1/analyze?url=http://public.example/", http.StatusFound)
+			return
+		}
+		selfHits.Add(1)
+		_, _ = w.Write([]byte("<html><title>API</title></html>"))
+	}))
+	defer server.Close()
+	listener := netip.MustParseAddrPort(server.Listener.Addr().String())
+
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, listener.String())
+			},
+		},
+		CheckRedirect: safeRedirectPolicy,
+	}
+	engine := NewEngine(&HTTPClient{client: client}, &mockLinkChecker{})
+	engine.self = &selfGuard{
+		addrs: map[netip.Addr]bool{listener.Addr(): true},
+		resolver: &fakeResolver{addrs: map[string][]string{
+			"tool.example":   {listener.Addr().String()},
+			"public.example": {"192.0.2.10"},
+		}},
+	}
+
+	_, err := engine.Analyze(context.Background(), "http://public.example/", model.AnalyzeOptions{})
+	var appErr *errs.AppError
+	if !errors.As(err, &appErr) || appErr.Kind != errs.InvalidInput || !errors.Is(err, errSelfTarget) {
+		t.Errorf("err = %v, want an InvalidInput AppError for this server", err)
+	}
+	if got := selfHits.Load(); got != 0 {
+		t.Errorf("server was fetched as itself %d times, want none", got)
+	}
+}
+
+func TestSelfGuard_Targets(t *testing.T) {
+	g := &selfGuard{
+		addrs:    map[netip.Addr]bool{netip.MustParseAddr("203.0.113.7"): true, netip.MustParseAddr("2001:db8::7"): true},
+		resolver: &fakeResolver{addrs: map[string][]string{"mixed.example": {"192.0.2.1", "203.0.113.7"}}},
+	}
+	tests := map[string]bool{
+		"203.0.113.7":        true,
+		"::ffff:203.0.113.7": true,
+		"2001:db8::7":        true,
+		"::":                 true,
+		"mixed.example":      true,
+		"192.0.2.1":          false,
+		"gone.example":       false,
+	}
+	for host, want := range tests {
+		if got := g.targets(context.Background(), host); got != want {
+			t.Errorf("targets(%s) = %v, want %v", host, got, want)
+		}
+	}
+	if (*selfGuard)(nil).targets(context.Background(), "203.0.113.7") {
+		t.Error("a nil guard recognized a host")
+	}
+}
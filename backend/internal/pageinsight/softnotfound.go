@@ -0,0 +1,36 @@
+package pageinsight
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// notFoundPattern matches the ways error pages say that nothing is there, in
+// a title or first heading. It is the one list of such phrases.
+var notFoundPattern = regexp.MustCompile(`(?i)\b(?:` +
+	`404|not found|` +
+	`page (?:cannot|can[’']t|could not|couldn[’']t) be found|` +
+	`(?:page|file) (?:does not|doesn[’']t) exist|` +
+	`no longer (?:exists|available)|` +
+	`nothing (?:was )?found|` +
+	`seite nicht gefunden|página no encontrada|page introuvable` +
+	`)\b`)
+
+// softNotFoundTextBytes is the visible text size below which a page that
+// isn't an app shell or a redirect stub likely says no more than "not found".
+const softNotFoundTextBytes = 100
+
+// softNotFound returns why a page served with a success status looks like an
+// error page, or "" when it doesn't.
+func softNotFound(parse *ParseResult) string {
+	switch {
+	case notFoundPattern.MatchString(parse.Title):
+		return fmt.Sprintf("its title %q reads like an error", parse.Title)
+	case notFoundPattern.MatchString(parse.FirstH1):
+		return fmt.Sprintf("its first heading %q reads like an error", parse.FirstH1)
+	case parse.TextBytes < softNotFoundTextBytes && !parse.LikelyRequiresJavascript && parse.MetaRefresh == nil:
+		return fmt.Sprintf("it has only %d bytes of text", parse.TextBytes)
+	default:
+		return ""
+	}
+}
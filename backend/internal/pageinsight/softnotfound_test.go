@@ -0,0 +1,84 @@
+package pageinsight
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+func TestSoftNotFound_Fixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string // a substring of the reason, or "" for a real page
+	}{
+		{"wordpress.html", "title"},
+		{"drupal.html", "first heading"},
+		{"shopify.html", "title"},
+		{"tiny.html", "bytes of text"},
+		{"article.html", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "soft404", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			parse, err := Parse(f, mustParseURL("https://example.com/missing"))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			got := softNotFound(parse)
+			if tt.want == "" && got != "" {
+				t.Errorf("softNotFound = %q, want a real page", got)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("softNotFound = %q, want a reason mentioning %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSoftNotFound(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse ParseResult
+		want  bool
+	}{
+		{"curly apostrophe", ParseResult{FirstH1: "This page can’t be found", TextBytes: 500}, true},
+		{"translated", ParseResult{Title: "Seite nicht gefunden", TextBytes: 500}, true},
+		{"number inside a word", ParseResult{Title: "Model X4040 review", TextBytes: 500}, false},
+		{"app shell", ParseResult{Title: "Dashboard", LikelyRequiresJavascript: true}, false},
+		{"refresh stub", ParseResult{MetaRefresh: &model.MetaRefresh{URL: "https://example.com/"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := softNotFound(&tt.parse); (got != "") != tt.want {
+				t.Errorf("softNotFound = %q, want flagged %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Analyze_SoftNotFound(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "soft404", "shopify.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := NewEngine(&mockFetcher{body: string(body), statusCode: 200}, &mockLinkChecker{})
+
+	result, err := engine.Analyze(context.Background(), "https://example.com/products/gone", model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if !result.LikelySoft404 {
+		t.Error("LikelySoft404 = false, want true")
+	}
+	if len(result.Warnings) == 0 || !strings.Contains(result.Warnings[len(result.Warnings)-1], "not-found page") {
+		t.Errorf("warnings = %q, want one about the soft 404", result.Warnings)
+	}
+}
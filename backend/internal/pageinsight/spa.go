@@ -0,0 +1,41 @@
+package pageinsight
+
+import "strings"
+
+const (
+	// shellTextBytes is the visible text size below which a page counts as
+	// an empty shell that is filled in by JavaScript.
+	shellTextBytes = 150
+	// largeInlineScriptBytes is the inline script size that, on its own,
+	// suggests the page renders client-side.
+	largeInlineScriptBytes = 100 << 10 // 100 KB
+)
+
+// appRootIDs are the mount point ids used by common client-side frameworks
+// (React, Vue, Next.js, Nuxt, Gatsby).
+var appRootIDs = map[string]bool{
+	"root": true, "app": true, "__next": true, "__nuxt": true, "___gatsby": true,
+}
+
+// jsSignals collects the hints that a page only renders with JavaScript.
+type jsSignals struct {
+	appRoot         bool // a framework mount point such as <div id="root">
+	noscriptWarning bool // a <noscript> asking to enable JavaScript
+	inlineScript    int  // bytes of inline script
+}
+
+func (s *jsSignals) noscriptText(text string) {
+	if strings.Contains(strings.ToLower(text), "javascript") {
+		s.noscriptWarning = true
+	}
+}
+
+// requiresJavascript reports whether a page with textBytes of visible text is
+// likely an empty shell rendered client-side, whose analysis says little about
+// what users see.
+func (s *jsSignals) requiresJavascript(textBytes int) bool {
+	if textBytes >= shellTextBytes {
+		return false
+	}
+	return s.appRoot || s.noscriptWarning || s.inlineScript >= largeInlineScriptBytes
+}
@@ -0,0 +1,38 @@
+package pageinsight
+
+import "strings"
+
+// parseSrcset returns the candidate URLs of a srcset attribute, such as
+// "a.jpg 1x, b.jpg 2x" or "s.jpg 640w,l.jpg 1280w", following the algorithm
+// browsers use. A candidate's URL runs until whitespace, so commas inside it
+// (as in a query string) are kept; a comma right after the URL ends the
+// candidate without descriptors.
+func parseSrcset(srcset string) []string {
+	var urls []string
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\f\r,")
+		if s == "" {
+			return urls
+		}
+
+		end := strings.IndexAny(s, " \t\n\f\r")
+		if end < 0 {
+			end = len(s)
+		}
+		candidate := s[:end]
+		s = s[end:]
+
+		if trimmed := strings.TrimRight(candidate, ","); len(trimmed) < len(candidate) {
+			// No descriptors follow a URL that ends with a comma.
+			candidate = trimmed
+		} else if i := strings.IndexByte(s, ','); i >= 0 {
+			s = s[i+1:] // skip the descriptors
+		} else {
+			s = ""
+		}
+		if candidate != "" {
+			urls = append(urls, candidate)
+		}
+	}
+}
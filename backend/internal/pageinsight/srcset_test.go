@@ -0,0 +1,39 @@
+package pageinsight
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcset string
+		want   []string
+	}{
+		{name: "density descriptors", srcset: "a.jpg 1x, b.jpg 2x", want: []string{"a.jpg", "b.jpg"}},
+		{name: "width descriptors without spaces", srcset: "s.jpg 640w,l.jpg 1280w", want: []string{"s.jpg", "l.jpg"}},
+		{name: "no descriptors", srcset: "a.jpg, b.jpg", want: []string{"a.jpg", "b.jpg"}},
+		{name: "comma without whitespace stays in the URL", srcset: "a.jpg,b.jpg", want: []string{"a.jpg,b.jpg"}},
+		{name: "single URL", srcset: "a.jpg", want: []string{"a.jpg"}},
+		{
+			name:   "commas in query string",
+			srcset: "/img?w=100,h=50 1x, /img?w=200,h=100 2x",
+			want:   []string{"/img?w=100,h=50", "/img?w=200,h=100"},
+		},
+		{
+			name:   "whitespace variations",
+			srcset: "\n\t a.jpg   1x ,\n b.jpg\t2x ,, ",
+			want:   []string{"a.jpg", "b.jpg"},
+		},
+		{name: "empty", srcset: "  ", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSrcset(tt.srcset); !slices.Equal(got, tt.want) {
+				t.Errorf("parseSrcset(%q) = %q, want %q", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package pageinsight
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// knownTrackers maps well-known analytics and tracking script domains to a
+// display name. Subdomains match too, so static.hotjar.com is Hotjar.
+var knownTrackers = map[string]string{
+	"googletagmanager.com":   "Google Tag Manager",
+	"google-analytics.com":   "Google Analytics",
+	"connect.facebook.net":   "Facebook Pixel",
+	"hotjar.com":             "Hotjar",
+	"doubleclick.net":        "DoubleClick",
+	"clarity.ms":             "Microsoft Clarity",
+	"segment.com":            "Segment",
+	"snap.licdn.com":         "LinkedIn Insight",
+	"static.ads-twitter.com": "X (Twitter) Ads",
+}
+
+// trackerName returns the tracker name for host, if it is a known one.
+func trackerName(host string) (string, bool) {
+	for {
+		if name, ok := knownTrackers[host]; ok {
+			return name, true
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			return "", false
+		}
+		host = parent
+	}
+}
+
+// thirdPartySummary orders third-party hosts by how many resources they
+// serve, most first, and names the known trackers among them.
+func thirdPartySummary(hosts map[string]*model.DomainCount) ([]model.DomainCount, []string) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	domains := make([]model.DomainCount, 0, len(hosts))
+	var trackers []string
+	for host, count := range hosts {
+		domains = append(domains, *count)
+		if name, ok := trackerName(host); ok && !slices.Contains(trackers, name) {
+			trackers = append(trackers, name)
+		}
+	}
+	slices.SortFunc(domains, func(a, b model.DomainCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Domain, b.Domain))
+	})
+	slices.Sort(trackers)
+	return domains, trackers
+}
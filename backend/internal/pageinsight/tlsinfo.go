@@ -0,0 +1,31 @@
+package pageinsight
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// certExpiryWarning is how close to expiry a certificate gets flagged.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// tlsDetails describes the connection the page was served over at now, or
+// returns nil for a plain-http page.
+func tlsDetails(state *tls.ConnectionState, now time.Time) *model.TLSInfo {
+	if state == nil {
+		return nil
+	}
+	info := &model.TLSInfo{Version: tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) == 0 {
+		return info
+	}
+	leaf := state.PeerCertificates[0]
+	remaining := leaf.NotAfter.Sub(now)
+	info.CertificateSubject = leaf.Subject.String()
+	info.CertificateIssuer = leaf.Issuer.String()
+	info.CertificateNotAfter = leaf.NotAfter.UTC().Format(time.RFC3339)
+	info.DaysUntilExpiry = int(remaining / (24 * time.Hour))
+	info.ExpiresSoon = remaining < certExpiryWarning
+	return info
+}
@@ -0,0 +1,89 @@
+package pageinsight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+func TestTLSDetails(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		notAfter   time.Time
+		wantDays   int
+		wantSoon   bool
+		wantNotAft string
+	}{
+		{name: "months left", notAfter: now.Add(90 * 24 * time.Hour), wantDays: 90, wantNotAft: "2024-08-30T12:00:00Z"},
+		{name: "just outside the window", notAfter: now.Add(15 * 24 * time.Hour), wantDays: 15, wantNotAft: "2024-06-16T12:00:00Z"},
+		{name: "expires soon", notAfter: now.Add(13*24*time.Hour + time.Hour), wantDays: 13, wantSoon: true, wantNotAft: "2024-06-14T13:00:00Z"},
+		{name: "expired", notAfter: now.Add(-48 * time.Hour), wantDays: -2, wantSoon: true, wantNotAft: "2024-05-30T12:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &tls.ConnectionState{
+				Version: tls.VersionTLS13,
+				PeerCertificates: []*x509.Certificate{{
+					Subject:  pkix.Name{CommonName: "example.com"},
+					Issuer:   pkix.Name{CommonName: "Example CA", Organization: []string{"Example"}},
+					NotAfter: tt.notAfter,
+				}},
+			}
+			want := &model.TLSInfo{
+				Version:             "TLS 1.3",
+				CertificateSubject:  "CN=example.com",
+				CertificateIssuer:   "CN=Example CA,O=Example",
+				CertificateNotAfter: tt.wantNotAft,
+				DaysUntilExpiry:     tt.wantDays,
+				ExpiresSoon:         tt.wantSoon,
+			}
+			if got := tlsDetails(state, now); *got != *want {
+				t.Errorf("tlsDetails = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	if got := tlsDetails(nil, now); got != nil {
+		t.Errorf("tlsDetails(nil) = %+v, want nil for plain http", got)
+	}
+}
+
+func TestEngine_Analyze_TLS(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "<html><head><title>T</title></head></html>")
+	})
+
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	engine := NewEngine(&HTTPClient{client: secure.Client()}, &mockLinkChecker{})
+	result, err := engine.Analyze(context.Background(), secure.URL, model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	leaf := secure.Certificate()
+	if result.TLS == nil || result.TLS.Version == "" || result.TLS.CertificateIssuer != leaf.Issuer.String() ||
+		result.TLS.CertificateNotAfter != leaf.NotAfter.UTC().Format(time.RFC3339) {
+		t.Errorf("TLS = %+v, want the test server's connection and certificate", result.TLS)
+	}
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+	engine = NewEngine(&HTTPClient{client: plain.Client()}, &mockLinkChecker{})
+	result, err = engine.Analyze(context.Background(), plain.URL, model.AnalyzeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.TLS != nil {
+		t.Errorf("TLS = %+v, want nil for a plain-http page", result.TLS)
+	}
+}
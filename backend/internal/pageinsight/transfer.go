@@ -0,0 +1,56 @@
+package pageinsight
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Bahjat/page-insight-tool/backend/internal/model"
+)
+
+// largeUncompressedThreshold is the decoded size above which an uncompressed
+// HTML document is flagged as wasteful.
+const largeUncompressedThreshold = 100 << 10 // 100 KB
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Count returns the number of bytes read so far.
+func (c *countingReader) Count() int64 {
+	return c.n
+}
+
+// evaluateTransfer summarizes how efficiently the page was delivered, given
+// the fetch response and the number of decoded bytes the parser consumed.
+func evaluateTransfer(resp *Response, decoded int64) (model.TransferStats, []string) {
+	stats := model.TransferStats{
+		Compressed:      resp.ContentEncoding != "" && resp.ContentEncoding != "identity",
+		ContentEncoding: resp.ContentEncoding,
+		DecodedBytes:    decoded,
+	}
+
+	switch {
+	case resp.WireBytes != nil:
+		stats.WireBytes = resp.WireBytes()
+	case !stats.Compressed:
+		stats.WireBytes = decoded
+	}
+
+	if stats.WireBytes > 0 && stats.DecodedBytes > 0 {
+		stats.CompressionRatio = float64(stats.WireBytes) / float64(stats.DecodedBytes)
+	}
+
+	var warnings []string
+	if !stats.Compressed && decoded > largeUncompressedThreshold {
+		warnings = append(warnings, fmt.Sprintf("HTML document of %d KB was served uncompressed", decoded>>10))
+	}
+	return stats, warnings
+}
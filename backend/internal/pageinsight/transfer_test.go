@@ -0,0 +1,68 @@
+package pageinsight
+
+import "testing"
+
+func TestEvaluateTransfer(t *testing.T) {
+	fixed := func(n int64) func() int64 { return func() int64 { return n } }
+
+	tests := []struct {
+		name           string
+		resp           *Response
+		decoded        int64
+		wantCompressed bool
+		wantWire       int64
+		wantWarnings   int
+	}{
+		{
+			name:           "gzip with known wire size",
+			resp:           &Response{ContentEncoding: "gzip", WireBytes: fixed(30 << 10)},
+			decoded:        200 << 10,
+			wantCompressed: true,
+			wantWire:       30 << 10,
+		},
+		{
+			name:         "large uncompressed page warns",
+			resp:         &Response{WireBytes: fixed(150 << 10)},
+			decoded:      150 << 10,
+			wantWire:     150 << 10,
+			wantWarnings: 1,
+		},
+		{
+			name:     "small uncompressed page is fine",
+			resp:     &Response{},
+			decoded:  4 << 10,
+			wantWire: 4 << 10,
+		},
+		{
+			name:           "compressed without wire counter",
+			resp:           &Response{ContentEncoding: "br"},
+			decoded:        200 << 10,
+			wantCompressed: true,
+		},
+		{
+			name:         "identity is not compression",
+			resp:         &Response{ContentEncoding: "identity"},
+			decoded:      101 << 10,
+			wantWire:     101 << 10,
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, warnings := evaluateTransfer(tt.resp, tt.decoded)
+			if stats.Compressed != tt.wantCompressed {
+				t.Errorf("Compressed = %v, want %v", stats.Compressed, tt.wantCompressed)
+			}
+			if stats.WireBytes != tt.wantWire {
+				t.Errorf("WireBytes = %d, want %d", stats.WireBytes, tt.wantWire)
+			}
+			if stats.DecodedBytes != tt.decoded {
+				t.Errorf("DecodedBytes = %d, want %d", stats.DecodedBytes, tt.decoded)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
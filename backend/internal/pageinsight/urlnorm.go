@@ -0,0 +1,93 @@
+package pageinsight
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// normalizeURL reduces a link target to a canonical form, so spellings of the
+// same URL are deduplicated and checked once: the scheme is lowercased, the
+// host lowercased and punycoded, default ports and the fragment are dropped,
+// and a trailing slash is removed. An empty path becomes "/". Repeated
+// slashes are kept, as some paths, like web.archive.org's, need them. Targets
+// that don't parse only lose their fragment.
+func normalizeURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		target, _, _ := strings.Cut(link, "#")
+		return target
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Fragment, u.RawFragment = "", ""
+
+	if u.Host != "" {
+		host := asciiHostname(u.Hostname())
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port := u.Port(); port != "" && !isDefaultPort(u.Scheme, port) {
+			host += ":" + port
+		}
+		u.Host = host
+	}
+
+	if u.Opaque == "" {
+		escaped := u.EscapedPath()
+		if len(escaped) > 1 {
+			escaped = strings.TrimSuffix(escaped, "/")
+		}
+		if escaped == "" && u.Host != "" {
+			escaped = "/"
+		}
+		if path, err := url.PathUnescape(escaped); err == nil {
+			u.Path, u.RawPath = path, escaped
+		}
+	}
+	return u.String()
+}
+
+// collapseSlashes collapses repeated slashes in the path of a normalized
+// link, so /docs//guide and /docs/guide compare equal.
+func collapseSlashes(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Opaque != "" {
+		return link
+	}
+	escaped := u.EscapedPath()
+	if !strings.Contains(escaped, "//") {
+		return link
+	}
+	for strings.Contains(escaped, "//") {
+		escaped = strings.ReplaceAll(escaped, "//", "/")
+	}
+	if path, err := url.PathUnescape(escaped); err == nil {
+		u.Path, u.RawPath = path, escaped
+	}
+	return u.String()
+}
+
+func isDefaultPort(scheme, port string) bool {
+	return (scheme == "http" && port == "80") || (scheme == "https" && port == "443")
+}
+
+// asciiHostname returns the lowercase ASCII (punycode) form of a hostname, so
+// münchen.example and xn--mnchen-3ya.example compare equal. Hostnames that are
+// not valid IDNs, such as IP literals, are only lowercased.
+func asciiHostname(hostname string) string {
+	if ascii, err := idna.Lookup.ToASCII(hostname); err == nil {
+		return ascii
+	}
+	return strings.ToLower(hostname)
+}
+
+// asciiHost is asciiHostname for a host with an optional port.
+func asciiHost(host string) string {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return asciiHostname(host)
+	}
+	return net.JoinHostPort(asciiHostname(hostname), port)
+}
@@ -0,0 +1,56 @@
+package pageinsight
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "https://example.com/page", want: "https://example.com/page"},
+		{link: "https://example.com/page/", want: "https://example.com/page"},
+		{link: "https://EXAMPLE.com/page", want: "https://example.com/page"},
+		{link: "HTTPS://example.com:443/page", want: "https://example.com/page"},
+		{link: "http://example.com:80/page", want: "http://example.com/page"},
+		{link: "http://example.com:8080/page", want: "http://example.com:8080/page"},
+		{link: "https://example.com/page#pricing", want: "https://example.com/page"},
+		{link: "https://example.com//docs///guide", want: "https://example.com//docs///guide"},
+		{link: "https://web.archive.org/web/2020/https://x.example/", want: "https://web.archive.org/web/2020/https://x.example"},
+		{link: "https://example.com", want: "https://example.com/"},
+		{link: "https://example.com/", want: "https://example.com/"},
+		{link: "https://example.com/Page?q=A#x", want: "https://example.com/Page?q=A"},
+		{link: "https://example.com/a%2Fb/", want: "https://example.com/a%2Fb"},
+		{link: "https://[2001:DB8::1]:443/", want: "https://[2001:db8::1]/"},
+		{link: "https://[2001:db8::1]:8443/", want: "https://[2001:db8::1]:8443/"},
+		{link: "https://münchen.example/a", want: "https://xn--mnchen-3ya.example/a"},
+		{link: "://bad-url#frag", want: "://bad-url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			if got := normalizeURL(tt.link); got != tt.want {
+				t.Errorf("normalizeURL(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapseSlashes(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{link: "https://example.com//docs///guide", want: "https://example.com/docs/guide"},
+		{link: "https://example.com/docs/guide?next=//x", want: "https://example.com/docs/guide?next=//x"},
+		{link: "https://example.com/a%2F%2Fb//c", want: "https://example.com/a%2F%2Fb/c"},
+		{link: "mailto:a//b@example.com", want: "mailto:a//b@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			if got := collapseSlashes(tt.link); got != tt.want {
+				t.Errorf("collapseSlashes(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
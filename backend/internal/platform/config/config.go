@@ -3,32 +3,133 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 )
 
 var (
-	errInvalidPort           = errors.New("config: invalid PORT number")
-	errConcurrencyOutOfRange = errors.New("config: LINK_CHECK_CONCURRENCY must be 1-100")
-	errInvalidShutdown       = errors.New("config: SHUTDOWN_TIMEOUT_SECONDS must be greater than 0")
+	errInvalidPort                = errors.New("config: invalid PORT number")
+	errInvalidPprofPort           = errors.New("config: invalid PPROF_PORT number")
+	errPprofPortInUse             = errors.New("config: PPROF_PORT must differ from PORT")
+	errConcurrencyOutOfRange      = errors.New("config: LINK_CHECK_CONCURRENCY must be 1-100")
+	errPerHostOutOfRange          = errors.New("config: LINK_CHECK_PER_HOST must be 1-100")
+	errLinkTimeoutOutOfRange      = errors.New("config: LINK_CHECK_TIMEOUT_MS must be 100-30000")
+	errMaxLinksOutOfRange         = errors.New("config: LINK_CHECK_MAX_LINKS must be 1-10000")
+	errUnknownStrategy            = errors.New("config: LINK_CHECK_STRATEGY must be head-first, get-only or head-only")
+	errCacheTTLOutOfRange         = errors.New("config: LINK_CHECK_CACHE_TTL_SECONDS must be 0-86400")
+	errCacheSizeOutOfRange        = errors.New("config: LINK_CHECK_CACHE_SIZE must be 1-1000000")
+	errRedirectsOutOfRange        = errors.New("config: LINK_CHECK_MAX_REDIRECTS must be 0-10")
+	errThresholdOutOfRange        = errors.New("config: LINK_CHECK_HOST_FAILURE_THRESHOLD must be 0-100")
+	errUnknownOverflow            = errors.New("config: LINK_CHECK_OVERFLOW must be sample or truncate")
+	errInvalidShutdown            = errors.New("config: SHUTDOWN_TIMEOUT_SECONDS must be greater than 0")
+	errUploadOutOfRange           = errors.New("config: MAX_UPLOAD_SIZE_MB must be 1-10")
+	errInvalidUserAgent           = errors.New("config: USER_AGENT must not contain control characters")
+	errInvalidBlockedHost         = errors.New("config: BLOCKED_HOSTS entries must be hostnames or *.suffix wildcards")
+	errInvalidOwnHostname         = errors.New("config: OWN_HOSTNAMES entries must be hostnames or *.suffix wildcards")
+	errUnknownRobotsPolicy        = errors.New("config: ROBOTS_TXT must be off, warn or enforce")
+	errCrawlDelayOutOfRange       = errors.New("config: CRAWL_DELAY_MS must be 0-10000")
+	errResponseBodyOutOfRange     = errors.New("config: MAX_RESPONSE_BODY_BYTES must be 1048576-104857600")
+	errFetchTimeoutOutOfRange     = errors.New("config: FETCH_TIMEOUT_SECONDS must be 1-60")
+	errFetchAttemptsOutOfRange    = errors.New("config: FETCH_ATTEMPTS must be 1-5")
+	errInvalidProxyURL            = errors.New("config: OUTBOUND_PROXY_URL must be an http:// or https:// URL with a host")
+	errPageCacheTTLOutOfRange     = errors.New("config: PAGE_CACHE_TTL_SECONDS must be 0-3600")
+	errPageCacheSizeOutOfRange    = errors.New("config: PAGE_CACHE_SIZE must be 1-10000")
+	errJobWorkersOutOfRange       = errors.New("config: JOB_WORKERS must be 1-100")
+	errJobQueueOutOfRange         = errors.New("config: JOB_QUEUE_SIZE must be 1-10000")
+	errJobTTLOutOfRange           = errors.New("config: JOB_TTL_SECONDS must be 60-86400")
+	errBatchConcurrencyOutOfRange = errors.New("config: BATCH_CONCURRENCY must be 1-20")
+	errMaxHistoryRowsOutOfRange   = errors.New("config: MAX_HISTORY_ROWS must be 1-1000000")
+	errMaxAnalysesOutOfRange      = errors.New("config: MAX_CONCURRENT_ANALYSES must be 1-1000")
+	errAnalysisWaitOutOfRange     = errors.New("config: ANALYSIS_QUEUE_WAIT_MS must be 0-30000")
 )
 
 // Config holds all application configuration loaded from environment variables.
 type Config struct {
-	Port                 string
-	LogLevel             string
-	LinkCheckConcurrency int
-	ShutdownTimeout      time.Duration
+	Port                          string
+	LogLevel                      string
+	LinkCheckConcurrency          int
+	LinkCheckPerHost              int
+	LinkCheckTimeout              time.Duration
+	LinkCheckMaxLinks             int
+	LinkCheckStrategy             string
+	LinkCheckMaxRedirects         int
+	LinkCheckHostFailureThreshold int
+	LinkCheckCacheTTL             time.Duration
+	LinkCheckCacheSize            int
+	LinkCheckOverflow             string
+	ShutdownTimeout               time.Duration
+	MaxUploadBytes                int64
+	MaxResponseBodyBytes          int64
+	FetchTimeout                  time.Duration
+	FetchAttempts                 int
+	CheckMediaLinks               bool
+	CollapseDuplicateSlashes      bool
+	UserAgent                     string
+	BlockedHosts                  []string
+	OwnHostnames                  []string
+	RobotsTxt                     string
+	CrawlDelay                    time.Duration
+	OutboundProxyURL              string
+	PageCacheTTL                  time.Duration
+	PageCacheSize                 int
+	JobWorkers                    int
+	JobQueueSize                  int
+	JobTTL                        time.Duration
+	BatchConcurrency              int
+	HTTPResultCaching             bool
+	EnablePprof                   bool
+	PprofPort                     string
+	HistoryDBPath                 string
+	MaxHistoryRows                int
+	MaxConcurrentAnalyses         int
+	AnalysisQueueWait             time.Duration
 }
 
 // Load reads configuration from environment variables with sensible defaults.
 func Load() (Config, error) {
 	cfg := Config{
-		Port:                 getEnv("PORT", "8080"),
-		LogLevel:             getEnv("LOG_LEVEL", "ERROR"),
-		LinkCheckConcurrency: getEnvAsInt("LINK_CHECK_CONCURRENCY", 25),
-		ShutdownTimeout:      time.Duration(getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 10)) * time.Second,
+		Port:                          getEnv("PORT", "8080"),
+		LogLevel:                      getEnv("LOG_LEVEL", "ERROR"),
+		LinkCheckConcurrency:          getEnvAsInt("LINK_CHECK_CONCURRENCY", 25),
+		LinkCheckPerHost:              getEnvAsInt("LINK_CHECK_PER_HOST", 4),
+		LinkCheckTimeout:              time.Duration(getEnvAsInt("LINK_CHECK_TIMEOUT_MS", 2000)) * time.Millisecond,
+		LinkCheckMaxLinks:             getEnvAsInt("LINK_CHECK_MAX_LINKS", 1000),
+		LinkCheckStrategy:             getEnv("LINK_CHECK_STRATEGY", "head-first"),
+		LinkCheckMaxRedirects:         getEnvAsInt("LINK_CHECK_MAX_REDIRECTS", 3),
+		LinkCheckHostFailureThreshold: getEnvAsInt("LINK_CHECK_HOST_FAILURE_THRESHOLD", 5),
+		LinkCheckCacheTTL:             time.Duration(getEnvAsInt("LINK_CHECK_CACHE_TTL_SECONDS", 300)) * time.Second,
+		LinkCheckCacheSize:            getEnvAsInt("LINK_CHECK_CACHE_SIZE", 10000),
+		LinkCheckOverflow:             getEnv("LINK_CHECK_OVERFLOW", "truncate"),
+		ShutdownTimeout:               time.Duration(getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 10)) * time.Second,
+		MaxUploadBytes:                int64(getEnvAsInt("MAX_UPLOAD_SIZE_MB", 5)) << 20,
+		MaxResponseBodyBytes:          int64(getEnvAsInt("MAX_RESPONSE_BODY_BYTES", 10<<20)),
+		FetchTimeout:                  time.Duration(getEnvAsInt("FETCH_TIMEOUT_SECONDS", 10)) * time.Second,
+		FetchAttempts:                 getEnvAsInt("FETCH_ATTEMPTS", 2),
+		CheckMediaLinks:               getEnvAsBool("CHECK_MEDIA_LINKS", false),
+		CollapseDuplicateSlashes:      getEnvAsBool("COLLAPSE_DUPLICATE_SLASHES", false),
+		UserAgent:                     getEnv("USER_AGENT", "PageInsightBot/1.0"),
+		BlockedHosts:                  getEnvAsList("BLOCKED_HOSTS"),
+		OwnHostnames:                  getEnvAsList("OWN_HOSTNAMES"),
+		RobotsTxt:                     getEnv("ROBOTS_TXT", "off"),
+		CrawlDelay:                    time.Duration(getEnvAsInt("CRAWL_DELAY_MS", 500)) * time.Millisecond,
+		OutboundProxyURL:              getEnv("OUTBOUND_PROXY_URL", ""),
+		PageCacheTTL:                  time.Duration(getEnvAsInt("PAGE_CACHE_TTL_SECONDS", 60)) * time.Second,
+		PageCacheSize:                 getEnvAsInt("PAGE_CACHE_SIZE", 100),
+		JobWorkers:                    getEnvAsInt("JOB_WORKERS", 4),
+		JobQueueSize:                  getEnvAsInt("JOB_QUEUE_SIZE", 100),
+		JobTTL:                        time.Duration(getEnvAsInt("JOB_TTL_SECONDS", 900)) * time.Second,
+		BatchConcurrency:              getEnvAsInt("BATCH_CONCURRENCY", 4),
+		HTTPResultCaching:             getEnvAsBool("HTTP_RESULT_CACHING", false),
+		EnablePprof:                   getEnvAsBool("ENABLE_PPROF", false),
+		PprofPort:                     getEnv("PPROF_PORT", "6060"),
+		HistoryDBPath:                 getEnv("HISTORY_DB_PATH", ""),
+		MaxHistoryRows:                getEnvAsInt("MAX_HISTORY_ROWS", 1000),
+		MaxConcurrentAnalyses:         getEnvAsInt("MAX_CONCURRENT_ANALYSES", 20),
+		AnalysisQueueWait:             time.Duration(getEnvAsInt("ANALYSIS_QUEUE_WAIT_MS", 2000)) * time.Millisecond,
 	}
 
 	return cfg, cfg.validate()
@@ -40,17 +141,163 @@ func (c Config) validate() error {
 		return fmt.Errorf("%w: %q", errInvalidPort, c.Port)
 	}
 
+	if c.EnablePprof {
+		pprofPort, err := strconv.Atoi(c.PprofPort)
+		if err != nil || pprofPort < 1 || pprofPort > 65535 {
+			return fmt.Errorf("%w: %q", errInvalidPprofPort, c.PprofPort)
+		}
+		if pprofPort == port {
+			return fmt.Errorf("%w: both are %d", errPprofPortInUse, port)
+		}
+	}
+
 	if c.LinkCheckConcurrency < 1 || c.LinkCheckConcurrency > 100 {
 		return fmt.Errorf("%w: got %d", errConcurrencyOutOfRange, c.LinkCheckConcurrency)
 	}
 
+	if c.LinkCheckPerHost < 1 || c.LinkCheckPerHost > 100 {
+		return fmt.Errorf("%w: got %d", errPerHostOutOfRange, c.LinkCheckPerHost)
+	}
+
+	if c.LinkCheckTimeout < 100*time.Millisecond || c.LinkCheckTimeout > 30*time.Second {
+		return fmt.Errorf("%w: got %s", errLinkTimeoutOutOfRange, c.LinkCheckTimeout)
+	}
+
+	if c.LinkCheckMaxLinks < 1 || c.LinkCheckMaxLinks > 10000 {
+		return fmt.Errorf("%w: got %d", errMaxLinksOutOfRange, c.LinkCheckMaxLinks)
+	}
+
+	switch c.LinkCheckStrategy {
+	case "head-first", "get-only", "head-only":
+	default:
+		return fmt.Errorf("%w: got %q", errUnknownStrategy, c.LinkCheckStrategy)
+	}
+
+	if c.LinkCheckMaxRedirects < 0 || c.LinkCheckMaxRedirects > 10 {
+		return fmt.Errorf("%w: got %d", errRedirectsOutOfRange, c.LinkCheckMaxRedirects)
+	}
+
+	if c.LinkCheckHostFailureThreshold < 0 || c.LinkCheckHostFailureThreshold > 100 {
+		return fmt.Errorf("%w: got %d", errThresholdOutOfRange, c.LinkCheckHostFailureThreshold)
+	}
+
+	if c.LinkCheckCacheTTL < 0 || c.LinkCheckCacheTTL > 24*time.Hour {
+		return fmt.Errorf("%w: got %s", errCacheTTLOutOfRange, c.LinkCheckCacheTTL)
+	}
+
+	if c.LinkCheckCacheSize < 1 || c.LinkCheckCacheSize > 1000000 {
+		return fmt.Errorf("%w: got %d", errCacheSizeOutOfRange, c.LinkCheckCacheSize)
+	}
+
+	switch c.LinkCheckOverflow {
+	case "sample", "truncate":
+	default:
+		return fmt.Errorf("%w: got %q", errUnknownOverflow, c.LinkCheckOverflow)
+	}
+
 	if c.ShutdownTimeout <= 0 {
 		return fmt.Errorf("%w: got %s", errInvalidShutdown, c.ShutdownTimeout)
 	}
 
+	if c.MaxUploadBytes < 1<<20 || c.MaxUploadBytes > 10<<20 {
+		return fmt.Errorf("%w: got %d bytes", errUploadOutOfRange, c.MaxUploadBytes)
+	}
+
+	if c.MaxResponseBodyBytes < 1<<20 || c.MaxResponseBodyBytes > 100<<20 {
+		return fmt.Errorf("%w: got %d", errResponseBodyOutOfRange, c.MaxResponseBodyBytes)
+	}
+
+	// The analysis as a whole has 60 seconds.
+	if c.FetchTimeout < time.Second || c.FetchTimeout > 60*time.Second {
+		return fmt.Errorf("%w: got %s", errFetchTimeoutOutOfRange, c.FetchTimeout)
+	}
+
+	if c.FetchAttempts < 1 || c.FetchAttempts > 5 {
+		return fmt.Errorf("%w: got %d", errFetchAttemptsOutOfRange, c.FetchAttempts)
+	}
+
+	// A header value with a newline would be rejected on every request.
+	if strings.ContainsFunc(c.UserAgent, unicode.IsControl) {
+		return fmt.Errorf("%w: got %q", errInvalidUserAgent, c.UserAgent)
+	}
+
+	for _, host := range c.BlockedHosts {
+		if !validHostPattern(host) {
+			return fmt.Errorf("%w: got %q", errInvalidBlockedHost, host)
+		}
+	}
+	for _, host := range c.OwnHostnames {
+		if !validHostPattern(host) {
+			return fmt.Errorf("%w: got %q", errInvalidOwnHostname, host)
+		}
+	}
+
+	switch c.RobotsTxt {
+	case "off", "warn", "enforce":
+	default:
+		return fmt.Errorf("%w: got %q", errUnknownRobotsPolicy, c.RobotsTxt)
+	}
+
+	if c.CrawlDelay < 0 || c.CrawlDelay > 10*time.Second {
+		return fmt.Errorf("%w: got %s", errCrawlDelayOutOfRange, c.CrawlDelay)
+	}
+
+	if c.PageCacheTTL < 0 || c.PageCacheTTL > time.Hour {
+		return fmt.Errorf("%w: got %s", errPageCacheTTLOutOfRange, c.PageCacheTTL)
+	}
+
+	if c.PageCacheSize < 1 || c.PageCacheSize > 10000 {
+		return fmt.Errorf("%w: got %d", errPageCacheSizeOutOfRange, c.PageCacheSize)
+	}
+
+	if c.JobWorkers < 1 || c.JobWorkers > 100 {
+		return fmt.Errorf("%w: got %d", errJobWorkersOutOfRange, c.JobWorkers)
+	}
+
+	if c.JobQueueSize < 1 || c.JobQueueSize > 10000 {
+		return fmt.Errorf("%w: got %d", errJobQueueOutOfRange, c.JobQueueSize)
+	}
+
+	if c.JobTTL < time.Minute || c.JobTTL > 24*time.Hour {
+		return fmt.Errorf("%w: got %s", errJobTTLOutOfRange, c.JobTTL)
+	}
+
+	if c.BatchConcurrency < 1 || c.BatchConcurrency > 20 {
+		return fmt.Errorf("%w: got %d", errBatchConcurrencyOutOfRange, c.BatchConcurrency)
+	}
+
+	if c.MaxHistoryRows < 1 || c.MaxHistoryRows > 1_000_000 {
+		return fmt.Errorf("%w: got %d", errMaxHistoryRowsOutOfRange, c.MaxHistoryRows)
+	}
+
+	if c.MaxConcurrentAnalyses < 1 || c.MaxConcurrentAnalyses > 1000 {
+		return fmt.Errorf("%w: got %d", errMaxAnalysesOutOfRange, c.MaxConcurrentAnalyses)
+	}
+
+	if c.AnalysisQueueWait < 0 || c.AnalysisQueueWait > 30*time.Second {
+		return fmt.Errorf("%w: got %s", errAnalysisWaitOutOfRange, c.AnalysisQueueWait)
+	}
+
+	if c.OutboundProxyURL != "" {
+		u, err := url.Parse(c.OutboundProxyURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%w: got %q", errInvalidProxyURL, c.OutboundProxyURL)
+		}
+	}
+
 	return nil
 }
 
+// OutboundProxy is the parsed OUTBOUND_PROXY_URL, or nil when outbound
+// requests go direct.
+func (c Config) OutboundProxy() *url.URL {
+	u, err := url.Parse(c.OutboundProxyURL)
+	if c.OutboundProxyURL == "" || err != nil {
+		return nil
+	}
+	return u
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -69,3 +316,32 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return v
 }
+
+// validHostPattern reports whether host is a hostname or a *.suffix wildcard.
+func validHostPattern(host string) bool {
+	name := strings.TrimPrefix(host, "*.")
+	return name != "" && !strings.ContainsAny(name, "*/:@ ")
+}
+
+// getEnvAsList splits a comma-separated variable, dropping empty entries.
+func getEnvAsList(key string) []string {
+	var list []string
+	for item := range strings.SplitSeq(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvAsBool(key string, fallback bool) bool {
+	s := os.Getenv(key)
+	if s == "" {
+		return fallback
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -16,6 +16,17 @@ const (
 	Timeout
 	// ParsingFailed indicates the response could not be parsed (HTTP 500).
 	ParsingFailed
+	// Forbidden indicates the target may not be fetched, e.g. because its
+	// robots.txt disallows it (HTTP 403).
+	Forbidden
+	// UnsupportedContent indicates the target is not an HTML page (HTTP 415).
+	UnsupportedContent
+	// Unavailable indicates the server cannot take or finish the work right
+	// now, e.g. because it is shutting down (HTTP 503).
+	Unavailable
+	// Overloaded indicates the server is running as many analyses as it may
+	// and the request should be retried shortly (HTTP 429).
+	Overloaded
 )
 
 // AppError carries a category, user message, and original cause.
@@ -24,6 +35,7 @@ type AppError struct {
 	UpstreamStatus int // HTTP status code returned by the target domain
 	Message        string
 	Cause          error
+	Detail         any // optional structured context rendered alongside Message
 }
 
 func (e *AppError) Error() string {
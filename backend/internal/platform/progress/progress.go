@@ -0,0 +1,44 @@
+// Package progress carries a callback through the context of an analysis,
+// for a caller that wants to follow it as it runs.
+package progress
+
+import "context"
+
+// Stages of an analysis, in the order they are reported.
+const (
+	Fetching      = "fetching"
+	Parsing       = "parsing"
+	CheckingLinks = "checking_links"
+)
+
+// Event reports the stage an analysis reached. While checking links, Checked
+// counts the links done so far out of Total.
+type Event struct {
+	Stage   string `json:"stage"`
+	Checked int    `json:"checked,omitempty"`
+	Total   int    `json:"total,omitempty"`
+}
+
+// Func receives the events of an analysis. It may be called from several
+// goroutines, though never concurrently, and must not block for long.
+type Func func(Event)
+
+type ctxKey struct{}
+
+// NewContext returns a context whose analysis reports its progress to fn.
+func NewContext(ctx context.Context, fn Func) context.Context {
+	return context.WithValue(ctx, ctxKey{}, fn)
+}
+
+// FromContext returns the callback stored in ctx, or nil.
+func FromContext(ctx context.Context) Func {
+	fn, _ := ctx.Value(ctxKey{}).(Func)
+	return fn
+}
+
+// Report sends ev to the callback of ctx, if it has one.
+func Report(ctx context.Context, ev Event) {
+	if fn := FromContext(ctx); fn != nil {
+		fn(ev)
+	}
+}
@@ -36,7 +36,7 @@ interface ErrorResponse {
 const API_URL = import.meta.env.VITE_API_URL ?? "http://localhost:8080";
 
 async function analyzeUrl(url: string): Promise<PageAnalysis> {
-  const res = await fetch(`${API_URL}/analyze`, {
+  const res = await fetch(`${API_URL}/v1/analyze`, {
     method: "POST",
     headers: { "Content-Type": "application/json" },
     body: JSON.stringify({ url }),